@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/stitchmap/stitchmap/internal/model"
+)
+
+// config is the server's startup configuration. Each setting comes from its flag if
+// given, else from its STITCHMAP_* environment variable if it has one, else from
+// the default.
+type config struct {
+	addr         string
+	dbPath       string
+	metricsToken string
+	logLevel     string
+	bcryptCost   int
+	tlsCert      string
+	tlsKey       string
+	redirectAddr string // plain-HTTP listener redirecting to HTTPS; "" for none
+	secureCookie bool   // always mark the session cookie Secure
+	adminEmail   string // account to make an admin at startup
+}
+
+// tls reports whether the server should serve HTTPS itself.
+func (c config) tls() bool {
+	return c.tlsCert != "" && c.tlsKey != ""
+}
+
+// loadConfig reads the environment and parses the command-line flags.
+func loadConfig() (config, error) {
+	cfg := config{
+		addr:       envString("STITCHMAP_ADDR", ":8080"),
+		dbPath:     envString("STITCHMAP_DB", "stitchmap.db"),
+		logLevel:   "info",
+		bcryptCost: model.DefaultBcryptCost,
+	}
+	if v := os.Getenv("STITCHMAP_BCRYPT_COST"); v != "" {
+		cost, err := strconv.Atoi(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid STITCHMAP_BCRYPT_COST %q", v)
+		}
+		cfg.bcryptCost = cost
+	}
+
+	flag.StringVar(&cfg.addr, "addr", cfg.addr, "HTTP listen address (env STITCHMAP_ADDR)")
+	flag.StringVar(&cfg.dbPath, "db", cfg.dbPath, "SQLite database file path (env STITCHMAP_DB)")
+	flag.StringVar(&cfg.metricsToken, "metrics-token", "", "bearer token for GET /metrics; the endpoint is disabled if empty")
+	flag.StringVar(&cfg.logLevel, "log-level", cfg.logLevel, "minimum log level: debug, info, warn or error")
+	flag.IntVar(&cfg.bcryptCost, "bcrypt-cost", cfg.bcryptCost, "bcrypt cost for password hashes; existing hashes are upgraded at login (env STITCHMAP_BCRYPT_COST)")
+	flag.StringVar(&cfg.tlsCert, "tls-cert", "", "TLS certificate file; with -tls-key, serve HTTPS")
+	flag.StringVar(&cfg.tlsKey, "tls-key", "", "TLS private key file; with -tls-cert, serve HTTPS")
+	flag.StringVar(&cfg.redirectAddr, "http-redirect-addr", "", "with TLS, also listen for plain HTTP here and redirect it to HTTPS")
+	flag.BoolVar(&cfg.secureCookie, "secure-cookies", false, "always mark the session cookie Secure, e.g. behind a proxy that doesn't send X-Forwarded-Proto")
+	flag.StringVar(&cfg.adminEmail, "admin-email", "", "make this account an admin at startup (the first registered account always is)")
+	flag.Parse()
+
+	if (cfg.tlsCert == "") != (cfg.tlsKey == "") {
+		return cfg, errors.New("-tls-cert and -tls-key must be given together")
+	}
+	if cfg.redirectAddr != "" && !cfg.tls() {
+		return cfg, errors.New("-http-redirect-addr needs -tls-cert and -tls-key")
+	}
+	return cfg, nil
+}
+
+// envString returns the environment variable key, or def if it is unset or empty.
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -1,10 +1,20 @@
 package main
 
 import (
-	"flag"
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/stitchmap/stitchmap/internal/database"
 	"github.com/stitchmap/stitchmap/internal/handler"
@@ -12,12 +22,24 @@ import (
 )
 
 func main() {
-	addr := flag.String("addr", ":8080", "HTTP listen address")
-	dbPath := flag.String("db", "stitchmap.db", "SQLite database file path")
-	flag.Parse()
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.logLevel)); err != nil {
+		log.Fatalf("Invalid -log-level: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	slog.SetDefault(logger)
+
+	if err := model.SetBcryptCost(cfg.bcryptCost); err != nil {
+		log.Fatalf("Invalid bcrypt cost: %v", err)
+	}
 
 	// Open database.
-	db, err := database.Open(*dbPath)
+	db, err := database.Open(cfg.dbPath)
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 	}
@@ -28,11 +50,57 @@ func main() {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
-	// Clean up expired sessions on startup.
-	if n, err := model.DeleteExpiredSessions(db); err == nil && n > 0 {
-		fmt.Printf("Cleaned up %d expired sessions\n", n)
+	// Lowercase stored emails, and report by user ID the accounts that had to be
+	// left as they are because they differ only in case.
+	if n, err := model.NormalizeStoredEmails(db); err != nil {
+		log.Fatalf("Failed to normalize emails: %v", err)
+	} else if n > 0 {
+		fmt.Printf("Normalized %d account emails\n", n)
+	}
+	if groups, err := model.EmailCaseCollisions(db); err != nil {
+		log.Printf("Failed to check email collisions: %v", err)
+	} else {
+		for _, ids := range groups {
+			log.Printf("Warning: accounts with user IDs %v differ only in email case; each can still log in with its exact email", ids)
+		}
 	}
 
+	if cfg.adminEmail != "" {
+		if err := model.MakeAdmin(db, cfg.adminEmail); errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Warning: -admin-email %s has no account yet; register it and restart", cfg.adminEmail)
+		} else if err != nil {
+			log.Fatalf("Failed to set admin: %v", err)
+		}
+	}
+
+	// Make sure the built-in stitch library is complete.
+	if n, err := model.SeedBuiltinStitches(db); err != nil {
+		log.Fatalf("Failed to seed built-in stitches: %v", err)
+	} else if n > 0 {
+		fmt.Printf("Added %d built-in stitches\n", n)
+	}
+
+	// Clean up expired sessions on startup, then hourly until shutdown.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cleanupExpired(db)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(cleanupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cleanupExpired(db)
+			}
+		}
+	}()
+
 	// Set up routes.
 	mux := http.NewServeMux()
 
@@ -42,40 +110,90 @@ func main() {
 	mux.HandleFunc("GET /register", handler.RegisterPage(db))
 	mux.HandleFunc("POST /register", handler.Register(db))
 	mux.HandleFunc("POST /logout", handler.Logout(db))
+	mux.HandleFunc("GET /forgot-password", handler.ForgotPasswordPage(db))
+	mux.HandleFunc("POST /forgot-password", handler.ForgotPassword(db))
+	mux.HandleFunc("GET /reset-password", handler.ResetPasswordPage(db))
+	mux.HandleFunc("POST /reset-password", handler.ResetPassword(db))
+	mux.HandleFunc("GET /shared/{token}", handler.SharedPatternShow(db))
+	mux.HandleFunc("GET /healthz", handler.Health(db))
+
+	metrics := handler.NewMetrics()
+	if cfg.metricsToken != "" {
+		mux.HandleFunc("GET /metrics", handler.MetricsHandler(db, metrics, cfg.metricsToken))
+	}
 
 	// Authenticated routes.
 	authed := http.NewServeMux()
 	authed.HandleFunc("GET /{$}", handler.Dashboard(db))
+	authed.HandleFunc("GET /history", handler.History(db))
+
+	// Account settings routes.
+	authed.HandleFunc("GET /settings", handler.Settings(db))
+	authed.HandleFunc("POST /settings/password", handler.SettingsChangePassword(db))
+	authed.HandleFunc("POST /settings/preferences", handler.SettingsPreferences(db))
+	authed.HandleFunc("POST /settings/sessions/revoke-others", handler.SettingsRevokeOtherSessions(db))
+	authed.HandleFunc("POST /settings/sessions/{token}/revoke", handler.SettingsRevokeSession(db))
+	authed.HandleFunc("POST /settings/tokens", handler.SettingsCreateAPIToken(db))
+	authed.HandleFunc("POST /settings/tokens/{id}/revoke", handler.SettingsRevokeAPIToken(db))
+
+	// Admin routes.
+	authed.Handle("GET /admin/users", handler.RequireAdmin(handler.AdminUsers(db)))
+	authed.Handle("POST /admin/users/{id}/disable", handler.RequireAdmin(handler.AdminUserDisable(db)))
+	authed.Handle("POST /admin/users/{id}/enable", handler.RequireAdmin(handler.AdminUserEnable(db)))
 
 	// Stitch routes.
 	authed.HandleFunc("GET /stitches", handler.StitchIndex(db))
 	authed.HandleFunc("POST /stitches", handler.StitchCreate(db))
 	authed.HandleFunc("GET /stitches/new", handler.StitchNewForm(db))
+	authed.HandleFunc("POST /stitches/import", handler.StitchImportCSV(db))
+	authed.HandleFunc("GET /stitches/export.csv", handler.StitchExportCSV(db))
+	authed.HandleFunc("GET /stitches/search", handler.StitchSearch(db))
 	authed.HandleFunc("GET /stitches/{id}/edit", handler.StitchEdit(db))
 	authed.HandleFunc("PUT /stitches/{id}", handler.StitchUpdate(db))
 	authed.HandleFunc("DELETE /stitches/{id}", handler.StitchDelete(db))
+	authed.HandleFunc("POST /stitches/{id}/duplicate", handler.StitchDuplicate(db))
 
 	// Pattern routes.
 	authed.HandleFunc("GET /patterns/new", handler.PatternNew(db))
 	authed.HandleFunc("POST /patterns", handler.PatternCreate(db))
+	authed.HandleFunc("GET /patterns/import-text", handler.PatternImportTextPage(db))
+	authed.HandleFunc("POST /patterns/import-text", handler.PatternImportText(db))
 	authed.HandleFunc("GET /patterns/{id}", handler.PatternShow(db))
 	authed.HandleFunc("GET /patterns/{id}/edit", handler.PatternEditForm(db))
 	authed.HandleFunc("PUT /patterns/{id}", handler.PatternUpdate(db))
 	authed.HandleFunc("DELETE /patterns/{id}", handler.PatternDelete(db))
+	authed.HandleFunc("POST /patterns/{id}/favorite", handler.PatternToggleFavorite(db))
 	authed.HandleFunc("GET /patterns/{id}/sections-refresh", handler.SectionsRefresh(db))
+	authed.HandleFunc("GET /patterns/{id}/resize", handler.PatternResizePreview(db))
+	authed.HandleFunc("GET /patterns/{id}/view", handler.PatternReadView(db))
+	authed.HandleFunc("GET /patterns/{id}/export.pdf", handler.PatternExportPDF(db))
+	authed.HandleFunc("GET /export/all.zip", handler.PatternExportAll(db))
+	authed.HandleFunc("POST /import/all", handler.PatternImportBackup(db))
+	authed.HandleFunc("POST /patterns/{id}/tags", handler.PatternTagAdd(db))
+	authed.HandleFunc("DELETE /patterns/{id}/tags/{tagID}", handler.PatternTagRemove(db))
+	authed.HandleFunc("POST /patterns/{id}/share", handler.ShareLinkCreate(db))
+	authed.HandleFunc("DELETE /patterns/{id}/share/{token}", handler.ShareLinkDelete(db))
 
 	// Section routes.
 	authed.HandleFunc("POST /patterns/{id}/sections", handler.SectionCreate(db))
 	authed.HandleFunc("GET /sections/{id}/edit", handler.SectionEditForm(db))
 	authed.HandleFunc("PUT /sections/{id}", handler.SectionUpdate(db))
 	authed.HandleFunc("DELETE /sections/{id}", handler.SectionDelete(db))
+	authed.HandleFunc("POST /sections/{id}/duplicate", handler.SectionDuplicate(db))
+	authed.HandleFunc("GET /sections/{id}/export.txt", handler.SectionExportText(db))
+	authed.HandleFunc("POST /sections/{id}/toggle-collapse", handler.SectionToggleCollapse(db))
 	authed.HandleFunc("POST /sections/{id}/move-up", handler.SectionMoveUp(db))
 	authed.HandleFunc("POST /sections/{id}/move-down", handler.SectionMoveDown(db))
+	authed.HandleFunc("POST /patterns/{id}/sections/reorder", handler.SectionReorder(db))
 
 	// Row routes.
 	authed.HandleFunc("GET /sections/{id}/rows/new", handler.RowNewForm(db))
 	authed.HandleFunc("POST /sections/{id}/rows", handler.RowCreate(db))
+	authed.HandleFunc("POST /sections/{id}/rows/range", handler.RowCreateRange(db))
 	authed.HandleFunc("GET /sections/{id}/rows-refresh", handler.RowsRefresh(db))
+	authed.HandleFunc("POST /sections/{id}/rows/reorder", handler.RowReorder(db))
+	authed.HandleFunc("POST /rows/restore", handler.RowRestore(db))
+	authed.HandleFunc("GET /rows/{id}/export.txt", handler.RowExportText(db))
 	authed.HandleFunc("GET /rows/{id}/edit", handler.RowEditForm(db))
 	authed.HandleFunc("GET /rows/{id}/cancel-edit", handler.RowCancelEdit(db))
 	authed.HandleFunc("PUT /rows/{id}", handler.RowUpdate(db))
@@ -85,27 +203,136 @@ func main() {
 
 	// Work session routes.
 	authed.HandleFunc("GET /patterns/{id}/work", handler.WorkStart(db))
+	authed.HandleFunc("POST /patterns/{id}/work/new", handler.WorkNew(db))
 	authed.HandleFunc("POST /sessions/{id}/advance", handler.WorkAdvance(db))
 	authed.HandleFunc("POST /sessions/{id}/undo", handler.WorkUndo(db))
+	authed.HandleFunc("POST /sessions/{id}/redo", handler.WorkRedo(db))
+	authed.HandleFunc("POST /sessions/{id}/bookmark", handler.WorkBookmark(db))
+	authed.HandleFunc("POST /sessions/{id}/bookmark/jump", handler.WorkBookmarkJump(db))
+	authed.HandleFunc("POST /sessions/{id}/restart", handler.WorkRestart(db))
+	authed.HandleFunc("POST /sessions/{id}/pause", handler.WorkPause(db))
+	authed.HandleFunc("POST /sessions/{id}/resume", handler.WorkResume(db))
+	authed.HandleFunc("DELETE /sessions/{id}", handler.WorkDelete(db))
+	authed.HandleFunc("GET /sessions/{id}/export.csv", handler.WorkExportCSV(db))
+	authed.HandleFunc("POST /sessions/{id}/notes", handler.WorkNoteCreate(db))
+	authed.HandleFunc("DELETE /sessions/{id}/notes/{noteID}", handler.WorkNoteDelete(db))
 
 	// Instruction routes.
 	authed.HandleFunc("GET /rows/{id}/instructions/new", handler.InstructionNewForm(db))
 	authed.HandleFunc("GET /rows/{id}/instructions/group/new", handler.InstructionGroupNewForm(db))
 	authed.HandleFunc("GET /rows/{id}/instructions-refresh", handler.InstructionsRefresh(db))
+	authed.HandleFunc("POST /rows/{id}/instructions/reorder", handler.InstructionReorder(db))
 	authed.HandleFunc("POST /rows/{id}/instructions", handler.InstructionCreate(db))
 	authed.HandleFunc("POST /rows/{id}/instructions/group", handler.InstructionGroupCreate(db))
+	authed.HandleFunc("POST /rows/{id}/instructions/group-selected", handler.InstructionGroupSelected(db))
+	authed.HandleFunc("POST /rows/{id}/instructions/clear", handler.InstructionsClear(db))
+	authed.HandleFunc("GET /rows/{id}/instructions/template/new", handler.InstructionTemplateFormShow(db))
+	authed.HandleFunc("POST /rows/{id}/instructions/template/{templateID}", handler.InstructionTemplateInsert(db))
+	authed.HandleFunc("DELETE /rows/{id}/instructions/template/{templateID}", handler.InstructionTemplateDelete(db))
 	authed.HandleFunc("GET /instructions/{id}/edit", handler.InstructionEditForm(db))
 	authed.HandleFunc("GET /instructions/{id}/children/new", handler.InstructionChildNewForm(db))
 	authed.HandleFunc("POST /instructions/{id}/children", handler.InstructionChildCreate(db))
+	authed.HandleFunc("POST /instructions/{id}/children/clear", handler.InstructionChildrenClear(db))
+	authed.HandleFunc("POST /instructions/{id}/save-template", handler.InstructionTemplateSave(db))
 	authed.HandleFunc("PUT /instructions/{id}", handler.InstructionUpdate(db))
 	authed.HandleFunc("DELETE /instructions/{id}", handler.InstructionDelete(db))
 	authed.HandleFunc("POST /instructions/{id}/move-up", handler.InstructionMoveUp(db))
 	authed.HandleFunc("POST /instructions/{id}/move-down", handler.InstructionMoveDown(db))
+	authed.HandleFunc("POST /instructions/{id}/move-into/{groupID}", handler.InstructionMoveIntoGroup(db))
+	authed.HandleFunc("POST /instructions/{id}/move-out", handler.InstructionMoveOutOfGroup(db))
+	authed.HandleFunc("POST /instructions/{id}/ungroup", handler.InstructionUngroup(db))
+
+	// Row marker routes.
+	authed.HandleFunc("GET /rows/{id}/markers", handler.RowMarkerList(db))
+	authed.HandleFunc("POST /rows/{id}/markers", handler.RowMarkerCreate(db))
+	authed.HandleFunc("DELETE /rows/{id}/markers/{markerID}", handler.RowMarkerDelete(db))
 
 	mux.Handle("/", handler.RequireAuth(db, authed))
 
-	fmt.Printf("StitchMap listening on %s\n", *addr)
-	if err := http.ListenAndServe(*addr, mux); err != nil {
+	// JSON API routes (API token or session cookie).
+	api := http.NewServeMux()
+	api.HandleFunc("GET /api/v1/patterns", handler.APIPatternList(db))
+	api.HandleFunc("POST /api/v1/patterns", handler.APIPatternCreate(db))
+	api.HandleFunc("GET /api/v1/patterns/{id}", handler.APIPatternGet(db))
+	api.HandleFunc("PUT /api/v1/patterns/{id}", handler.APIPatternUpdate(db))
+	api.HandleFunc("DELETE /api/v1/patterns/{id}", handler.APIPatternDelete(db))
+	mux.Handle("/api/", handler.RequireAuthAPI(db, api))
+
+	handler.SetSecureCookies(cfg.tls() || cfg.secureCookie)
+
+	srv := &http.Server{Addr: cfg.addr, Handler: metrics.Middleware(handler.LogRequests(logger, mux))}
+	serverErr := make(chan error, 2)
+	go func() {
+		var err error
+		if cfg.tls() {
+			fmt.Printf("StitchMap listening on %s (HTTPS)\n", cfg.addr)
+			err = srv.ListenAndServeTLS(cfg.tlsCert, cfg.tlsKey)
+		} else {
+			fmt.Printf("StitchMap listening on %s\n", cfg.addr)
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	// Optional plain-HTTP listener that only redirects to HTTPS.
+	var redirectSrv *http.Server
+	if cfg.redirectAddr != "" {
+		redirectSrv = &http.Server{Addr: cfg.redirectAddr, Handler: httpsRedirect(cfg.addr)}
+		go func() {
+			fmt.Printf("Redirecting HTTP on %s to HTTPS\n", cfg.redirectAddr)
+			if err := redirectSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- err
+			}
+		}()
+	}
+
+	select {
+	case err := <-serverErr:
 		log.Fatalf("Server error: %v", err)
+	case <-ctx.Done():
+		stop() // a second signal kills the process immediately
+		fmt.Println("Shutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Shutdown error: %v", err)
+		}
+		if redirectSrv != nil {
+			redirectSrv.Shutdown(shutdownCtx)
+		}
 	}
+	wg.Wait()
+}
+
+const (
+	cleanupInterval = time.Hour
+	shutdownTimeout = 10 * time.Second
+)
+
+// cleanupExpired removes expired login sessions, password reset tokens and deleted
+// rows past their undo window.
+func cleanupExpired(db *sql.DB) {
+	if n, err := model.DeleteExpiredSessions(db); err == nil && n > 0 {
+		fmt.Printf("Cleaned up %d expired sessions\n", n)
+	}
+	model.DeleteExpiredPasswordResetTokens(db)
+	model.DeleteExpiredRowTrash(db)
+}
+
+// httpsRedirect sends every request to the same host and path over HTTPS, on the
+// port the TLS listener at tlsAddr uses.
+func httpsRedirect(tlsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(tlsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
 }
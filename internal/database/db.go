@@ -3,12 +3,21 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
 	_ "modernc.org/sqlite"
 )
 
 func Open(path string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite", path)
+	// Transactions take the write lock up front (BEGIN IMMEDIATE) so read-then-write
+	// sequences like position swaps can't interleave; concurrent writers wait on the
+	// busy timeout instead of failing straight away. foreign_keys is a per-connection
+	// pragma, so it goes in the DSN to be set on every pooled connection.
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite", path+sep+"_txlock=immediate&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
@@ -19,11 +28,5 @@ func Open(path string) (*sql.DB, error) {
 		return nil, fmt.Errorf("set WAL mode: %w", err)
 	}
 
-	// Enable foreign key enforcement.
-	if _, err := db.Exec("PRAGMA foreign_keys=ON"); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("enable foreign keys: %w", err)
-	}
-
 	return db, nil
 }
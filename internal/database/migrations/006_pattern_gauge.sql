@@ -0,0 +1,3 @@
+-- Gauge is recorded over a standard 4 in (10 cm) swatch; 0 means not set.
+ALTER TABLE patterns ADD COLUMN gauge_stitches INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE patterns ADD COLUMN gauge_rows INTEGER NOT NULL DEFAULT 0;
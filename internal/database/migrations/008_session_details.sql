@@ -0,0 +1,4 @@
+ALTER TABLE sessions ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN last_seen_at TEXT NOT NULL DEFAULT '';
+
+UPDATE sessions SET last_seen_at = created_at WHERE last_seen_at = '';
@@ -0,0 +1,8 @@
+-- Free-form category used to group stitches in lists and pickers; '' means uncategorized.
+ALTER TABLE stitches ADD COLUMN category TEXT NOT NULL DEFAULT '';
+
+UPDATE stitches SET category = 'Foundation' WHERE user_id IS NULL AND abbreviation IN ('ch', 'sl st', 'MR');
+UPDATE stitches SET category = 'Basic'      WHERE user_id IS NULL AND abbreviation IN ('sc', 'hdc', 'dc', 'tr');
+UPDATE stitches SET category = 'Increases'  WHERE user_id IS NULL AND abbreviation = 'inc';
+UPDATE stitches SET category = 'Decreases'  WHERE user_id IS NULL AND abbreviation = 'dec';
+UPDATE stitches SET category = 'Post'       WHERE user_id IS NULL AND abbreviation IN ('FPdc', 'BPdc');
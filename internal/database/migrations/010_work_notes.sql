@@ -0,0 +1,13 @@
+-- Journal notes taken while working a pattern. Notes belong to the pattern as well as the
+-- session they were written in, so they survive the session being completed or removed.
+CREATE TABLE work_notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    pattern_id INTEGER NOT NULL REFERENCES patterns(id) ON DELETE CASCADE,
+    session_id INTEGER REFERENCES work_sessions(id) ON DELETE SET NULL,
+    context TEXT NOT NULL DEFAULT '',
+    text TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE INDEX idx_work_notes_pattern ON work_notes(user_id, pattern_id);
@@ -0,0 +1,7 @@
+-- Allow several active sessions of the same pattern (e.g. two hats at once),
+-- told apart by an optional label.
+DROP INDEX IF EXISTS idx_work_sessions_active;
+
+ALTER TABLE work_sessions ADD COLUMN label TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_work_sessions_pattern ON work_sessions(user_id, pattern_id);
@@ -0,0 +1,27 @@
+-- Count-only rows have no instructions of their own; work mode treats each repeat
+-- of such a row as a single step.
+ALTER TABLE rows ADD COLUMN count_only INTEGER NOT NULL DEFAULT 0;
+
+-- Progress on a count-only row has no instruction, so instruction_id must be nullable.
+CREATE TABLE work_progress_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER UNIQUE NOT NULL REFERENCES work_sessions(id) ON DELETE CASCADE,
+    section_id INTEGER NOT NULL REFERENCES pattern_sections(id),
+    row_id INTEGER NOT NULL REFERENCES rows(id),
+    row_repeat_index INTEGER NOT NULL DEFAULT 0,
+    instruction_id INTEGER REFERENCES row_instructions(id),
+    stitch_index INTEGER NOT NULL DEFAULT 0,
+    group_repeat_index INTEGER NOT NULL DEFAULT 0,
+    stitches_completed_in_row INTEGER NOT NULL DEFAULT 0,
+    updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+INSERT INTO work_progress_new
+    (id, session_id, section_id, row_id, row_repeat_index, instruction_id,
+     stitch_index, group_repeat_index, stitches_completed_in_row, updated_at)
+SELECT id, session_id, section_id, row_id, row_repeat_index, instruction_id,
+       stitch_index, group_repeat_index, stitches_completed_in_row, updated_at
+FROM work_progress;
+
+DROP TABLE work_progress;
+ALTER TABLE work_progress_new RENAME TO work_progress;
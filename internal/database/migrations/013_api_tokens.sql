@@ -0,0 +1,13 @@
+-- Personal API tokens for the JSON API. Only a SHA-256 hash of each token is stored;
+-- the raw token is shown to the user once when it is created.
+CREATE TABLE api_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    label TEXT NOT NULL DEFAULT '',
+    token_hash TEXT UNIQUE NOT NULL,
+    prefix TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    last_used_at TEXT
+);
+
+CREATE INDEX idx_api_tokens_user_id ON api_tokens(user_id);
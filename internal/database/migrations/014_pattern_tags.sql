@@ -0,0 +1,16 @@
+-- Per-user tags for organizing patterns. Names are matched case-insensitively,
+-- so "Gift" and "gift" are the same tag.
+CREATE TABLE tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL COLLATE NOCASE,
+    UNIQUE(user_id, name)
+);
+
+CREATE TABLE pattern_tags (
+    pattern_id INTEGER NOT NULL REFERENCES patterns(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (pattern_id, tag_id)
+);
+
+CREATE INDEX idx_pattern_tags_tag_id ON pattern_tags(tag_id);
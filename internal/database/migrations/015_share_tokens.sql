@@ -0,0 +1,8 @@
+-- Read-only share links. Anyone holding the token can view the pattern without an account.
+CREATE TABLE share_tokens (
+    token TEXT PRIMARY KEY,
+    pattern_id INTEGER NOT NULL REFERENCES patterns(id) ON DELETE CASCADE,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE INDEX idx_share_tokens_pattern_id ON share_tokens(pattern_id);
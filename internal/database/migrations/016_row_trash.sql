@@ -0,0 +1,9 @@
+-- Recently deleted rows, kept briefly so a delete can be undone. data holds a JSON
+-- snapshot of the row and its instruction tree.
+CREATE TABLE row_trash (
+    token TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    section_id INTEGER NOT NULL,
+    data TEXT NOT NULL,
+    expires_at TEXT NOT NULL
+);
@@ -0,0 +1,11 @@
+-- Active working time per row (each repeat of a repeated row counts separately),
+-- used to estimate how long the rest of a pattern will take.
+CREATE TABLE row_timings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER NOT NULL REFERENCES work_sessions(id) ON DELETE CASCADE,
+    row_id INTEGER NOT NULL,
+    row_repeat_index INTEGER NOT NULL DEFAULT 0,
+    active_seconds INTEGER NOT NULL DEFAULT 0,
+    completed_at TEXT,
+    UNIQUE(session_id, row_id, row_repeat_index)
+);
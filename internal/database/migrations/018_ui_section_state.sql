@@ -0,0 +1,7 @@
+-- Sections a user has collapsed on the pattern editor. A row means collapsed;
+-- sections without one render expanded.
+CREATE TABLE ui_section_state (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    section_id INTEGER NOT NULL REFERENCES pattern_sections(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, section_id)
+);
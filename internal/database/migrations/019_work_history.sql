@@ -0,0 +1,16 @@
+-- Positions undone in work mode, newest last, so an undo can be redone. Cleared
+-- whenever the session advances normally and capped per session.
+CREATE TABLE work_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER NOT NULL REFERENCES work_sessions(id) ON DELETE CASCADE,
+    section_id INTEGER NOT NULL,
+    row_id INTEGER NOT NULL,
+    row_repeat_index INTEGER NOT NULL,
+    instruction_id INTEGER,
+    stitch_index INTEGER NOT NULL,
+    group_repeat_index INTEGER NOT NULL,
+    stitches_completed_in_row INTEGER NOT NULL,
+    session_completed INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_work_history_session ON work_history(session_id, id);
@@ -0,0 +1,2 @@
+-- Favorite patterns are pinned to the top of the dashboard.
+ALTER TABLE patterns ADD COLUMN is_favorite INTEGER NOT NULL DEFAULT 0;
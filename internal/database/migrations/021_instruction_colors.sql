@@ -0,0 +1,3 @@
+-- Yarn color for colorwork (intarsia, tapestry). Empty means no color change:
+-- a group's children inherit the group's color.
+ALTER TABLE row_instructions ADD COLUMN color TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+-- Markers flag a stitch within a row (a color change, a pocket opening, ...) so work
+-- mode can call attention to it. at_stitch is 1-based within one repeat of the row.
+CREATE TABLE row_markers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    row_id INTEGER NOT NULL REFERENCES rows(id) ON DELETE CASCADE,
+    at_stitch INTEGER NOT NULL DEFAULT 1,
+    note TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE INDEX idx_row_markers_row_id ON row_markers(row_id);
@@ -0,0 +1,2 @@
+-- Whether work mode counts stitches up ("12 / 48") or down ("36 left").
+ALTER TABLE users ADD COLUMN work_count_direction TEXT NOT NULL DEFAULT 'up';
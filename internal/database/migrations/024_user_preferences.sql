@@ -0,0 +1,13 @@
+-- Per-user preferences as a JSON object. New preferences only need a field on
+-- model.Prefs with a default; users without a row, or without the key, get the default.
+CREATE TABLE user_preferences (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    data TEXT NOT NULL DEFAULT '{}'
+);
+
+-- Carry over the counter direction, which used to be a column on users.
+INSERT INTO user_preferences (user_id, data)
+    SELECT id, json_object('count_direction', work_count_direction)
+    FROM users WHERE work_count_direction <> 'up';
+
+ALTER TABLE users DROP COLUMN work_count_direction;
@@ -0,0 +1,6 @@
+-- How many stitches of the previous row an instruction works into. NULL means one
+-- per stitch made, i.e. the same as count.
+ALTER TABLE row_instructions ADD COLUMN consumes INTEGER;
+
+-- Opt-in per row: check that the row works into exactly the previous row's stitches.
+ALTER TABLE rows ADD COLUMN check_previous INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Optional descriptive details about a pattern. Empty means not set.
+ALTER TABLE patterns ADD COLUMN difficulty TEXT NOT NULL DEFAULT '';
+ALTER TABLE patterns ADD COLUMN hook_size TEXT NOT NULL DEFAULT '';
+ALTER TABLE patterns ADD COLUMN yarn_weight TEXT NOT NULL DEFAULT '';
+ALTER TABLE patterns ADD COLUMN finished_size TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,12 @@
+-- Named, reusable instruction snippets ("2 sc, inc" repeats). data is the JSON of the
+-- instruction tree with stitches referenced by abbreviation, so a template can be
+-- inserted into any of the user's patterns.
+CREATE TABLE instruction_templates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    data TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE UNIQUE INDEX idx_instruction_templates_name ON instruction_templates(user_id, name);
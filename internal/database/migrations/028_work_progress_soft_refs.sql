@@ -0,0 +1,26 @@
+-- Progress is repaired after pattern edits (see RepairPatternSessions), so it no
+-- longer holds foreign keys into the pattern's structure: with them, deleting the
+-- section, row or instruction a session is on failed outright. Like work_history,
+-- the pattern columns are now plain IDs.
+CREATE TABLE work_progress_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER UNIQUE NOT NULL REFERENCES work_sessions(id) ON DELETE CASCADE,
+    section_id INTEGER NOT NULL,
+    row_id INTEGER NOT NULL,
+    row_repeat_index INTEGER NOT NULL DEFAULT 0,
+    instruction_id INTEGER,
+    stitch_index INTEGER NOT NULL DEFAULT 0,
+    group_repeat_index INTEGER NOT NULL DEFAULT 0,
+    stitches_completed_in_row INTEGER NOT NULL DEFAULT 0,
+    updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+INSERT INTO work_progress_new
+    (id, session_id, section_id, row_id, row_repeat_index, instruction_id,
+     stitch_index, group_repeat_index, stitches_completed_in_row, updated_at)
+SELECT id, session_id, section_id, row_id, row_repeat_index, instruction_id,
+       stitch_index, group_repeat_index, stitches_completed_in_row, updated_at
+FROM work_progress;
+
+DROP TABLE work_progress;
+ALTER TABLE work_progress_new RENAME TO work_progress;
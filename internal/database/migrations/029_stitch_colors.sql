@@ -0,0 +1,3 @@
+-- Display color for a stitch kind, as #rrggbb, used to tint its chips in pattern
+-- views. Empty means no tint. Unrelated to the yarn color on instructions.
+ALTER TABLE stitches ADD COLUMN color TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- When a session was explicitly paused; NULL while it is being worked. Resuming
+-- clears it.
+ALTER TABLE work_sessions ADD COLUMN paused_at TEXT;
@@ -0,0 +1,12 @@
+-- Emails are now stored lowercased so login is case-insensitive. Accounts whose
+-- emails differ only in case are left alone, since lowercasing them would break the
+-- unique constraint; the server reports them at startup. SQLite's lower() only
+-- folds ASCII, so emails with other characters are left for the server to
+-- normalize at startup (model.NormalizeStoredEmails).
+UPDATE users SET email = lower(trim(email))
+WHERE email != lower(trim(email))
+  AND email NOT GLOB '*[^ -~]*'
+  AND NOT EXISTS (
+      SELECT 1 FROM users other
+      WHERE other.id != users.id AND lower(trim(other.email)) = lower(trim(users.email))
+  );
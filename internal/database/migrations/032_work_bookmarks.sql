@@ -0,0 +1,13 @@
+-- One saved position per work session, so the crafter can try something further
+-- on and jump back. Replaced whenever a new bookmark is set.
+CREATE TABLE work_bookmarks (
+    session_id INTEGER PRIMARY KEY REFERENCES work_sessions(id) ON DELETE CASCADE,
+    section_id INTEGER NOT NULL,
+    row_id INTEGER NOT NULL,
+    row_repeat_index INTEGER NOT NULL,
+    instruction_id INTEGER,
+    stitch_index INTEGER NOT NULL,
+    group_repeat_index INTEGER NOT NULL,
+    stitches_completed_in_row INTEGER NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
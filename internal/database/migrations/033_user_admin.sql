@@ -0,0 +1,7 @@
+-- Admins can see every account and disable abusive ones. A disabled user can't log
+-- in or use the API until re-enabled.
+ALTER TABLE users ADD COLUMN is_admin INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN disabled_at TEXT;
+
+-- The first account on an existing instance becomes its admin.
+UPDATE users SET is_admin = 1 WHERE id = (SELECT MIN(id) FROM users);
@@ -0,0 +1,5 @@
+-- An instruction worked "to end" takes its count from the stitches of the previous
+-- row that the rest of the row leaves over, worked out when the row is loaded; count
+-- is only a placeholder while to_end is set. Clearing to_end fixes count at the
+-- value it last resolved to.
+ALTER TABLE row_instructions ADD COLUMN to_end INTEGER NOT NULL DEFAULT 0;
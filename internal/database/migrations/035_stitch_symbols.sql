@@ -0,0 +1,3 @@
+-- A chart symbol for each stitch, shown instead of the abbreviation for users who
+-- prefer symbol notation. Built-in symbols are filled in by SeedBuiltinStitches.
+ALTER TABLE stitches ADD COLUMN symbol TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+-- Yarn over was seeded with the chain's symbol, so the two read the same in symbol
+-- notation. It now uses the knitting chart O; a fresh database gets it from
+-- SeedBuiltinStitches.
+UPDATE stitches SET symbol = 'O' WHERE user_id IS NULL AND abbreviation = 'yo' AND symbol = '○';
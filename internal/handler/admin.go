@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/stitchmap/stitchmap/internal/model"
+	"github.com/stitchmap/stitchmap/internal/view"
+)
+
+// AdminUsers handles GET /admin/users, listing every account. Routes under /admin
+// are wrapped in RequireAdmin.
+func AdminUsers(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		renderAdminUsers(w, r, db, http.StatusOK, "")
+	}
+}
+
+func renderAdminUsers(w http.ResponseWriter, r *http.Request, db *sql.DB, status int, errMsg string) {
+	user := UserFromContext(r.Context())
+	users, err := model.ListUsersForAdmin(db)
+	if err != nil {
+		http.Error(w, "Failed to load users", http.StatusInternalServerError)
+		return
+	}
+	renderTempl(w, r, status, view.AdminUsersPage(view.AdminUsersData{
+		Email:   user.Email,
+		AdminID: user.ID,
+		Users:   users,
+		Error:   errMsg,
+	}))
+}
+
+// AdminUserDisable handles POST /admin/users/{id}/disable. The user is logged out
+// everywhere and can't log back in until re-enabled.
+func AdminUserDisable(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin := UserFromContext(r.Context())
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		err = model.DisableUser(db, admin.ID, id)
+		switch {
+		case errors.Is(err, model.ErrDisableSelf):
+			renderAdminUsers(w, r, db, http.StatusUnprocessableEntity, "You can't disable your own account.")
+			return
+		case errors.Is(err, sql.ErrNoRows):
+			http.NotFound(w, r)
+			return
+		case err != nil:
+			http.Error(w, "Failed to disable user", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+	}
+}
+
+// AdminUserEnable handles POST /admin/users/{id}/enable.
+func AdminUserEnable(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err := model.EnableUser(db, id); errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			http.Error(w, "Failed to enable user", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+	}
+}
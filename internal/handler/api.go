@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stitchmap/stitchmap/internal/model"
+)
+
+// --- JSON API (/api/v1) ---
+//
+// The response types below are the API contract. They are kept separate from the model
+// structs so that schema changes don't leak into clients; a whole pattern is served as
+// model.PatternJSON, the format backups use.
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type apiPatternSummary struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	SectionCount int       `json:"section_count"`
+	RowCount     int       `json:"row_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// apiPatternInput is the request body for creating or replacing a pattern.
+type apiPatternInput struct {
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	GaugeStitches int    `json:"gauge_stitches"`
+	GaugeRows     int    `json:"gauge_rows"`
+	Difficulty    string `json:"difficulty"`
+	HookSize      string `json:"hook_size"`
+	YarnWeight    string `json:"yarn_weight"`
+	FinishedSize  string `json:"finished_size"`
+}
+
+func (in *apiPatternInput) meta() model.PatternMeta {
+	return model.PatternMeta{
+		Difficulty:   in.Difficulty,
+		HookSize:     in.HookSize,
+		YarnWeight:   in.YarnWeight,
+		FinishedSize: in.FinishedSize,
+	}
+}
+
+func (in *apiPatternInput) validate() error {
+	in.Name = strings.TrimSpace(in.Name)
+	in.Description = strings.TrimSpace(in.Description)
+	if in.Name == "" {
+		return errors.New("name is required")
+	}
+	if in.GaugeStitches < 0 || in.GaugeRows < 0 {
+		return errors.New("gauge must not be negative")
+	}
+	meta := in.meta()
+	if err := meta.Normalize(); err != nil {
+		return fmt.Errorf("difficulty must be one of %s, and other details at most %d characters",
+			strings.Join(model.ValidDifficulties, ", "), model.MaxPatternMetaLength)
+	}
+	in.Difficulty, in.HookSize, in.YarnWeight, in.FinishedSize = meta.Difficulty, meta.HookSize, meta.YarnWeight, meta.FinishedSize
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, apiError{Error: msg})
+}
+
+// loadOwnedPattern resolves the {id} path value to a fully loaded pattern owned by the user,
+// writing the appropriate JSON error and returning ok=false otherwise.
+func loadOwnedPattern(w http.ResponseWriter, r *http.Request, db *sql.DB, userID int64) (*model.Pattern, []model.PatternSection, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid pattern id")
+		return nil, nil, false
+	}
+	pattern, sections, err := model.LoadPatternFull(db, id)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, "pattern not found")
+		return nil, nil, false
+	}
+	if pattern.UserID != userID {
+		writeAPIError(w, http.StatusForbidden, "forbidden")
+		return nil, nil, false
+	}
+	return pattern, sections, true
+}
+
+// APIPatternList handles GET /api/v1/patterns.
+func APIPatternList(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+
+		patterns, err := model.ListPatternsByUser(db, user.ID)
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "failed to list patterns")
+			return
+		}
+
+		out := []apiPatternSummary{}
+		for _, p := range patterns {
+			out = append(out, apiPatternSummary{
+				ID:           p.ID,
+				Name:         p.Name,
+				Description:  p.Description,
+				SectionCount: p.SectionCount,
+				RowCount:     p.RowCount,
+				CreatedAt:    p.CreatedAt,
+				UpdatedAt:    p.UpdatedAt,
+			})
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// APIPatternGet handles GET /api/v1/patterns/{id}, including nested sections, rows and instructions.
+func APIPatternGet(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+
+		pattern, sections, ok := loadOwnedPattern(w, r, db, user.ID)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, model.NewPatternJSON(pattern, sections))
+	}
+}
+
+// APIPatternCreate handles POST /api/v1/patterns.
+func APIPatternCreate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+
+		var in apiPatternInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if err := in.validate(); err != nil {
+			writeAPIError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+
+		pattern, err := model.CreatePattern(db, user.ID, in.Name, in.Description, in.meta())
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "failed to create pattern")
+			return
+		}
+		if in.GaugeStitches > 0 || in.GaugeRows > 0 {
+			if err := model.UpdatePattern(db, pattern.ID, user.ID, in.Name, in.Description, in.GaugeStitches, in.GaugeRows, in.meta()); err != nil {
+				writeAPIError(w, http.StatusInternalServerError, "failed to create pattern")
+				return
+			}
+		}
+
+		pattern, sections, err := model.LoadPatternFull(db, pattern.ID)
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "failed to load pattern")
+			return
+		}
+		w.Header().Set("Location", "/api/v1/patterns/"+strconv.FormatInt(pattern.ID, 10))
+		writeJSON(w, http.StatusCreated, model.NewPatternJSON(pattern, sections))
+	}
+}
+
+// APIPatternUpdate handles PUT /api/v1/patterns/{id}. The body replaces the pattern's
+// name, description, gauge and details; sections are edited through the HTML UI for now.
+func APIPatternUpdate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+
+		pattern, _, ok := loadOwnedPattern(w, r, db, user.ID)
+		if !ok {
+			return
+		}
+
+		var in apiPatternInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if err := in.validate(); err != nil {
+			writeAPIError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+
+		if err := model.UpdatePattern(db, pattern.ID, user.ID, in.Name, in.Description, in.GaugeStitches, in.GaugeRows, in.meta()); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "failed to update pattern")
+			return
+		}
+
+		pattern, sections, err := model.LoadPatternFull(db, pattern.ID)
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "failed to load pattern")
+			return
+		}
+		writeJSON(w, http.StatusOK, model.NewPatternJSON(pattern, sections))
+	}
+}
+
+// APIPatternDelete handles DELETE /api/v1/patterns/{id}.
+func APIPatternDelete(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+
+		pattern, _, ok := loadOwnedPattern(w, r, db, user.ID)
+		if !ok {
+			return
+		}
+
+		if err := model.DeletePattern(db, pattern.ID, user.ID); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "failed to delete pattern")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -2,7 +2,12 @@ package handler
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/a-h/templ"
@@ -13,28 +18,55 @@ import (
 const sessionCookieName = "session"
 const sessionMaxAge = 30 * 24 * 60 * 60 // 30 days in seconds
 
-func setSessionCookie(w http.ResponseWriter, token string) {
+// secureCookies marks the session cookie Secure on every request; see SetSecureCookies.
+var secureCookies bool
+
+// SetSecureCookies sets whether the session cookie is always marked Secure, so
+// browsers only send it over HTTPS. Without it, the cookie is still Secure on
+// requests that arrived over HTTPS, directly or through a TLS-terminating proxy.
+// Call it once at startup, before serving.
+func SetSecureCookies(secure bool) {
+	secureCookies = secure
+}
+
+// cookieSecure reports whether cookies set in response to r should be Secure.
+func cookieSecure(r *http.Request) bool {
+	return secureCookies || r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+func setSessionCookie(w http.ResponseWriter, r *http.Request, token string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     sessionCookieName,
 		Value:    token,
 		Path:     "/",
 		MaxAge:   sessionMaxAge,
 		HttpOnly: true,
+		Secure:   cookieSecure(r),
 		SameSite: http.SameSiteLaxMode,
 	})
 }
 
-func clearSessionCookie(w http.ResponseWriter) {
+func clearSessionCookie(w http.ResponseWriter, r *http.Request) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     sessionCookieName,
 		Value:    "",
 		Path:     "/",
 		MaxAge:   -1,
 		HttpOnly: true,
+		Secure:   cookieSecure(r),
 		SameSite: http.SameSiteLaxMode,
 	})
 }
 
+// baseURL returns the scheme and host the request was made to, for building absolute links.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
 func renderTempl(w http.ResponseWriter, r *http.Request, status int, component templ.Component) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(status)
@@ -48,48 +80,114 @@ func LoginPage(db *sql.DB) http.HandlerFunc {
 	}
 }
 
+// authInput is the body of a login or register post: form fields from the HTML
+// pages, or the same fields as JSON from fetch clients.
+type authInput struct {
+	Email           string `json:"email"`
+	Password        string `json:"password"`
+	PasswordConfirm string `json:"password_confirm"`
+}
+
+func readAuthInput(r *http.Request) authInput {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var in authInput
+		json.NewDecoder(r.Body).Decode(&in)
+		return in
+	}
+	return authInput{
+		Email:           r.FormValue("email"),
+		Password:        r.FormValue("password"),
+		PasswordConfirm: r.FormValue("password_confirm"),
+	}
+}
+
+// authResponse is the JSON reply to an auth post from a fetch client.
+type authResponse struct {
+	OK       bool   `json:"ok"`
+	Redirect string `json:"redirect,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// wantsJSON reports whether an auth post came from a fetch client (a JSON Accept
+// header, or a Datastar request) that can't follow a redirect to a full page.
+func wantsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json") ||
+		r.Header.Get("Datastar-Request") == "true"
+}
+
+// authRedirect ends a successful auth post: a 303 to target for form posts, or
+// {"ok":true,"redirect":target} for fetch clients. Cookies set before it go out
+// either way.
+func authRedirect(w http.ResponseWriter, r *http.Request, target string) {
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, authResponse{OK: true, Redirect: target})
+		return
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// authError re-renders an auth page with data.Error, or reports the error as JSON
+// to fetch clients.
+func authError(w http.ResponseWriter, r *http.Request, status int, page func(view.AuthPageData) templ.Component, data view.AuthPageData) {
+	if wantsJSON(r) {
+		writeJSON(w, status, authResponse{Error: data.Error})
+		return
+	}
+	renderTempl(w, r, status, page(data))
+}
+
 // Login handles POST /login.
 func Login(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		email := strings.TrimSpace(r.FormValue("email"))
-		password := r.FormValue("password")
+		in := readAuthInput(r)
+		email, password := strings.TrimSpace(in.Email), in.Password
 
 		if email == "" || password == "" {
-			renderTempl(w, r, http.StatusUnprocessableEntity, view.LoginPage(view.AuthPageData{
+			authError(w, r, http.StatusUnprocessableEntity, view.LoginPage, view.AuthPageData{
 				Error: "Email and password are required.",
 				Email: email,
-			}))
+			})
 			return
 		}
 
 		user, err := model.FindUserByEmail(db, email)
 		if err != nil {
-			renderTempl(w, r, http.StatusUnprocessableEntity, view.LoginPage(view.AuthPageData{
+			authError(w, r, http.StatusUnprocessableEntity, view.LoginPage, view.AuthPageData{
 				Error: "Invalid email or password.",
 				Email: email,
-			}))
+			})
 			return
 		}
 
 		if !model.CheckPassword(user, password) {
-			renderTempl(w, r, http.StatusUnprocessableEntity, view.LoginPage(view.AuthPageData{
+			authError(w, r, http.StatusUnprocessableEntity, view.LoginPage, view.AuthPageData{
 				Error: "Invalid email or password.",
 				Email: email,
-			}))
+			})
 			return
 		}
+		if user.Disabled() {
+			authError(w, r, http.StatusForbidden, view.LoginPage, view.AuthPageData{
+				Error: "This account has been disabled.",
+				Email: email,
+			})
+			return
+		}
+		if err := model.RehashPasswordIfNeeded(db, user, password); err != nil {
+			log.Printf("login: %v", err)
+		}
 
-		session, err := model.CreateSession(db, user.ID)
+		session, err := model.CreateSession(db, user.ID, r.UserAgent())
 		if err != nil {
-			renderTempl(w, r, http.StatusInternalServerError, view.LoginPage(view.AuthPageData{
+			authError(w, r, http.StatusInternalServerError, view.LoginPage, view.AuthPageData{
 				Error: "Something went wrong. Please try again.",
 				Email: email,
-			}))
+			})
 			return
 		}
 
-		setSessionCookie(w, session.ID)
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+		setSessionCookie(w, r, session.ID)
+		authRedirect(w, r, "/")
 	}
 }
 
@@ -103,63 +201,62 @@ func RegisterPage(db *sql.DB) http.HandlerFunc {
 // Register handles POST /register.
 func Register(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		email := strings.TrimSpace(r.FormValue("email"))
-		password := r.FormValue("password")
-		passwordConfirm := r.FormValue("password_confirm")
+		in := readAuthInput(r)
+		email, password, passwordConfirm := strings.TrimSpace(in.Email), in.Password, in.PasswordConfirm
 
 		if email == "" || password == "" {
-			renderTempl(w, r, http.StatusUnprocessableEntity, view.RegisterPage(view.AuthPageData{
+			authError(w, r, http.StatusUnprocessableEntity, view.RegisterPage, view.AuthPageData{
 				Error: "Email and password are required.",
 				Email: email,
-			}))
+			})
 			return
 		}
 
-		if len(password) < 8 {
-			renderTempl(w, r, http.StatusUnprocessableEntity, view.RegisterPage(view.AuthPageData{
-				Error: "Password must be at least 8 characters.",
+		if len(password) < model.MinPasswordLength {
+			authError(w, r, http.StatusUnprocessableEntity, view.RegisterPage, view.AuthPageData{
+				Error: fmt.Sprintf("Password must be at least %d characters.", model.MinPasswordLength),
 				Email: email,
-			}))
+			})
 			return
 		}
 
 		if password != passwordConfirm {
-			renderTempl(w, r, http.StatusUnprocessableEntity, view.RegisterPage(view.AuthPageData{
+			authError(w, r, http.StatusUnprocessableEntity, view.RegisterPage, view.AuthPageData{
 				Error: "Passwords do not match.",
 				Email: email,
-			}))
+			})
 			return
 		}
 
 		// Check if email is already taken.
 		if _, err := model.FindUserByEmail(db, email); err == nil {
-			renderTempl(w, r, http.StatusUnprocessableEntity, view.RegisterPage(view.AuthPageData{
+			authError(w, r, http.StatusUnprocessableEntity, view.RegisterPage, view.AuthPageData{
 				Error: "An account with that email already exists.",
 				Email: email,
-			}))
+			})
 			return
 		}
 
 		user, err := model.CreateUser(db, email, password)
 		if err != nil {
-			renderTempl(w, r, http.StatusInternalServerError, view.RegisterPage(view.AuthPageData{
+			authError(w, r, http.StatusInternalServerError, view.RegisterPage, view.AuthPageData{
 				Error: "Something went wrong. Please try again.",
 				Email: email,
-			}))
+			})
 			return
 		}
 
-		session, err := model.CreateSession(db, user.ID)
+		session, err := model.CreateSession(db, user.ID, r.UserAgent())
 		if err != nil {
-			renderTempl(w, r, http.StatusInternalServerError, view.RegisterPage(view.AuthPageData{
+			authError(w, r, http.StatusInternalServerError, view.RegisterPage, view.AuthPageData{
 				Error: "Account created but could not log in. Please try logging in.",
 				Email: email,
-			}))
+			})
 			return
 		}
 
-		setSessionCookie(w, session.ID)
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+		setSessionCookie(w, r, session.ID)
+		authRedirect(w, r, "/")
 	}
 }
 
@@ -170,7 +267,108 @@ func Logout(db *sql.DB) http.HandlerFunc {
 		if err == nil {
 			model.DeleteSession(db, cookie.Value)
 		}
-		clearSessionCookie(w)
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		clearSessionCookie(w, r)
+		authRedirect(w, r, "/login")
+	}
+}
+
+// ForgotPasswordPage renders the "forgot password" form.
+func ForgotPasswordPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		renderTempl(w, r, http.StatusOK, view.ForgotPasswordPage(view.AuthPageData{}))
+	}
+}
+
+// ForgotPassword handles POST /forgot-password.
+// The response is the same whether or not the email exists, so accounts can't be probed.
+// There is no mailer yet; the reset link is logged to stdout.
+func ForgotPassword(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := strings.TrimSpace(r.FormValue("email"))
+
+		if email == "" {
+			renderTempl(w, r, http.StatusUnprocessableEntity, view.ForgotPasswordPage(view.AuthPageData{
+				Error: "Email is required.",
+			}))
+			return
+		}
+
+		if user, err := model.FindUserByEmail(db, email); err == nil {
+			token, err := model.CreatePasswordResetToken(db, user.ID)
+			if err != nil {
+				renderTempl(w, r, http.StatusInternalServerError, view.ForgotPasswordPage(view.AuthPageData{
+					Error: "Something went wrong. Please try again.",
+					Email: email,
+				}))
+				return
+			}
+			fmt.Printf("Password reset link for %s: %s/reset-password?token=%s\n",
+				user.Email, baseURL(r), url.QueryEscape(token))
+		}
+
+		renderTempl(w, r, http.StatusOK, view.ForgotPasswordPage(view.AuthPageData{
+			Notice: "If an account exists for that email, a reset link has been sent. It expires in one hour.",
+		}))
+	}
+}
+
+// ResetPasswordPage renders the new-password form for a reset token.
+func ResetPasswordPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		renderTempl(w, r, http.StatusOK, view.ResetPasswordPage(view.AuthPageData{
+			Token: r.URL.Query().Get("token"),
+		}))
+	}
+}
+
+// ResetPassword handles POST /reset-password.
+func ResetPassword(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.FormValue("token")
+		password := r.FormValue("password")
+		passwordConfirm := r.FormValue("password_confirm")
+
+		// Validate the new password before consuming the token, so a typo doesn't burn the link.
+		if len(password) < model.MinPasswordLength {
+			renderTempl(w, r, http.StatusUnprocessableEntity, view.ResetPasswordPage(view.AuthPageData{
+				Error: fmt.Sprintf("Password must be at least %d characters.", model.MinPasswordLength),
+				Token: token,
+			}))
+			return
+		}
+
+		if password != passwordConfirm {
+			renderTempl(w, r, http.StatusUnprocessableEntity, view.ResetPasswordPage(view.AuthPageData{
+				Error: "Passwords do not match.",
+				Token: token,
+			}))
+			return
+		}
+
+		userID, err := model.ConsumePasswordResetToken(db, token)
+		if err != nil {
+			status := http.StatusInternalServerError
+			msg := "Something went wrong. Please try again."
+			if errors.Is(err, model.ErrInvalidResetToken) {
+				status = http.StatusUnprocessableEntity
+				msg = "This reset link is invalid or has expired. Please request a new one."
+			}
+			renderTempl(w, r, status, view.ForgotPasswordPage(view.AuthPageData{Error: msg}))
+			return
+		}
+
+		if err := model.UpdateUserPassword(db, userID, password); err != nil {
+			renderTempl(w, r, http.StatusInternalServerError, view.ForgotPasswordPage(view.AuthPageData{
+				Error: "Something went wrong. Please try again.",
+			}))
+			return
+		}
+
+		// Sign out every existing session; whoever knew the old password loses access.
+		model.DeleteSessionsForUser(db, userID)
+
+		renderTempl(w, r, http.StatusOK, view.LoginPage(view.AuthPageData{
+			Notice: "Your password has been reset. Please log in.",
+		}))
 	}
 }
@@ -3,7 +3,10 @@ package handler
 import (
 	"database/sql"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/starfederation/datastar-go/datastar"
 	"github.com/stitchmap/stitchmap/internal/model"
 	"github.com/stitchmap/stitchmap/internal/view"
 )
@@ -17,12 +20,68 @@ func Dashboard(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		patterns, _ := model.ListPatternsByUser(db, user.ID)
-		sessions, _ := model.ListSessionSummaries(db, user.ID)
-		renderTempl(w, r, http.StatusOK, view.DashboardPage(view.DashboardData{
-			Email:    user.Email,
-			Patterns: patterns,
-			Sessions: sessions,
-		}))
+		data := loadDashboardPatterns(db, user.ID, r)
+		data.Email = user.Email
+		data.Tags, _ = model.ListTagsForUser(db, user.ID)
+		data.Sessions, _ = model.ListSessionSummaries(db, user.ID)
+		data.Stats, _ = model.UserStats(db, user.ID)
+		renderTempl(w, r, http.StatusOK, view.DashboardPage(data))
+	}
+}
+
+// History renders GET /history: the user's finished projects.
+func History(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		sessions, err := model.ListCompletedSessions(db, user.ID)
+		if err != nil {
+			http.Error(w, "Failed to load history", http.StatusInternalServerError)
+			return
+		}
+		renderTempl(w, r, http.StatusOK, view.HistoryPage(sessions, user.Email))
+	}
+}
+
+// loadDashboardPatterns fills in the pattern list part of the dashboard, honoring
+// the ?tag= and ?sort= query parameters.
+func loadDashboardPatterns(db *sql.DB, userID int64, r *http.Request) view.DashboardData {
+	var data view.DashboardData
+
+	// ?tag= narrows the pattern list to one tag.
+	data.ActiveTag = strings.TrimSpace(r.URL.Query().Get("tag"))
+	if data.ActiveTag != "" {
+		data.Patterns, _ = model.ListPatternsByTag(db, userID, data.ActiveTag)
+	} else {
+		data.Patterns, _ = model.ListPatternsByUser(db, userID)
+	}
+	// ?sort=worked orders by when each pattern was last worked instead of edited.
+	if r.URL.Query().Get("sort") == "worked" {
+		data.SortBy = "worked"
+		model.SortPatternsByLastWorked(data.Patterns)
+	}
+	data.Stitches, _ = model.PatternStitchTotals(db, userID)
+	return data
+}
+
+// PatternToggleFavorite handles POST /patterns/{id}/favorite via SSE.
+// It flips the pattern's favorite flag and re-renders the dashboard pattern list,
+// keeping the tag filter and sort order passed in the query string.
+func PatternToggleFavorite(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		pattern, err := model.FindPatternByID(db, id)
+		if err != nil || pattern.UserID != user.ID {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if err := model.SetPatternFavorite(db, id, user.ID, !pattern.IsFavorite); err != nil {
+			http.Error(w, "Failed to update pattern", http.StatusInternalServerError)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+		sse.PatchElementTempl(view.PatternList(loadDashboardPatterns(db, user.ID, r)))
 	}
 }
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/starfederation/datastar-go/datastar"
+	"github.com/stitchmap/stitchmap/internal/model"
+	"github.com/stitchmap/stitchmap/internal/view"
+)
+
+type saveTemplateSignals struct {
+	Name string `json:"templateName"`
+}
+
+// patchTemplateForm shows the template picker in place of the row's add buttons.
+func patchTemplateForm(sse *datastar.ServerSentEventGenerator, db *sql.DB, rowID, userID int64, notice, errMsg string) {
+	templates, _ := model.ListInstructionTemplates(db, userID)
+	sse.PatchElementTempl(
+		view.InstructionTemplateForm(rowID, templates, notice, errMsg),
+		datastar.WithSelectorID("row-"+strconv.FormatInt(rowID, 10)+"-add-instr"),
+	)
+}
+
+// InstructionTemplateFormShow handles GET /rows/{id}/instructions/template/new via SSE.
+func InstructionTemplateFormShow(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		rowID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		if !rowOwnedBy(db, rowID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+		patchTemplateForm(sse, db, rowID, user.ID, "", "")
+	}
+}
+
+// InstructionTemplateSave handles POST /instructions/{id}/save-template via SSE. It
+// saves a group, with its children, as a template named by the templateName signal.
+func InstructionTemplateSave(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		rowID, ok := checkInstructionOwnership(db, id, user.ID)
+		if !ok {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		signals := &saveTemplateSignals{}
+		if err := datastar.ReadSignals(r, signals); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		name := strings.TrimSpace(signals.Name)
+
+		sse := datastar.NewSSE(w, r)
+
+		group, err := model.FindInstructionByID(db, id)
+		if err != nil || !group.IsGroup {
+			sse.PatchElementTempl(view.PatternError("Only groups can be saved as templates."),
+				datastar.WithSelectorID("pattern-content"), datastar.WithModePrepend())
+			return
+		}
+		group.Children, _ = model.ListChildInstructions(db, id)
+
+		errMsg := ""
+		if name == "" || len(name) > model.MaxTemplateNameLength {
+			errMsg = fmt.Sprintf("Template name must be 1 to %d characters.", model.MaxTemplateNameLength)
+		} else if _, err := model.SaveInstructionTemplate(db, user.ID, name, []model.RowInstruction{*group}); errors.Is(err, model.ErrTemplateNameTaken) {
+			errMsg = "You already have a template with that name."
+		} else if err != nil {
+			errMsg = "Failed to save template."
+		}
+		if errMsg != "" {
+			sse.PatchElementTempl(view.PatternError(errMsg),
+				datastar.WithSelectorID("pattern-content"), datastar.WithModePrepend())
+			return
+		}
+
+		sse.RemoveElementByID("pattern-error")
+		patchTemplateForm(sse, db, rowID, user.ID, fmt.Sprintf("Saved “%s”. Insert it into any row from “+ From Template”.", name), "")
+	}
+}
+
+// InstructionTemplateInsert handles POST /rows/{id}/instructions/template/{templateID}
+// via SSE, appending the template's instructions to the row.
+func InstructionTemplateInsert(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		rowID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		templateID, _ := strconv.ParseInt(r.PathValue("templateID"), 10, 64)
+
+		patternID, err := model.GetPatternIDForRow(db, rowID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if !checkPatternOwnership(db, patternID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if _, err := model.FindInstructionTemplate(db, templateID, user.ID); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		if err := model.InsertTemplateIntoRow(db, rowID, templateID); err != nil {
+			patchTemplateForm(sse, db, rowID, user.ID, "", "Failed to insert template.")
+			return
+		}
+
+		refreshRowInstructions(sse, db, rowID, patternID)
+	}
+}
+
+// InstructionTemplateDelete handles DELETE /rows/{id}/instructions/template/{templateID}
+// via SSE. The row is only where the picker is open; the template is removed for good.
+func InstructionTemplateDelete(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		rowID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		templateID, _ := strconv.ParseInt(r.PathValue("templateID"), 10, 64)
+
+		if !rowOwnedBy(db, rowID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		errMsg := ""
+		if err := model.DeleteInstructionTemplate(db, templateID, user.ID); err != nil {
+			errMsg = "Failed to delete template."
+		}
+		patchTemplateForm(sse, db, rowID, user.ID, "", errMsg)
+	}
+}
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -32,9 +34,12 @@ type addInstrSignals struct {
 	Count    string `json:"addInstrCount"`
 	Into     string `json:"addInstrInto"`
 	Note     string `json:"addInstrNote"`
+	Color    string `json:"addInstrColor"`
+	Consumes string `json:"addInstrConsumes"`
+	ToEnd    bool   `json:"addInstrToEnd"`
 }
 
-func (s *addInstrSignals) parse() (stitchID *int64, count int, into, note string) {
+func (s *addInstrSignals) parse() (stitchID *int64, count int, into, note, color string, consumes *int, err error) {
 	if id, err := strconv.ParseInt(strings.TrimSpace(s.StitchID), 10, 64); err == nil && id > 0 {
 		stitchID = &id
 	}
@@ -44,6 +49,9 @@ func (s *addInstrSignals) parse() (stitchID *int64, count int, into, note string
 	}
 	into = strings.TrimSpace(s.Into)
 	note = strings.TrimSpace(s.Note)
+	color = strings.TrimSpace(s.Color)
+	consumes = parseConsumes(s.Consumes)
+	err = model.CheckInstructionCounts(count, 1, consumes)
 	return
 }
 
@@ -52,9 +60,11 @@ type addChildSignals struct {
 	Count    string `json:"childInstrCount"`
 	Into     string `json:"childInstrInto"`
 	Note     string `json:"childInstrNote"`
+	Color    string `json:"childInstrColor"`
+	Consumes string `json:"childInstrConsumes"`
 }
 
-func (s *addChildSignals) parse() (stitchID *int64, count int, into, note string) {
+func (s *addChildSignals) parse() (stitchID *int64, count int, into, note, color string, consumes *int, err error) {
 	if id, err := strconv.ParseInt(strings.TrimSpace(s.StitchID), 10, 64); err == nil && id > 0 {
 		stitchID = &id
 	}
@@ -64,6 +74,9 @@ func (s *addChildSignals) parse() (stitchID *int64, count int, into, note string
 	}
 	into = strings.TrimSpace(s.Into)
 	note = strings.TrimSpace(s.Note)
+	color = strings.TrimSpace(s.Color)
+	consumes = parseConsumes(s.Consumes)
+	err = model.CheckInstructionCounts(count, 1, consumes)
 	return
 }
 
@@ -72,9 +85,12 @@ type editInstrSignals struct {
 	Count    string `json:"editInstrCount"`
 	Into     string `json:"editInstrInto"`
 	Note     string `json:"editInstrNote"`
+	Color    string `json:"editInstrColor"`
+	Consumes string `json:"editInstrConsumes"`
+	ToEnd    bool   `json:"editInstrToEnd"`
 }
 
-func (s *editInstrSignals) parse() (stitchID *int64, count int, into, note string) {
+func (s *editInstrSignals) parse() (stitchID *int64, count int, into, note, color string, consumes *int, err error) {
 	if id, err := strconv.ParseInt(strings.TrimSpace(s.StitchID), 10, 64); err == nil && id > 0 {
 		stitchID = &id
 	}
@@ -84,17 +100,66 @@ func (s *editInstrSignals) parse() (stitchID *int64, count int, into, note strin
 	}
 	into = strings.TrimSpace(s.Into)
 	note = strings.TrimSpace(s.Note)
+	color = strings.TrimSpace(s.Color)
+	consumes = parseConsumes(s.Consumes)
+	err = model.CheckInstructionCounts(count, 1, consumes)
 	return
 }
 
+// countTooLarge reports err, if it is model.ErrCountTooLarge, above the pattern
+// sections and returns true.
+func countTooLarge(sse *datastar.ServerSentEventGenerator, err error) bool {
+	if !errors.Is(err, model.ErrCountTooLarge) {
+		return false
+	}
+	sse.PatchElementTempl(view.PatternError(fmt.Sprintf(
+		"Counts are limited to %d stitches and %d group repeats.", model.MaxInstructionCount, model.MaxGroupRepeat)),
+		datastar.WithSelectorID("pattern-content"), datastar.WithModePrepend())
+	return true
+}
+
+// toEndErrorMessage explains why an instruction can't be worked "to end", or
+// returns fallback for any other error.
+func toEndErrorMessage(err error, fallback string) string {
+	switch {
+	case errors.Is(err, model.ErrToEndInGroup):
+		return "Only a stitch at the top of a row can be worked to end."
+	case errors.Is(err, model.ErrToEndNoStitch):
+		return "Choose a stitch to work to end."
+	}
+	return fallback
+}
+
+// parseConsumes reads an optional "works into" count. Blank or invalid means unset,
+// i.e. the instruction works into one stitch per stitch made.
+func parseConsumes(s string) *int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return nil
+	}
+	return &n
+}
+
 type addGroupSignals struct {
 	Repeat string `json:"addGrpRepeat"`
 	Note   string `json:"addGrpNote"`
+	Color  string `json:"addGrpColor"`
 }
 
 type editGroupSignals struct {
 	Repeat string `json:"editGrpRepeat"`
 	Note   string `json:"editGrpNote"`
+	Color  string `json:"editGrpColor"`
+}
+
+// colorTooLong reports an over-long instruction color above the pattern sections.
+func colorTooLong(sse *datastar.ServerSentEventGenerator, color string) bool {
+	if len(color) <= model.MaxInstructionColorLength {
+		return false
+	}
+	sse.PatchElementTempl(view.PatternError(fmt.Sprintf("Color must be %d characters or fewer.", model.MaxInstructionColorLength)),
+		datastar.WithSelectorID("pattern-content"), datastar.WithModePrepend())
+	return true
 }
 
 // --- Form display handlers (loaded lazily on click) ---
@@ -115,9 +180,11 @@ func InstructionNewForm(db *sql.DB) http.HandlerFunc {
 		}
 
 		stitches, _ := model.ListStitchesForUser(db, user.ID)
+		intoValues, _ := model.DistinctIntoValuesForUser(db, user.ID)
+		prevCount, _ := model.PreviousRowStitchCount(db, rowID)
 		sse := datastar.NewSSE(w, r)
 		sse.PatchElementTempl(
-			view.AddInstructionForm(rowID, stitches),
+			view.AddInstructionForm(rowID, stitches, intoValues, prevCount),
 			datastar.WithSelectorID("row-"+strconv.FormatInt(rowID, 10)+"-add-instr"),
 		)
 	}
@@ -158,9 +225,10 @@ func InstructionChildNewForm(db *sql.DB) http.HandlerFunc {
 		}
 
 		stitches, _ := model.ListStitchesForUser(db, user.ID)
+		intoValues, _ := model.DistinctIntoValuesForUser(db, user.ID)
 		sse := datastar.NewSSE(w, r)
 		sse.PatchElementTempl(
-			view.AddChildInstructionForm(parentID, rowID, stitches),
+			view.AddChildInstructionForm(parentID, rowID, stitches, intoValues),
 			datastar.WithSelectorID("group-"+strconv.FormatInt(parentID, 10)+"-add-child"),
 		)
 	}
@@ -189,11 +257,19 @@ func InstructionCreate(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		stitchID, count, into, note := signals.parse()
+		stitchID, count, into, note, color, consumes, err := signals.parse()
 		sse := datastar.NewSSE(w, r)
+		if countTooLarge(sse, err) || colorTooLong(sse, color) {
+			return
+		}
 
-		if _, err := model.CreateInstruction(db, rowID, stitchID, count, into, note); err != nil {
-			sse.PatchElementTempl(view.PatternError("Failed to add instruction."))
+		if signals.ToEnd {
+			_, err = model.CreateToEndInstruction(db, rowID, stitchID, into, note, color)
+		} else {
+			_, err = model.CreateInstruction(db, rowID, stitchID, count, into, note, color, consumes)
+		}
+		if err != nil {
+			sse.PatchElementTempl(view.PatternError(toEndErrorMessage(err, "Failed to add instruction.")))
 			return
 		}
 
@@ -227,9 +303,13 @@ func InstructionGroupCreate(db *sql.DB) http.HandlerFunc {
 			groupRepeat = 1
 		}
 		note := strings.TrimSpace(signals.Note)
+		color := strings.TrimSpace(signals.Color)
 		sse := datastar.NewSSE(w, r)
+		if countTooLarge(sse, model.CheckInstructionCounts(1, groupRepeat, nil)) || colorTooLong(sse, color) {
+			return
+		}
 
-		if _, err := model.CreateGroupInstruction(db, rowID, groupRepeat, note); err != nil {
+		if _, err := model.CreateGroupInstruction(db, rowID, groupRepeat, note, color); err != nil {
 			sse.PatchElementTempl(view.PatternError("Failed to add group."))
 			return
 		}
@@ -256,11 +336,21 @@ func InstructionChildCreate(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		stitchID, count, into, note := signals.parse()
+		stitchID, count, into, note, color, consumes, err := signals.parse()
 		sse := datastar.NewSSE(w, r)
+		if countTooLarge(sse, err) || colorTooLong(sse, color) {
+			return
+		}
 
-		if _, err := model.CreateChildInstruction(db, parentID, stitchID, count, into, note); err != nil {
-			sse.PatchElementTempl(view.PatternError("Failed to add stitch to group."))
+		if _, err := model.CreateChildInstruction(db, parentID, stitchID, count, into, note, color, consumes); err != nil {
+			msg := "Failed to add stitch to group."
+			switch {
+			case errors.Is(err, model.ErrParentNotGroup):
+				msg = "Stitches can only be added inside a group."
+			case errors.Is(err, model.ErrEmptyChild):
+				msg = "Choose a stitch or add a note."
+			}
+			sse.PatchElementTempl(view.PatternError(msg))
 			return
 		}
 
@@ -294,14 +384,34 @@ func InstructionEditForm(db *sql.DB) http.HandlerFunc {
 			)
 		} else {
 			stitches, _ := model.ListStitchesForUser(db, user.ID)
+			stitches = withCurrentStitch(db, stitches, instr.StitchID)
+			intoValues, _ := model.DistinctIntoValuesForUser(db, user.ID)
 			sse.PatchElementTempl(
-				view.EditInstructionForm(instr, stitches),
+				view.EditInstructionForm(instr, stitches, intoValues),
 				datastar.WithSelectorID("instruction-"+strconv.FormatInt(id, 10)),
 			)
 		}
 	}
 }
 
+// withCurrentStitch makes sure an instruction's stitch is in the picker list. A built-in
+// shadowed by a custom stitch of the same abbreviation is left out of
+// ListStitchesForUser, but instructions created before the custom one still use it.
+func withCurrentStitch(db *sql.DB, stitches []model.Stitch, stitchID *int64) []model.Stitch {
+	if stitchID == nil {
+		return stitches
+	}
+	for _, s := range stitches {
+		if s.ID == *stitchID {
+			return stitches
+		}
+	}
+	if s, err := model.FindStitchByID(db, *stitchID); err == nil {
+		stitches = append(stitches, *s)
+	}
+	return stitches
+}
+
 func InstructionUpdate(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user := UserFromContext(r.Context())
@@ -320,35 +430,45 @@ func InstructionUpdate(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		// Signals must be read before the SSE response starts, which closes the body.
+		groupSignals := &editGroupSignals{}
+		instrSignals := &editInstrSignals{}
+		var signals any = instrSignals
+		if instr.IsGroup {
+			signals = groupSignals
+		}
+		if err := datastar.ReadSignals(r, signals); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
 		sse := datastar.NewSSE(w, r)
 
 		if instr.IsGroup {
-			signals := &editGroupSignals{}
-			if err := datastar.ReadSignals(r, signals); err != nil {
-				http.Error(w, "Bad request", http.StatusBadRequest)
-				return
-			}
-			groupRepeat, _ := strconv.Atoi(signals.Repeat)
+			groupRepeat, _ := strconv.Atoi(groupSignals.Repeat)
 			if groupRepeat < 1 {
 				groupRepeat = 1
 			}
-			note := strings.TrimSpace(signals.Note)
-			if err := model.UpdateGroupInstruction(db, id, groupRepeat, note); err != nil {
+			note := strings.TrimSpace(groupSignals.Note)
+			color := strings.TrimSpace(groupSignals.Color)
+			if countTooLarge(sse, model.CheckInstructionCounts(1, groupRepeat, nil)) || colorTooLong(sse, color) {
+				return
+			}
+			if err := model.UpdateGroupInstruction(db, id, groupRepeat, note, color); err != nil {
 				sse.PatchElementTempl(view.PatternError("Failed to update group."))
 				return
 			}
 		} else {
-			signals := &editInstrSignals{}
-			if err := datastar.ReadSignals(r, signals); err != nil {
-				http.Error(w, "Bad request", http.StatusBadRequest)
+			stitchID, count, into, note, color, consumes, err := instrSignals.parse()
+			if countTooLarge(sse, err) || colorTooLong(sse, color) {
 				return
 			}
-			stitchID, count, into, note := signals.parse()
-			if err := model.UpdateInstruction(db, id, stitchID, count, into, note); err != nil {
-				sse.PatchElementTempl(view.PatternError("Failed to update instruction."))
+			if err := model.UpdateInstruction(db, id, stitchID, count, into, note, color, consumes, instrSignals.ToEnd); err != nil {
+				sse.PatchElementTempl(view.PatternError(toEndErrorMessage(err, "Failed to update instruction.")))
 				return
 			}
 		}
+		model.RepairPatternSessions(db, patternID)
 
 		refreshRowInstructions(sse, db, rowID, patternID)
 	}
@@ -372,6 +492,60 @@ func InstructionDelete(db *sql.DB) http.HandlerFunc {
 			sse.PatchElementTempl(view.PatternError("Failed to delete instruction."))
 			return
 		}
+		model.RepairPatternSessions(db, patternID)
+
+		refreshRowInstructions(sse, db, rowID, patternID)
+	}
+}
+
+// InstructionsClear handles POST /rows/{id}/instructions/clear via SSE.
+func InstructionsClear(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		rowID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		patternID, err := model.GetPatternIDForRow(db, rowID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if !checkPatternOwnership(db, patternID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		if err := model.DeleteAllInstructionsForRow(db, rowID); err != nil {
+			sse.PatchElementTempl(view.PatternError("Failed to clear row."))
+			return
+		}
+		model.RepairPatternSessions(db, patternID)
+
+		refreshRowInstructions(sse, db, rowID, patternID)
+	}
+}
+
+// InstructionChildrenClear handles POST /instructions/{id}/children/clear via SSE.
+func InstructionChildrenClear(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		rowID, ok := checkInstructionOwnership(db, id, user.ID)
+		if !ok {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		patternID, _ := model.GetPatternIDForRow(db, rowID)
+
+		sse := datastar.NewSSE(w, r)
+
+		if err := model.DeleteGroupChildren(db, id); err != nil {
+			sse.PatchElementTempl(view.PatternError("Failed to clear group."))
+			return
+		}
+		model.RepairPatternSessions(db, patternID)
 
 		refreshRowInstructions(sse, db, rowID, patternID)
 	}
@@ -403,6 +577,201 @@ func instructionMoveHandler(db *sql.DB, moveFn func(*sql.DB, int64) error) http.
 	}
 }
 
+// InstructionMoveIntoGroup handles POST /instructions/{id}/move-into/{groupID} via SSE.
+func InstructionMoveIntoGroup(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		groupID, _ := strconv.ParseInt(r.PathValue("groupID"), 10, 64)
+
+		rowID, ok := checkInstructionOwnership(db, id, user.ID)
+		if !ok {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		patternID, _ := model.GetPatternIDForRow(db, rowID)
+
+		sse := datastar.NewSSE(w, r)
+
+		if err := model.MoveInstructionIntoGroup(db, id, groupID); err != nil {
+			msg := "Failed to move stitch into the group."
+			switch {
+			case errors.Is(err, model.ErrParentNotGroup):
+				msg = "Stitches can only be moved into a group of the same row."
+			case errors.Is(err, model.ErrNestedGroup):
+				msg = "Groups can't be put inside other groups."
+			case errors.Is(err, model.ErrEmptyChild):
+				msg = "Only a stitch or a note can go inside a group."
+			}
+			sse.PatchElementTempl(view.PatternError(msg))
+			return
+		}
+		model.RepairPatternSessions(db, patternID)
+
+		refreshRowInstructions(sse, db, rowID, patternID)
+	}
+}
+
+// InstructionMoveOutOfGroup handles POST /instructions/{id}/move-out via SSE.
+func InstructionMoveOutOfGroup(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		rowID, ok := checkInstructionOwnership(db, id, user.ID)
+		if !ok {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		patternID, _ := model.GetPatternIDForRow(db, rowID)
+
+		sse := datastar.NewSSE(w, r)
+
+		if err := model.MoveInstructionOutOfGroup(db, id); err != nil {
+			sse.PatchElementTempl(view.PatternError("Failed to move stitch out of the group."))
+			return
+		}
+		model.RepairPatternSessions(db, patternID)
+
+		refreshRowInstructions(sse, db, rowID, patternID)
+	}
+}
+
+// InstructionUngroup handles POST /instructions/{id}/ungroup via SSE.
+func InstructionUngroup(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		rowID, ok := checkInstructionOwnership(db, id, user.ID)
+		if !ok {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		patternID, _ := model.GetPatternIDForRow(db, rowID)
+
+		sse := datastar.NewSSE(w, r)
+
+		if err := model.UngroupInstruction(db, id); err != nil {
+			sse.PatchElementTempl(view.PatternError("Failed to ungroup."))
+			return
+		}
+		model.RepairPatternSessions(db, patternID)
+
+		refreshRowInstructions(sse, db, rowID, patternID)
+	}
+}
+
+type groupSelectedSignals struct {
+	IDs    []int64 `json:"groupSelection"`
+	Repeat string  `json:"groupSelectionRepeat"`
+}
+
+// InstructionGroupSelected handles POST /rows/{id}/instructions/group-selected via
+// SSE, wrapping the ticked top-level instructions in a new group.
+func InstructionGroupSelected(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		rowID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		patternID, err := model.GetPatternIDForRow(db, rowID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if !checkPatternOwnership(db, patternID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		signals := &groupSelectedSignals{}
+		if err := datastar.ReadSignals(r, signals); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		groupRepeat, _ := strconv.Atoi(strings.TrimSpace(signals.Repeat))
+		if groupRepeat < 1 {
+			groupRepeat = 1
+		}
+		sse := datastar.NewSSE(w, r)
+		if countTooLarge(sse, model.CheckInstructionCounts(1, groupRepeat, nil)) {
+			return
+		}
+
+		if _, err := model.GroupInstructions(db, rowID, signals.IDs, groupRepeat); err != nil {
+			msg := "Failed to group stitches."
+			switch {
+			case errors.Is(err, model.ErrEmptySelection):
+				msg = "Tick the stitches to group first."
+			case errors.Is(err, model.ErrSelectionNotContiguous):
+				msg = "Only stitches next to each other can be grouped."
+			case errors.Is(err, model.ErrSelectionHasGroup):
+				msg = "A group can't be put inside another group."
+			case errors.Is(err, model.ErrEmptyChild):
+				msg = "Only stitches and notes can go inside a group."
+			}
+			sse.PatchElementTempl(view.PatternError(msg))
+			return
+		}
+		model.RepairPatternSessions(db, patternID)
+
+		refreshRowInstructions(sse, db, rowID, patternID)
+	}
+}
+
+type instructionReorderSignals struct {
+	Order  []int64 `json:"instructionOrder"`
+	Parent string  `json:"instructionParent"` // group ID, or "" for top-level
+}
+
+// InstructionReorder handles POST /rows/{id}/instructions/reorder via SSE.
+// It reorders one sibling scope (top level or one group's children); the
+// instructionOrder signal must list every instruction in that scope exactly once.
+func InstructionReorder(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		rowID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		patternID, err := model.GetPatternIDForRow(db, rowID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if !checkPatternOwnership(db, patternID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		signals := &instructionReorderSignals{}
+		if err := datastar.ReadSignals(r, signals); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		var parentID *int64
+		if signals.Parent != "" {
+			id, err := strconv.ParseInt(signals.Parent, 10, 64)
+			if err != nil {
+				http.Error(w, "Bad request", http.StatusBadRequest)
+				return
+			}
+			parentID = &id
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		if err := model.ReorderInstructions(db, rowID, parentID, signals.Order); err != nil {
+			refreshRowInstructions(sse, db, rowID, patternID)
+			sse.PatchElementTempl(view.PatternError("Stitches changed since the page loaded. Please try again."),
+				datastar.WithSelectorID("pattern-content"), datastar.WithModePrepend())
+			return
+		}
+
+		refreshRowInstructions(sse, db, rowID, patternID)
+	}
+}
+
 func InstructionsRefresh(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user := UserFromContext(r.Context())
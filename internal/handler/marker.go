@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/starfederation/datastar-go/datastar"
+	"github.com/stitchmap/stitchmap/internal/model"
+	"github.com/stitchmap/stitchmap/internal/view"
+)
+
+type markerSignals struct {
+	At   string `json:"markerAt"`
+	Note string `json:"markerNote"`
+}
+
+// rowOwnedBy reports whether the row exists and belongs to one of the user's patterns.
+func rowOwnedBy(db *sql.DB, rowID, userID int64) bool {
+	patternID, err := model.GetPatternIDForRow(db, rowID)
+	return err == nil && checkPatternOwnership(db, patternID, userID)
+}
+
+// RowMarkerList handles GET /rows/{id}/markers via SSE, re-rendering the row's marker
+// panel. With ?form=1 the panel shows the add form.
+func RowMarkerList(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		rowID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		if !rowOwnedBy(db, rowID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		markers, _ := model.ListRowMarkers(db, rowID)
+		sse := datastar.NewSSE(w, r)
+		sse.PatchElementTempl(view.RowMarkerPanel(rowID, markers, r.URL.Query().Get("form") == "1", ""))
+	}
+}
+
+// RowMarkerCreate handles POST /rows/{id}/markers via SSE.
+func RowMarkerCreate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		rowID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		if !rowOwnedBy(db, rowID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		signals := &markerSignals{}
+		if err := datastar.ReadSignals(r, signals); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		at, _ := strconv.Atoi(strings.TrimSpace(signals.At))
+		note := strings.TrimSpace(signals.Note)
+		sse := datastar.NewSSE(w, r)
+
+		errMsg := ""
+		if note == "" {
+			errMsg = "Marker note can't be empty."
+		} else if _, err := model.CreateRowMarker(db, rowID, at, note); errors.Is(err, model.ErrInvalidMarkerStitch) {
+			errMsg = "That stitch isn't in this row."
+		} else if err != nil {
+			errMsg = "Failed to add marker."
+		}
+
+		markers, _ := model.ListRowMarkers(db, rowID)
+		sse.PatchElementTempl(view.RowMarkerPanel(rowID, markers, errMsg != "", errMsg))
+	}
+}
+
+// RowMarkerDelete handles DELETE /rows/{id}/markers/{markerID} via SSE.
+func RowMarkerDelete(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		rowID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		markerID, _ := strconv.ParseInt(r.PathValue("markerID"), 10, 64)
+
+		if !rowOwnedBy(db, rowID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+		errMsg := ""
+		if err := model.DeleteRowMarker(db, markerID, rowID); err != nil {
+			errMsg = "Failed to remove marker."
+		}
+
+		markers, _ := model.ListRowMarkers(db, rowID)
+		sse.PatchElementTempl(view.RowMarkerPanel(rowID, markers, false, errMsg))
+	}
+}
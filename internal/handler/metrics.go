@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"cmp"
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/stitchmap/stitchmap/internal/model"
+)
+
+// Metrics counts requests by route and status code for the /metrics endpoint.
+// Create one with NewMetrics and wrap the whole mux in its Middleware.
+type Metrics struct {
+	mu       sync.Mutex
+	requests map[requestKey]uint64
+}
+
+type requestKey struct {
+	route string
+	code  int
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{requests: make(map[requestKey]uint64)}
+}
+
+// Middleware counts every request once it has been served, labeled with the route
+// pattern it matched rather than its path, so IDs in URLs don't multiply the series.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, info := withRequestInfo(r)
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		key := requestKey{route: routeLabel(r, info), code: sw.Status()}
+		m.mu.Lock()
+		m.requests[key]++
+		m.mu.Unlock()
+	})
+}
+
+// MetricsHandler handles GET /metrics, writing the counters in the Prometheus text
+// format. It sits outside the login wall and instead requires an
+// "Authorization: Bearer <token>" header matching token.
+func MetricsHandler(db *sql.DB, m *Metrics, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(bearerToken(r)), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		stats, err := model.SiteWideStats(db)
+		if err != nil {
+			log.Printf("metrics: %v", err)
+			http.Error(w, "Failed to load metrics", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+
+		writeGauge(w, "stitchmap_users", "Registered users.", stats.Users)
+		writeGauge(w, "stitchmap_patterns", "Patterns across all users.", stats.Patterns)
+		writeGauge(w, "stitchmap_work_sessions_active", "Work sessions not yet completed.", stats.ActiveSessions)
+		writeGauge(w, "stitchmap_login_sessions", "Unexpired login sessions.", stats.LoginSessions)
+
+		dbStats := db.Stats()
+		writeGauge(w, "stitchmap_db_open_connections", "Open database connections, in use or idle.", dbStats.OpenConnections)
+		writeGauge(w, "stitchmap_db_in_use_connections", "Database connections currently in use.", dbStats.InUse)
+		writeGauge(w, "stitchmap_db_idle_connections", "Idle database connections.", dbStats.Idle)
+		writeCounter(w, "stitchmap_db_wait_count_total", "Times a query waited for a free connection.", dbStats.WaitCount)
+		fmt.Fprintf(w, "# HELP stitchmap_db_wait_seconds_total Time spent waiting for a free connection.\n")
+		fmt.Fprintf(w, "# TYPE stitchmap_db_wait_seconds_total counter\n")
+		fmt.Fprintf(w, "stitchmap_db_wait_seconds_total %g\n", dbStats.WaitDuration.Seconds())
+
+		fmt.Fprintf(w, "# HELP stitchmap_http_requests_total HTTP requests served, by route and status code.\n")
+		fmt.Fprintf(w, "# TYPE stitchmap_http_requests_total counter\n")
+		for _, rc := range m.snapshot() {
+			fmt.Fprintf(w, "stitchmap_http_requests_total{route=\"%s\",code=\"%d\"} %d\n",
+				escapeLabel(rc.route), rc.code, rc.count)
+		}
+	}
+}
+
+type routeCount struct {
+	requestKey
+	count uint64
+}
+
+// snapshot copies the request counters, sorted by route and code so the output is
+// stable between scrapes.
+func (m *Metrics) snapshot() []routeCount {
+	m.mu.Lock()
+	counts := make([]routeCount, 0, len(m.requests))
+	for k, n := range m.requests {
+		counts = append(counts, routeCount{k, n})
+	}
+	m.mu.Unlock()
+	slices.SortFunc(counts, func(a, b routeCount) int {
+		return cmp.Or(strings.Compare(a.route, b.route), cmp.Compare(a.code, b.code))
+	})
+	return counts
+}
+
+func writeGauge(w io.Writer, name, help string, value int) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", name, help, name, name, value)
+}
+
+func writeCounter(w io.Writer, name, help string, value int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, value)
+}
+
+// escapeLabel escapes a label value for the Prometheus text format.
+var escapeLabel = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace
@@ -4,19 +4,121 @@ import (
 	"context"
 	"database/sql"
 	"net/http"
+	"strings"
 
 	"github.com/stitchmap/stitchmap/internal/model"
+	"github.com/stitchmap/stitchmap/internal/view"
 )
 
 type contextKey string
 
-const userContextKey contextKey = "user"
+const (
+	userContextKey        contextKey = "user"
+	requestInfoContextKey contextKey = "request-info"
+)
 
 func UserFromContext(ctx context.Context) *model.User {
 	u, _ := ctx.Value(userContextKey).(*model.User)
 	return u
 }
 
+// requestInfo collects what inner layers learn about a request so outer middleware
+// can report it. RequireAuth hands nested muxes a copy of the request, so the route
+// they match is recorded here rather than left on a request the outer layers never
+// see.
+type requestInfo struct {
+	route  string // pattern of the innermost mux that matched, e.g. "GET /patterns/{id}"
+	path   string // the request path with any {token} wildcard redacted
+	userID int64  // the authenticated user, 0 if none
+}
+
+// withRequestInfo returns r carrying a requestInfo, reusing one already attached by
+// an outer middleware.
+func withRequestInfo(r *http.Request) (*http.Request, *requestInfo) {
+	if info := requestInfoFromContext(r.Context()); info != nil {
+		return r, info
+	}
+	info := &requestInfo{}
+	return r.WithContext(context.WithValue(r.Context(), requestInfoContextKey, info)), info
+}
+
+func requestInfoFromContext(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestInfoContextKey).(*requestInfo)
+	return info
+}
+
+// noteRoute records the pattern a nested mux matched for r, once it has served r.
+func noteRoute(r *http.Request) {
+	if info := requestInfoFromContext(r.Context()); info != nil && r.Pattern != "" {
+		info.route = r.Pattern
+		info.path = redactedPath(r)
+	}
+}
+
+// noteUser records the user a request was authenticated as.
+func noteUser(r *http.Request, user *model.User) {
+	if info := requestInfoFromContext(r.Context()); info != nil {
+		info.userID = user.ID
+	}
+}
+
+// redactedPath is r's path, without the query, with the value of a {token} wildcard
+// (share links, login sessions) replaced so it never reaches the logs.
+func redactedPath(r *http.Request) string {
+	path := r.URL.Path
+	if token := r.PathValue("token"); token != "" {
+		path = strings.Replace(path, token, "[redacted]", 1)
+	}
+	return path
+}
+
+// routeLabel is the route to report for a request that has been served: the
+// innermost matched pattern, or "unmatched" so unknown paths can't each become a
+// label of their own.
+func routeLabel(r *http.Request, info *requestInfo) string {
+	switch {
+	case info.route != "":
+		return info.route
+	case r.Pattern != "":
+		return r.Pattern
+	}
+	return "unmatched"
+}
+
+// statusWriter remembers the status code written through it.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, which SSE
+// responses need for flushing.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Status is the code written, 200 if the handler wrote nothing.
+func (w *statusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
 // RequireAuth is middleware that checks for a valid session cookie.
 // If the session is missing or invalid, it redirects to /login.
 func RequireAuth(db *sql.DB, next http.Handler) http.Handler {
@@ -30,14 +132,7 @@ func RequireAuth(db *sql.DB, next http.Handler) http.Handler {
 		session, err := model.FindSession(db, cookie.Value)
 		if err != nil {
 			// Invalid or expired session — clear cookie and redirect.
-			http.SetCookie(w, &http.Cookie{
-				Name:     "session",
-				Value:    "",
-				Path:     "/",
-				MaxAge:   -1,
-				HttpOnly: true,
-				SameSite: http.SameSiteLaxMode,
-			})
+			clearSessionCookie(w, r)
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
@@ -47,8 +142,82 @@ func RequireAuth(db *sql.DB, next http.Handler) http.Handler {
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
+		if user.Disabled() {
+			model.DeleteSession(db, cookie.Value)
+			clearSessionCookie(w, r)
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
 
 		ctx := context.WithValue(r.Context(), userContextKey, user)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		prefs, _ := model.GetPreferences(db, user.ID)
+		ctx = view.WithTheme(ctx, prefs.Theme)
+		r = r.WithContext(ctx)
+		noteUser(r, user)
+		next.ServeHTTP(w, r)
+		noteRoute(r)
+	})
+}
+
+// RequireAuthAPI is middleware for the JSON API. It checks for an API token in an
+// "Authorization: Bearer <token>" header and falls back to the session cookie.
+// Unauthenticated requests get a 401 JSON error instead of a redirect.
+func RequireAuthAPI(db *sql.DB, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var user *model.User
+		if token := bearerToken(r); token != "" {
+			u, err := model.FindUserByAPIToken(db, token)
+			if err != nil {
+				writeAPIError(w, http.StatusUnauthorized, "invalid API token")
+				return
+			}
+			user = u
+		} else {
+			cookie, err := r.Cookie(sessionCookieName)
+			if err != nil {
+				writeAPIError(w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+			session, err := model.FindSession(db, cookie.Value)
+			if err != nil {
+				writeAPIError(w, http.StatusUnauthorized, "invalid or expired session")
+				return
+			}
+			user, err = model.FindUserByID(db, session.UserID)
+			if err != nil {
+				writeAPIError(w, http.StatusUnauthorized, "invalid or expired session")
+				return
+			}
+		}
+		if user.Disabled() {
+			writeAPIError(w, http.StatusForbidden, "account disabled")
+			return
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), userContextKey, user))
+		noteUser(r, user)
+		next.ServeHTTP(w, r)
+		noteRoute(r)
 	})
 }
+
+// RequireAdmin wraps a handler behind RequireAuth so only admins reach it. Anyone
+// else gets a 404, as if the admin pages didn't exist.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := UserFromContext(r.Context()); user == nil || !user.IsAdmin {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bearerToken returns the token from an "Authorization: Bearer" header, or "".
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
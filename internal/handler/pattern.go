@@ -2,6 +2,10 @@ package handler
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -37,14 +41,25 @@ func PatternCreate(db *sql.DB) http.HandlerFunc {
 		user := UserFromContext(r.Context())
 		name := strings.TrimSpace(r.FormValue("name"))
 		description := strings.TrimSpace(r.FormValue("description"))
+		meta := model.PatternMeta{
+			Difficulty:   r.FormValue("difficulty"),
+			HookSize:     r.FormValue("hook_size"),
+			YarnWeight:   r.FormValue("yarn_weight"),
+			FinishedSize: r.FormValue("finished_size"),
+		}
 
 		if name == "" {
 			renderTempl(w, r, http.StatusUnprocessableEntity,
 				view.NewPatternPage(user.Email, "Pattern name is required."))
 			return
 		}
+		if err := meta.Normalize(); err != nil {
+			renderTempl(w, r, http.StatusUnprocessableEntity,
+				view.NewPatternPage(user.Email, patternMetaError))
+			return
+		}
 
-		pattern, err := model.CreatePattern(db, user.ID, name, description)
+		pattern, err := model.CreatePattern(db, user.ID, name, description, meta)
 		if err != nil {
 			renderTempl(w, r, http.StatusInternalServerError,
 				view.NewPatternPage(user.Email, "Failed to create pattern."))
@@ -55,6 +70,51 @@ func PatternCreate(db *sql.DB) http.HandlerFunc {
 	}
 }
 
+// PatternImportTextPage renders the import-from-text form.
+func PatternImportTextPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		renderTempl(w, r, http.StatusOK, view.ImportTextPage(user.Email, "", "", ""))
+	}
+}
+
+// PatternImportText handles POST /patterns/import-text (normal form submit).
+// It creates a pattern from pasted text; lines that couldn't be parsed are listed
+// afterwards instead of failing the import.
+func PatternImportText(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		name := strings.TrimSpace(r.FormValue("name"))
+		text := r.FormValue("text")
+
+		if name == "" {
+			renderTempl(w, r, http.StatusUnprocessableEntity,
+				view.ImportTextPage(user.Email, name, text, "Pattern name is required."))
+			return
+		}
+
+		parsed, err := model.ParsePatternText(text)
+		if err != nil {
+			renderTempl(w, r, http.StatusUnprocessableEntity,
+				view.ImportTextPage(user.Email, name, text, "No rows found. Rows should start with a label like \"Rnd 1:\" or \"Row 1:\"."))
+			return
+		}
+
+		pattern, err := model.CreatePatternFromParsed(db, user.ID, name, parsed)
+		if err != nil {
+			renderTempl(w, r, http.StatusInternalServerError,
+				view.ImportTextPage(user.Email, name, text, "Failed to create pattern."))
+			return
+		}
+
+		if len(parsed.Skipped) > 0 {
+			renderTempl(w, r, http.StatusOK, view.ImportTextResultPage(user.Email, pattern, parsed.Skipped))
+			return
+		}
+		http.Redirect(w, r, "/patterns/"+strconv.FormatInt(pattern.ID, 10), http.StatusSeeOther)
+	}
+}
+
 // PatternShow renders the pattern detail page (normal page).
 func PatternShow(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -76,10 +136,17 @@ func PatternShow(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		tags, _ := model.ListTagsForPattern(db, pattern.ID)
+		shares, _ := model.ListShareLinks(db, pattern.ID)
+		collapsed, _ := model.CollapsedSections(db, user.ID, pattern.ID)
 		renderTempl(w, r, http.StatusOK, view.PatternDetailPage(view.PatternDetailData{
-			Pattern:  pattern,
-			Sections: sections,
-			Email:    user.Email,
+			Pattern:   pattern,
+			Sections:  sections,
+			Collapsed: collapsed,
+			Tags:      tags,
+			Shares:    shares,
+			BaseURL:   baseURL(r),
+			Email:     user.Email,
 		}))
 	}
 }
@@ -102,8 +169,38 @@ func PatternEditForm(db *sql.DB) http.HandlerFunc {
 }
 
 type patternSignals struct {
-	Name string `json:"patternName"`
-	Desc string `json:"patternDesc"`
+	Name          string `json:"patternName"`
+	Desc          string `json:"patternDesc"`
+	GaugeStitches string `json:"patternGaugeStitches"`
+	GaugeRows     string `json:"patternGaugeRows"`
+	Difficulty    string `json:"patternDifficulty"`
+	HookSize      string `json:"patternHookSize"`
+	YarnWeight    string `json:"patternYarnWeight"`
+	FinishedSize  string `json:"patternFinishedSize"`
+}
+
+// patternMetaError is shown when model.PatternMeta.Normalize rejects the details.
+var patternMetaError = fmt.Sprintf("Choose a difficulty from the list, and keep hook size, yarn weight and finished size to %d characters or fewer.", model.MaxPatternMetaLength)
+
+func (s *patternSignals) meta() model.PatternMeta {
+	return model.PatternMeta{
+		Difficulty:   s.Difficulty,
+		HookSize:     s.HookSize,
+		YarnWeight:   s.YarnWeight,
+		FinishedSize: s.FinishedSize,
+	}
+}
+
+func (s *patternSignals) parseGauge() (gaugeStitches, gaugeRows int) {
+	gaugeStitches, _ = strconv.Atoi(strings.TrimSpace(s.GaugeStitches))
+	gaugeRows, _ = strconv.Atoi(strings.TrimSpace(s.GaugeRows))
+	if gaugeStitches < 0 {
+		gaugeStitches = 0
+	}
+	if gaugeRows < 0 {
+		gaugeRows = 0
+	}
+	return
 }
 
 // PatternUpdate handles PUT /patterns/{id} via SSE.
@@ -125,6 +222,8 @@ func PatternUpdate(db *sql.DB) http.HandlerFunc {
 
 		name := strings.TrimSpace(signals.Name)
 		desc := strings.TrimSpace(signals.Desc)
+		gaugeStitches, gaugeRows := signals.parseGauge()
+		meta := signals.meta()
 
 		sse := datastar.NewSSE(w, r)
 
@@ -132,8 +231,12 @@ func PatternUpdate(db *sql.DB) http.HandlerFunc {
 			sse.PatchElementTempl(view.PatternError("Pattern name is required."))
 			return
 		}
+		if err := meta.Normalize(); err != nil {
+			sse.PatchElementTempl(view.PatternError(patternMetaError))
+			return
+		}
 
-		if err := model.UpdatePattern(db, id, user.ID, name, desc); err != nil {
+		if err := model.UpdatePattern(db, id, user.ID, name, desc, gaugeStitches, gaugeRows, meta); err != nil {
 			sse.PatchElementTempl(view.PatternError("Failed to update pattern."))
 			return
 		}
@@ -143,14 +246,42 @@ func PatternUpdate(db *sql.DB) http.HandlerFunc {
 	}
 }
 
+type deletePatternSignals struct {
+	Confirm string `json:"deleteConfirm"`
+}
+
 // PatternDelete handles DELETE /patterns/{id} via SSE.
+// The client must echo the pattern's name back in the deleteConfirm signal;
+// anything else leaves the pattern in place.
 func PatternDelete(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user := UserFromContext(r.Context())
 		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
 
+		pattern, err := model.FindPatternByID(db, id)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if pattern.UserID != user.ID {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		signals := &deletePatternSignals{}
+		if err := datastar.ReadSignals(r, signals); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
 		sse := datastar.NewSSE(w, r)
 
+		if strings.TrimSpace(signals.Confirm) != pattern.Name {
+			sse.PatchElementTempl(view.PatternError("Type the pattern's name exactly to delete it."),
+				datastar.WithSelectorID("pattern-list"), datastar.WithModePrepend())
+			return
+		}
+
 		if err := model.DeletePattern(db, id, user.ID); err != nil {
 			sse.PatchElementTempl(view.PatternError("Failed to delete pattern."))
 			return
@@ -160,6 +291,51 @@ func PatternDelete(db *sql.DB) http.HandlerFunc {
 	}
 }
 
+type resizeSignals struct {
+	TargetGauge string `json:"resizeTargetGauge"`
+}
+
+// PatternResizePreview handles GET /patterns/{id}/resize via SSE.
+// It recomputes each row's stitch count for a target gauge without saving anything.
+func PatternResizePreview(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		pattern, sections, err := model.LoadPatternFull(db, id)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if pattern.UserID != user.ID {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		signals := &resizeSignals{}
+		if err := datastar.ReadSignals(r, signals); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		if pattern.GaugeStitches <= 0 {
+			sse.PatchElementTempl(view.ResizePreview(pattern, 0, nil, "Set the pattern's stitch gauge first."))
+			return
+		}
+
+		targetGauge, err := strconv.Atoi(strings.TrimSpace(signals.TargetGauge))
+		if err != nil || targetGauge < 1 {
+			sse.PatchElementTempl(view.ResizePreview(pattern, 0, nil, "Target gauge must be a positive number."))
+			return
+		}
+
+		preview := model.ResizePreview(sections, pattern.GaugeStitches, targetGauge)
+		sse.PatchElementTempl(view.ResizePreview(pattern, targetGauge, preview, ""))
+	}
+}
+
 // --- Section Handlers ---
 
 type sectionSignals struct {
@@ -198,7 +374,7 @@ func SectionCreate(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		refreshPatternSections(sse, db, patternID)
+		refreshPatternSections(sse, db, user.ID, patternID)
 	}
 }
 
@@ -261,7 +437,7 @@ func SectionUpdate(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		refreshPatternSections(sse, db, section.PatternID)
+		refreshPatternSections(sse, db, user.ID, section.PatternID)
 	}
 }
 
@@ -287,8 +463,64 @@ func SectionDelete(db *sql.DB) http.HandlerFunc {
 			sse.PatchElementTempl(view.PatternError("Failed to delete section."))
 			return
 		}
+		model.RepairPatternSessions(db, patternID)
+
+		refreshPatternSections(sse, db, user.ID, patternID)
+	}
+}
+
+// SectionDuplicate handles POST /sections/{id}/duplicate via SSE.
+func SectionDuplicate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		patternID, err := model.GetPatternIDForSection(db, id)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if !checkPatternOwnership(db, patternID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		if _, err := model.DuplicateSection(db, id); err != nil {
+			sse.PatchElementTempl(view.PatternError("Failed to duplicate section."),
+				datastar.WithSelectorID("pattern-content"), datastar.WithModePrepend())
+			return
+		}
+
+		refreshPatternSections(sse, db, user.ID, patternID)
+	}
+}
+
+// SectionToggleCollapse handles POST /sections/{id}/toggle-collapse via SSE.
+func SectionToggleCollapse(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		patternID, err := model.GetPatternIDForSection(db, id)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if !checkPatternOwnership(db, patternID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		if _, err := model.ToggleSectionCollapsed(db, user.ID, id); err != nil {
+			sse.PatchElementTempl(view.PatternError("Failed to update section."))
+			return
+		}
 
-		refreshPatternSections(sse, db, patternID)
+		refreshPatternSections(sse, db, user.ID, patternID)
 	}
 }
 
@@ -319,7 +551,43 @@ func sectionMoveHandler(db *sql.DB, moveFn func(*sql.DB, int64) error) http.Hand
 
 		sse := datastar.NewSSE(w, r)
 		moveFn(db, id)
-		refreshPatternSections(sse, db, patternID)
+		refreshPatternSections(sse, db, user.ID, patternID)
+	}
+}
+
+type sectionReorderSignals struct {
+	Order []int64 `json:"sectionOrder"`
+}
+
+// SectionReorder handles POST /patterns/{id}/sections/reorder via SSE.
+// The new order comes from the sectionOrder signal and must list every section exactly once.
+func SectionReorder(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		patternID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		if !checkPatternOwnership(db, patternID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		signals := &sectionReorderSignals{}
+		if err := datastar.ReadSignals(r, signals); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		if err := model.ReorderSections(db, patternID, signals.Order); err != nil {
+			// Most likely a stale page; re-render so the client sees the real order.
+			refreshPatternSections(sse, db, user.ID, patternID)
+			sse.PatchElementTempl(view.PatternError("Sections changed since the page loaded. Please try again."),
+				datastar.WithSelectorID("pattern-content"), datastar.WithModePrepend())
+			return
+		}
+
+		refreshPatternSections(sse, db, user.ID, patternID)
 	}
 }
 
@@ -335,7 +603,7 @@ func SectionsRefresh(db *sql.DB) http.HandlerFunc {
 		}
 
 		sse := datastar.NewSSE(w, r)
-		refreshPatternSections(sse, db, patternID)
+		refreshPatternSections(sse, db, user.ID, patternID)
 	}
 }
 
@@ -349,9 +617,13 @@ type rowSignals struct {
 	TurningChainCounts bool   `json:"rowTurningChainCounts"`
 	RepeatCount        string `json:"rowRepeatCount"`
 	Notes              string `json:"rowNotes"`
+	CountOnly          bool   `json:"rowCountOnly"`
+	CheckPrevious      bool   `json:"rowCheckPrevious"`
 }
 
-func (s *rowSignals) parse() (label, rowType string, stitchCount, turningChain int, turningChainCounts bool, repeatCount int, notes string) {
+// parse normalizes the row signals. err is model.ErrInvalidRowType if the type
+// isn't one of model.ValidRowTypes.
+func (s *rowSignals) parse() (label, rowType string, stitchCount, turningChain int, turningChainCounts bool, repeatCount int, notes string, countOnly bool, err error) {
 	label = strings.TrimSpace(s.Label)
 	rowType = s.Type
 	stitchCount, _ = strconv.Atoi(s.StitchCount)
@@ -365,16 +637,27 @@ func (s *rowSignals) parse() (label, rowType string, stitchCount, turningChain i
 		stitchCount = 1
 	}
 	notes = strings.TrimSpace(s.Notes)
+	countOnly = s.CountOnly
 
-	// Validate type.
-	switch rowType {
-	case "row", "joined_round", "continuous_round":
-	default:
-		rowType = "continuous_round"
+	if !model.IsValidRowType(rowType) {
+		err = model.ErrInvalidRowType
+	} else if repeatCount > model.MaxRowRepeat {
+		err = model.ErrCountTooLarge
 	}
 	return
 }
 
+// rowInputError reports a row form error from rowSignals.parse above the pattern
+// sections.
+func rowInputError(sse *datastar.ServerSentEventGenerator, err error) {
+	msg := "Unknown row type."
+	if errors.Is(err, model.ErrCountTooLarge) {
+		msg = fmt.Sprintf("A row can repeat at most %d times.", model.MaxRowRepeat)
+	}
+	sse.PatchElementTempl(view.PatternError(msg),
+		datastar.WithSelectorID("pattern-content"), datastar.WithModePrepend())
+}
+
 // RowNewForm returns the add row form via SSE.
 func RowNewForm(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -391,8 +674,20 @@ func RowNewForm(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		// A new row is usually like the one before it, and works into the stitches it
+		// ended with; fall back to the user's default row type for an empty section.
+		prefs, _ := model.GetPreferences(db, user.ID)
+		defaults := model.Row{Type: prefs.DefaultRowType}
+		if rows, err := model.ListRowsBySection(db, sectionID); err == nil && len(rows) > 0 {
+			last := rows[len(rows)-1]
+			defaults.Type = last.Type
+			defaults.TurningChainCount = last.TurningChainCount
+			defaults.TurningChainCountsAsStitch = last.TurningChainCountsAsStitch
+		}
+		defaults.ExpectedStitchCount, _ = model.LastRowStitchCount(db, sectionID)
+
 		sse := datastar.NewSSE(w, r)
-		sse.PatchElementTempl(view.AddRowForm(sectionID))
+		sse.PatchElementTempl(view.AddRowForm(sectionID, defaults))
 	}
 }
 
@@ -418,11 +713,16 @@ func RowCreate(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		label, rowType, stitchCount, turningChain, turningChainCounts, repeatCount, notes := signals.parse()
+		label, rowType, stitchCount, turningChain, turningChainCounts, repeatCount, notes, countOnly, err := signals.parse()
 
 		sse := datastar.NewSSE(w, r)
 
-		if _, err := model.CreateRow(db, sectionID, label, rowType, stitchCount, turningChain, turningChainCounts, repeatCount, notes); err != nil {
+		if err != nil {
+			rowInputError(sse, err)
+			return
+		}
+
+		if _, err := model.CreateRow(db, sectionID, label, rowType, stitchCount, turningChain, turningChainCounts, repeatCount, notes, countOnly, signals.CheckPrevious); err != nil {
 			sse.PatchElementTempl(view.PatternError("Failed to create row."))
 			return
 		}
@@ -431,6 +731,69 @@ func RowCreate(db *sql.DB) http.HandlerFunc {
 	}
 }
 
+type rowRangeSignals struct {
+	rowSignals
+	Count string `json:"rowRangeCount"`
+}
+
+// RowCreateRange handles POST /sections/{id}/rows/range via SSE.
+// It adds several identical rows at once, e.g. for "Rows 5-20: sc in each st".
+func RowCreateRange(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		sectionID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		patternID, err := model.GetPatternIDForSection(db, sectionID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if !checkPatternOwnership(db, patternID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		signals := &rowRangeSignals{}
+		if err := datastar.ReadSignals(r, signals); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		label, rowType, stitchCount, turningChain, turningChainCounts, repeatCount, notes, countOnly, err := signals.parse()
+		count, _ := strconv.Atoi(strings.TrimSpace(signals.Count))
+
+		sse := datastar.NewSSE(w, r)
+
+		if err != nil {
+			rowInputError(sse, err)
+			return
+		}
+
+		if count < 1 || count > model.MaxRowRange {
+			sse.PatchElementTempl(view.PatternError(fmt.Sprintf("Number of rows must be between 1 and %d.", model.MaxRowRange)),
+				datastar.WithSelectorID("pattern-content"), datastar.WithModePrepend())
+			return
+		}
+
+		if _, err := model.CreateRowRange(db, sectionID, count, model.RowInput{
+			Label:                      label,
+			Type:                       rowType,
+			ExpectedStitchCount:        stitchCount,
+			TurningChainCount:          turningChain,
+			TurningChainCountsAsStitch: turningChainCounts,
+			RepeatCount:                repeatCount,
+			Notes:                      notes,
+			CountOnly:                  countOnly,
+			CheckPrevious:              signals.CheckPrevious,
+		}); err != nil {
+			sse.PatchElementTempl(view.PatternError("Failed to create rows."))
+			return
+		}
+
+		refreshSectionRows(sse, db, sectionID)
+	}
+}
+
 // RowEditForm returns the row edit form via SSE.
 func RowEditForm(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -504,14 +867,20 @@ func RowUpdate(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		label, rowType, stitchCount, turningChain, turningChainCounts, repeatCount, notes := signals.parse()
+		label, rowType, stitchCount, turningChain, turningChainCounts, repeatCount, notes, countOnly, err := signals.parse()
 
 		sse := datastar.NewSSE(w, r)
 
-		if err := model.UpdateRow(db, id, label, rowType, stitchCount, turningChain, turningChainCounts, repeatCount, notes); err != nil {
+		if err != nil {
+			rowInputError(sse, err)
+			return
+		}
+
+		if err := model.UpdateRow(db, id, label, rowType, stitchCount, turningChain, turningChainCounts, repeatCount, notes, countOnly, signals.CheckPrevious); err != nil {
 			sse.PatchElementTempl(view.PatternError("Failed to update row."))
 			return
 		}
+		model.RepairPatternSessions(db, patternID)
 
 		refreshSectionRows(sse, db, row.SectionID)
 	}
@@ -537,10 +906,50 @@ func RowDelete(db *sql.DB) http.HandlerFunc {
 
 		sse := datastar.NewSSE(w, r)
 
-		if err := model.DeleteRow(db, id); err != nil {
+		token, err := model.DeleteRow(db, id, user.ID)
+		if err != nil {
 			sse.PatchElementTempl(view.PatternError("Failed to delete row."))
 			return
 		}
+		model.RepairPatternSessions(db, patternID)
+
+		refreshSectionRows(sse, db, row.SectionID)
+		sse.PatchElementTempl(view.RowUndoNotice(token, model.RowUndoWindow),
+			datastar.WithSelectorID("section-"+strconv.FormatInt(row.SectionID, 10)+"-rows"),
+			datastar.WithModePrepend())
+	}
+}
+
+type rowRestoreSignals struct {
+	Token string `json:"restoreToken"`
+}
+
+// RowRestore handles POST /rows/restore via SSE, undoing a recent row delete.
+func RowRestore(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+
+		signals := &rowRestoreSignals{}
+		if err := datastar.ReadSignals(r, signals); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		row, err := model.RestoreRow(db, signals.Token, user.ID)
+		if err != nil {
+			msg := "Failed to restore row."
+			switch {
+			case errors.Is(err, model.ErrRowTrashExpired):
+				msg = "Too late to undo; the row is gone for good."
+			case errors.Is(err, model.ErrRowStitchDeleted):
+				msg = "Can't undo: a stitch the row used has since been deleted."
+			}
+			sse.RemoveElementByID("row-undo")
+			sse.PatchElementTempl(view.PatternError(msg), datastar.WithSelectorID("pattern-content"), datastar.WithModePrepend())
+			return
+		}
 
 		refreshSectionRows(sse, db, row.SectionID)
 	}
@@ -579,6 +988,48 @@ func rowMoveHandler(db *sql.DB, moveFn func(*sql.DB, int64) error) http.HandlerF
 	}
 }
 
+type rowReorderSignals struct {
+	Order []int64 `json:"rowOrder"`
+}
+
+// RowReorder handles POST /sections/{id}/rows/reorder via SSE.
+// The new order comes from the rowOrder signal and must list every row in the section exactly once.
+func RowReorder(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		sectionID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		patternID, err := model.GetPatternIDForSection(db, sectionID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if !checkPatternOwnership(db, patternID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		signals := &rowReorderSignals{}
+		if err := datastar.ReadSignals(r, signals); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		if err := model.ReorderRows(db, sectionID, signals.Order); err != nil {
+			refreshPatternSections(sse, db, user.ID, patternID)
+			sse.PatchElementTempl(view.PatternError("Rows changed since the page loaded. Please try again."),
+				datastar.WithSelectorID("pattern-content"), datastar.WithModePrepend())
+			return
+		}
+
+		refreshSectionRows(sse, db, sectionID)
+		_, sections, _ := model.LoadPatternFull(db, patternID)
+		sse.PatchElementTempl(view.PatternSummaryBlock(sections), datastar.WithSelectorID("pattern-summary"))
+	}
+}
+
 // RowsRefresh handles GET /sections/{id}/rows-refresh via SSE.
 func RowsRefresh(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -602,9 +1053,10 @@ func RowsRefresh(db *sql.DB) http.HandlerFunc {
 
 // --- Helpers ---
 
-func refreshPatternSections(sse *datastar.ServerSentEventGenerator, db *sql.DB, patternID int64) {
+func refreshPatternSections(sse *datastar.ServerSentEventGenerator, db *sql.DB, userID, patternID int64) {
 	_, sections, _ := model.LoadPatternFull(db, patternID)
-	sse.PatchElementTempl(view.PatternSections(patternID, sections), datastar.WithSelectorID("pattern-content"), datastar.WithModeInner())
+	collapsed, _ := model.CollapsedSections(db, userID, patternID)
+	sse.PatchElementTempl(view.PatternSections(patternID, sections, collapsed), datastar.WithSelectorID("pattern-content"), datastar.WithModeInner())
 	sse.PatchElementTempl(view.PatternSummaryBlock(sections), datastar.WithSelectorID("pattern-summary"))
 	sse.RemoveElementByID("pattern-error")
 }
@@ -614,7 +1066,195 @@ func refreshSectionRows(sse *datastar.ServerSentEventGenerator, db *sql.DB, sect
 	for i := range rows {
 		instructions, _ := model.ListInstructionsForRow(db, rows[i].ID)
 		rows[i].Instructions = instructions
+		rows[i].Markers, _ = model.ListRowMarkers(db, rows[i].ID)
 	}
 	sse.PatchElementTempl(view.RowList(sectionID, rows), datastar.WithSelectorID("section-"+strconv.FormatInt(sectionID, 10)+"-rows"), datastar.WithModeInner())
 	sse.RemoveElementByID("pattern-error")
 }
+
+// PatternReadView handles GET /patterns/{id}/view: the whole pattern, read-only and
+// print-friendly, with no work session involved.
+func PatternReadView(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		pattern, sections, err := model.LoadPatternFull(db, id)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if pattern.UserID != user.ID {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		// Show how far the most recent active session is through each section.
+		var progress *model.WorkProgress
+		if session, _ := model.FindActiveSession(db, user.ID, pattern.ID); session != nil {
+			progress, _ = model.GetProgress(db, session.ID)
+		}
+
+		prefs, _ := model.GetPreferences(db, user.ID)
+		renderTempl(w, r, http.StatusOK, view.PatternReadPage(pattern, sections, progress, prefs.ShowSymbols, user.Email))
+	}
+}
+
+// PatternExportPDF handles GET /patterns/{id}/export.pdf as a file download.
+func PatternExportPDF(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+
+		pattern, sections, err := model.LoadPatternFull(db, id)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if pattern.UserID != user.ID {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		data, err := model.RenderPatternPDF(pattern, sections)
+		if err != nil {
+			http.Error(w, "Failed to render PDF", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, model.ExportFilename(pattern.Name)))
+		w.Write(data)
+	}
+}
+
+// SectionExportText handles GET /sections/{id}/export.txt: the section's notation as
+// plain text, in the same form as the pattern summary, for pasting elsewhere.
+func SectionExportText(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		sectionID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		patternID, err := model.GetPatternIDForSection(db, sectionID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		section, ok := loadOwnedSection(w, r, db, patternID, sectionID, user.ID)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, model.RenderSectionSummary(*section))
+	}
+}
+
+// RowExportText handles GET /rows/{id}/export.txt: one row's notation line as plain text.
+func RowExportText(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		rowID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		row, err := model.FindRowByID(db, rowID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		patternID, err := model.GetPatternIDForRow(db, rowID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		section, ok := loadOwnedSection(w, r, db, patternID, row.SectionID, user.ID)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, model.RenderRowNotation(*section, rowID))
+	}
+}
+
+// loadOwnedSection loads a section with its rows and instructions, writing a 404 or
+// 403 and returning ok=false unless the user owns its pattern.
+func loadOwnedSection(w http.ResponseWriter, r *http.Request, db *sql.DB, patternID, sectionID, userID int64) (*model.PatternSection, bool) {
+	pattern, sections, err := model.LoadPatternFull(db, patternID)
+	if err != nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	if pattern.UserID != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+	for i := range sections {
+		if sections[i].ID == sectionID {
+			return &sections[i], true
+		}
+	}
+	http.NotFound(w, r)
+	return nil, false
+}
+
+// PatternExportAll handles GET /export/all.zip as a download of every pattern the
+// user owns, one JSON file per pattern in the API's format. The zip is streamed, so
+// a failure partway through can only be logged and leaves a truncated download.
+func PatternExportAll(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="stitchmap-patterns.zip"`)
+		if err := model.ExportAllPatternsZip(db, user.ID, w); err != nil {
+			log.Printf("export patterns for user %d: %v", user.ID, err)
+		}
+	}
+}
+
+// maxBackupUpload caps the zip a backup upload may send; model.ImportAllPatternsZip
+// caps what it unpacks to.
+const maxBackupUpload = 32 << 20
+
+// PatternImportBackup handles POST /import/all (multipart form submit) and restores
+// a zip made by PatternExportAll. Each JSON file becomes a new pattern; a file that
+// can't be read or imported is reported without stopping the rest. Stitches are
+// matched by abbreviation, and any the user doesn't have are created along with the
+// first pattern that uses them.
+func PatternImportBackup(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBackupUpload)
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			renderTempl(w, r, http.StatusUnprocessableEntity,
+				view.ImportBackupResultPage(user.Email, nil, 0, "Choose a backup zip of at most 32 MB."))
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		results, created, err := model.ImportAllPatternsZip(db, user.ID, data)
+		if err != nil {
+			msg := "That file isn't a zip."
+			switch {
+			case errors.Is(err, model.ErrBackupTooManyFiles):
+				msg = fmt.Sprintf("A backup can hold at most %d files.", model.MaxBackupEntries)
+			case errors.Is(err, model.ErrBackupTooLarge):
+				msg = "That backup is too large once unzipped."
+			}
+			renderTempl(w, r, http.StatusUnprocessableEntity, view.ImportBackupResultPage(user.Email, nil, 0, msg))
+			return
+		}
+		renderTempl(w, r, http.StatusOK, view.ImportBackupResultPage(user.Email, results, created, ""))
+	}
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stitchmap/stitchmap/internal/database"
+	"github.com/stitchmap/stitchmap/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestUser opens a fresh, migrated database and signs up one user in it.
+func newTestUser(t *testing.T) (*sql.DB, *model.User) {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := model.SeedBuiltinStitches(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.SetBcryptCost(bcrypt.MinCost); err != nil {
+		t.Fatal(err)
+	}
+	user, err := model.CreateUser(db, "a@b.com", "password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, user
+}
+
+// backupUpload builds a multipart request for POST /import/all carrying a zip of
+// files, signed in as user.
+func backupUpload(t *testing.T, user *model.User, files map[string]string) *http.Request {
+	t.Helper()
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "backup.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(zipped.Bytes())
+	mw.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/import/all", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r.WithContext(context.WithValue(r.Context(), userContextKey, user))
+}
+
+func TestPatternImportBackupLimits(t *testing.T) {
+	const pattern = `{"name": "Hat", "sections": [{"name": "Body", "rows": [{"type": "row", "repeat_count": 1,
+		"expected_stitch_count": 6, "instructions": [{"stitch_abbr": "sc", "count": 6}]}]}]}`
+	tooMany := make(map[string]string)
+	for i := range model.MaxBackupEntries + 1 {
+		tooMany[fmt.Sprintf("p%d.json", i)] = pattern
+	}
+	// Whitespace compresses to almost nothing, so this fits the upload limit but
+	// not the unzipped total.
+	tooLarge := make(map[string]string)
+	for i := range model.MaxBackupTotal/model.MaxBackupEntry + 1 {
+		tooLarge[fmt.Sprintf("p%d.json", i)] = pattern + strings.Repeat(" ", model.MaxBackupEntry-len(pattern)-1)
+	}
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		status   int
+		patterns int
+		message  string
+	}{
+		{"valid", map[string]string{"a.json": pattern, "b.json": pattern}, http.StatusOK, 2, "Created 2 patterns"},
+		{"too many files", tooMany, http.StatusUnprocessableEntity, 0, "at most 500 files"},
+		{"too large unzipped", tooLarge, http.StatusUnprocessableEntity, 0, "too large once unzipped"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, user := newTestUser(t)
+			w := httptest.NewRecorder()
+			PatternImportBackup(db)(w, backupUpload(t, user, tt.files))
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d", w.Code, tt.status)
+			}
+			if !strings.Contains(w.Body.String(), tt.message) {
+				t.Errorf("response does not say %q", tt.message)
+			}
+			patterns, err := model.ListPatternsByUser(db, user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(patterns) != tt.patterns {
+				t.Errorf("imported %d patterns, want %d", len(patterns), tt.patterns)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/stitchmap/stitchmap/internal/model"
+	"github.com/stitchmap/stitchmap/internal/view"
+)
+
+// Settings renders the account settings page.
+func Settings(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		if user == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		renderTempl(w, r, http.StatusOK, view.SettingsPage(settingsData(db, r, user)))
+	}
+}
+
+// settingsData builds the common settings page data for the current user.
+func settingsData(db *sql.DB, r *http.Request, user *model.User) view.SettingsData {
+	data := view.SettingsData{Email: user.Email, IsAdmin: user.IsAdmin}
+	data.Sessions, _ = model.ListSessionsForUser(db, user.ID)
+	data.APITokens, _ = model.ListAPITokens(db, user.ID)
+	data.Prefs, _ = model.GetPreferences(db, user.ID)
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		data.CurrentSessionKey = model.Session{ID: cookie.Value}.Key()
+	}
+	return data
+}
+
+// SettingsChangePassword handles POST /settings/password.
+// On success every other session for the user is signed out.
+func SettingsChangePassword(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		if user == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		current := r.FormValue("current_password")
+		password := r.FormValue("password")
+		passwordConfirm := r.FormValue("password_confirm")
+
+		data := settingsData(db, r, user)
+
+		if password != passwordConfirm {
+			data.PasswordError = "New passwords do not match."
+			renderTempl(w, r, http.StatusUnprocessableEntity, view.SettingsPage(data))
+			return
+		}
+
+		if err := model.ChangePassword(db, user.ID, current, password); err != nil {
+			status := http.StatusUnprocessableEntity
+			switch {
+			case errors.Is(err, model.ErrWrongPassword):
+				data.PasswordError = "Current password is incorrect."
+			case errors.Is(err, model.ErrPasswordTooShort):
+				data.PasswordError = fmt.Sprintf("New password must be at least %d characters.", model.MinPasswordLength)
+			default:
+				status = http.StatusInternalServerError
+				data.PasswordError = "Something went wrong. Please try again."
+			}
+			renderTempl(w, r, status, view.SettingsPage(data))
+			return
+		}
+
+		if cookie, err := r.Cookie(sessionCookieName); err == nil {
+			model.DeleteSessionsForUserExcept(db, user.ID, cookie.Value)
+		}
+
+		data = settingsData(db, r, user)
+		data.PasswordNotice = "Password changed. Other devices have been signed out."
+		renderTempl(w, r, http.StatusOK, view.SettingsPage(data))
+	}
+}
+
+// SettingsPreferences handles POST /settings/preferences.
+func SettingsPreferences(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		if user == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		prefs := model.Prefs{
+			CountDirection: r.FormValue("count_direction"),
+			DefaultRowType: r.FormValue("default_row_type"),
+			Theme:          r.FormValue("theme"),
+			ShowSymbols:    r.FormValue("show_symbols") == "on",
+		}
+		if err := model.SavePreferences(db, user.ID, prefs); err != nil {
+			data := settingsData(db, r, user)
+			data.PrefsError = "Those preferences couldn't be saved. Please try again."
+			renderTempl(w, r, http.StatusUnprocessableEntity, view.SettingsPage(data))
+			return
+		}
+
+		// The page is rendered before the middleware sees the new theme.
+		r = r.WithContext(view.WithTheme(r.Context(), prefs.Theme))
+		data := settingsData(db, r, user)
+		data.PrefsNotice = "Preferences saved."
+		renderTempl(w, r, http.StatusOK, view.SettingsPage(data))
+	}
+}
+
+// SettingsRevokeSession handles POST /settings/sessions/{token}/revoke.
+// {token} is the session's public Key, not the session token.
+// Revoking the current session logs the user out.
+func SettingsRevokeSession(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		if user == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		session, err := model.FindSessionByKey(db, user.ID, r.PathValue("token"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err := model.DeleteSession(db, session.ID); err != nil {
+			http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+			return
+		}
+
+		if cookie, err := r.Cookie(sessionCookieName); err == nil && cookie.Value == session.ID {
+			clearSessionCookie(w, r)
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+	}
+}
+
+// SettingsRevokeOtherSessions handles POST /settings/sessions/revoke-others.
+func SettingsRevokeOtherSessions(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		if user == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		cookie, err := r.Cookie(sessionCookieName)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		if err := model.DeleteSessionsForUserExcept(db, user.ID, cookie.Value); err != nil {
+			http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+	}
+}
+
+// SettingsCreateAPIToken handles POST /settings/tokens.
+// The new token is rendered once in the response and cannot be retrieved again.
+func SettingsCreateAPIToken(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		if user == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		label := strings.TrimSpace(r.FormValue("label"))
+		if label == "" {
+			data := settingsData(db, r, user)
+			data.APITokenError = "Give the token a name so you can recognize it later."
+			renderTempl(w, r, http.StatusUnprocessableEntity, view.SettingsPage(data))
+			return
+		}
+
+		_, token, err := model.CreateAPIToken(db, user.ID, label)
+		if err != nil {
+			data := settingsData(db, r, user)
+			data.APITokenError = "Something went wrong. Please try again."
+			renderTempl(w, r, http.StatusInternalServerError, view.SettingsPage(data))
+			return
+		}
+
+		data := settingsData(db, r, user)
+		data.NewAPIToken = token
+		renderTempl(w, r, http.StatusOK, view.SettingsPage(data))
+	}
+}
+
+// SettingsRevokeAPIToken handles POST /settings/tokens/{id}/revoke.
+func SettingsRevokeAPIToken(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		if user == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := model.RevokeAPIToken(db, id, user.ID); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+	}
+}
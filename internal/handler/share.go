@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/starfederation/datastar-go/datastar"
+	"github.com/stitchmap/stitchmap/internal/model"
+	"github.com/stitchmap/stitchmap/internal/view"
+)
+
+// SharedPatternShow handles GET /shared/{token}. It needs no login and renders the
+// pattern read-only, without anything identifying the owner.
+func SharedPatternShow(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pattern, err := model.FindPatternByShareToken(db, r.PathValue("token"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, sections, err := model.LoadPatternFull(db, pattern.ID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		renderTempl(w, r, http.StatusOK, view.SharedPatternPage(pattern, sections))
+	}
+}
+
+// ShareLinkCreate handles POST /patterns/{id}/share via SSE.
+func ShareLinkCreate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		patternID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		if !checkPatternOwnership(db, patternID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		errMsg := ""
+		if _, err := model.CreateShareLink(db, patternID, user.ID); err != nil {
+			errMsg = "Failed to create share link."
+		}
+
+		links, _ := model.ListShareLinks(db, patternID)
+		sse.PatchElementTempl(view.ShareLinksBlock(patternID, links, baseURL(r), errMsg))
+	}
+}
+
+// ShareLinkDelete handles DELETE /patterns/{id}/share/{token} via SSE.
+func ShareLinkDelete(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		patternID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		if !checkPatternOwnership(db, patternID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		errMsg := ""
+		if err := model.DeleteShareLink(db, r.PathValue("token"), user.ID); err != nil {
+			errMsg = "Failed to revoke share link."
+		}
+
+		links, _ := model.ListShareLinks(db, patternID)
+		sse.PatchElementTempl(view.ShareLinksBlock(patternID, links, baseURL(r), errMsg))
+	}
+}
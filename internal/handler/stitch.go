@@ -2,7 +2,10 @@ package handler
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -12,9 +15,59 @@ import (
 )
 
 type stitchSignals struct {
-	Name string `json:"stitchName"`
-	Abbr string `json:"stitchAbbr"`
-	Desc string `json:"stitchDesc"`
+	Name   string `json:"stitchName"`
+	Abbr   string `json:"stitchAbbr"`
+	Desc   string `json:"stitchDesc"`
+	Cat    string `json:"stitchCategory"`
+	Color  string `json:"stitchColor"`
+	Symbol string `json:"stitchSymbol"`
+}
+
+// stitchPickerSignals are the signals a StitchSelectWidget can be bound to, each with
+// whether a search selects the first match when the current choice is filtered out.
+// An edit form keeps the instruction's stitch until the user picks another.
+var stitchPickerSignals = map[string]bool{
+	"addInstrStitchID":   true,
+	"childInstrStitchID": true,
+	"editInstrStitchID":  false,
+}
+
+// StitchSearch handles GET /stitches/search?signal=...&id=...&q=... via SSE. It narrows
+// the stitch picker bound to signal in the form of row, group or instruction id to the
+// user's stitches whose name or abbreviation contains q. An add form selects the first
+// match unless the current choice is still listed.
+func StitchSearch(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		signal := r.URL.Query().Get("signal")
+		autoSelect, ok := stitchPickerSignals[signal]
+		ownerID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		if !ok || err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		stitches, err := model.ListStitchesForUser(db, user.ID)
+		if err != nil {
+			http.Error(w, "Failed to search stitches", http.StatusInternalServerError)
+			return
+		}
+		stitches = model.FilterStitches(stitches, r.URL.Query().Get("q"))
+
+		// Keep the current choice if it's still listed.
+		signals := map[string]any{}
+		datastar.ReadSignals(r, &signals)
+		current := fmt.Sprint(signals[signal])
+		keep := slices.ContainsFunc(stitches, func(s model.Stitch) bool {
+			return strconv.FormatInt(s.ID, 10) == current
+		})
+
+		sse := datastar.NewSSE(w, r)
+		sse.PatchElementTempl(view.StitchSelect(stitches, signal, ownerID))
+		if autoSelect && len(stitches) > 0 && !keep {
+			sse.MarshalAndPatchSignals(map[string]string{signal: strconv.FormatInt(stitches[0].ID, 10)})
+		}
+	}
 }
 
 // StitchIndex renders the full stitches management page (normal GET, no SSE).
@@ -26,7 +79,8 @@ func StitchIndex(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		stitches, err := model.ListStitchesForUser(db, user.ID)
+		stitches, err := model.ListStitchLibraryForUser(db, user.ID)
+		usage, _ := model.CountStitchUsageForUser(db, user.ID)
 		if err != nil {
 			renderTempl(w, r, http.StatusInternalServerError, view.StitchPage(view.StitchPageData{
 				Error: "Failed to load stitches.",
@@ -36,10 +90,66 @@ func StitchIndex(db *sql.DB) http.HandlerFunc {
 
 		renderTempl(w, r, http.StatusOK, view.StitchPage(view.StitchPageData{
 			Stitches: stitches,
+			Usage:    usage,
 		}, user.Email))
 	}
 }
 
+// maxStitchCSVUpload limits a stitch CSV upload.
+const maxStitchCSVUpload = 1 << 20
+
+// StitchImportCSV handles POST /stitches/import (multipart form submit), creating
+// custom stitches from an uploaded CSV. Lines that can't be imported are listed on
+// the stitch page without stopping the rest.
+func StitchImportCSV(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+
+		data := view.StitchPageData{}
+		status := http.StatusOK
+		r.Body = http.MaxBytesReader(w, r.Body, maxStitchCSVUpload)
+		if file, _, err := r.FormFile("file"); err != nil {
+			data.Error = "Choose a CSV file of at most 1 MB."
+			status = http.StatusUnprocessableEntity
+		} else {
+			created, errs := model.ImportStitchesCSV(db, user.ID, file)
+			file.Close()
+			data.Notice = fmt.Sprintf("Imported %d stitches.", created)
+			if len(errs) > 0 {
+				data.Notice += fmt.Sprintf(" %d lines were skipped:", len(errs))
+				data.ImportErrors = errs
+			}
+		}
+
+		data.Stitches, _ = model.ListStitchLibraryForUser(db, user.ID)
+		data.Usage, _ = model.CountStitchUsageForUser(db, user.ID)
+		renderTempl(w, r, status, view.StitchPage(data, user.Email))
+	}
+}
+
+// StitchExportCSV handles GET /stitches/export.csv as a download of the user's
+// custom stitches, in the format StitchImportCSV reads. Built-ins are left out.
+func StitchExportCSV(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+
+		stitches, err := model.ListCustomStitches(db, user.ID)
+		if err != nil {
+			http.Error(w, "Failed to load stitches", http.StatusInternalServerError)
+			return
+		}
+		data, err := model.ExportStitchesCSV(stitches)
+		if err != nil {
+			http.Error(w, "Failed to export stitches", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="stitches.csv"`)
+		w.Write(data)
+	}
+}
+
 // StitchCreate handles POST /stitches via Datastar SSE.
 func StitchCreate(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -58,6 +168,7 @@ func StitchCreate(db *sql.DB) http.HandlerFunc {
 		name := strings.TrimSpace(signals.Name)
 		abbr := strings.TrimSpace(signals.Abbr)
 		desc := strings.TrimSpace(signals.Desc)
+		category := strings.TrimSpace(signals.Cat)
 
 		sse := datastar.NewSSE(w, r)
 
@@ -65,11 +176,20 @@ func StitchCreate(db *sql.DB) http.HandlerFunc {
 			sse.PatchElementTempl(view.StitchError("Name and abbreviation are required."))
 			return
 		}
-
-		_, err := model.CreateStitch(db, user.ID, name, abbr, desc)
+		color, err := model.NormalizeStitchColor(signals.Color)
+		if err != nil {
+			sse.PatchElementTempl(view.StitchError("Color must be a hex color like #a1b2c3, or left blank."))
+			return
+		}
+		symbol, err := model.NormalizeStitchSymbol(signals.Symbol)
 		if err != nil {
+			sse.PatchElementTempl(view.StitchError(fmt.Sprintf("Symbol must be %d characters or fewer.", model.MaxStitchSymbolLength)))
+			return
+		}
+
+		if _, err := model.CreateStitch(db, user.ID, name, abbr, desc, category, color, symbol); err != nil {
 			if strings.Contains(err.Error(), "UNIQUE constraint") {
-				sse.PatchElementTempl(view.StitchError("A stitch with that abbreviation already exists."))
+				sse.PatchElementTempl(view.StitchError("You already have a custom stitch with that abbreviation."))
 			} else {
 				sse.PatchElementTempl(view.StitchError("Failed to create stitch."))
 			}
@@ -77,8 +197,9 @@ func StitchCreate(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Re-render the full stitch list and a blank form.
-		stitches, _ := model.ListStitchesForUser(db, user.ID)
-		sse.PatchElementTempl(view.StitchList(stitches), datastar.WithSelectorID("stitch-list"))
+		stitches, _ := model.ListStitchLibraryForUser(db, user.ID)
+		usage, _ := model.CountStitchUsageForUser(db, user.ID)
+		sse.PatchElementTempl(view.StitchList(stitches, usage), datastar.WithSelectorID("stitch-list"))
 		sse.PatchElementTempl(view.StitchForm(nil))
 		sse.RemoveElementByID("stitch-error")
 	}
@@ -147,6 +268,7 @@ func StitchUpdate(db *sql.DB) http.HandlerFunc {
 		name := strings.TrimSpace(signals.Name)
 		abbr := strings.TrimSpace(signals.Abbr)
 		desc := strings.TrimSpace(signals.Desc)
+		category := strings.TrimSpace(signals.Cat)
 
 		sse := datastar.NewSSE(w, r)
 
@@ -154,23 +276,66 @@ func StitchUpdate(db *sql.DB) http.HandlerFunc {
 			sse.PatchElementTempl(view.StitchError("Name and abbreviation are required."))
 			return
 		}
+		color, err := model.NormalizeStitchColor(signals.Color)
+		if err != nil {
+			sse.PatchElementTempl(view.StitchError("Color must be a hex color like #a1b2c3, or left blank."))
+			return
+		}
+		symbol, err := model.NormalizeStitchSymbol(signals.Symbol)
+		if err != nil {
+			sse.PatchElementTempl(view.StitchError(fmt.Sprintf("Symbol must be %d characters or fewer.", model.MaxStitchSymbolLength)))
+			return
+		}
 
-		if err := model.UpdateStitch(db, id, user.ID, name, abbr, desc); err != nil {
+		if err := model.UpdateStitch(db, id, user.ID, name, abbr, desc, category, color, symbol); err != nil {
 			if strings.Contains(err.Error(), "UNIQUE constraint") {
-				sse.PatchElementTempl(view.StitchError("A stitch with that abbreviation already exists."))
+				sse.PatchElementTempl(view.StitchError("You already have a custom stitch with that abbreviation."))
 			} else {
 				sse.PatchElementTempl(view.StitchError("Failed to update stitch."))
 			}
 			return
 		}
 
-		stitches, _ := model.ListStitchesForUser(db, user.ID)
-		sse.PatchElementTempl(view.StitchList(stitches), datastar.WithSelectorID("stitch-list"))
+		stitches, _ := model.ListStitchLibraryForUser(db, user.ID)
+		usage, _ := model.CountStitchUsageForUser(db, user.ID)
+		sse.PatchElementTempl(view.StitchList(stitches, usage), datastar.WithSelectorID("stitch-list"))
 		sse.PatchElementTempl(view.StitchForm(nil))
 		sse.RemoveElementByID("stitch-error")
 	}
 }
 
+// StitchDuplicate handles POST /stitches/{id}/duplicate via Datastar SSE.
+// The copy is opened in the edit form so it can be renamed straight away.
+func StitchDuplicate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		if user == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		stitch, err := model.DuplicateStitch(db, id, user.ID)
+		if err != nil {
+			sse.PatchElementTempl(view.StitchError("Failed to duplicate stitch."))
+			return
+		}
+
+		stitches, _ := model.ListStitchLibraryForUser(db, user.ID)
+		usage, _ := model.CountStitchUsageForUser(db, user.ID)
+		sse.PatchElementTempl(view.StitchList(stitches, usage), datastar.WithSelectorID("stitch-list"))
+		sse.PatchElementTempl(view.StitchForm(stitch))
+		sse.RemoveElementByID("stitch-error")
+	}
+}
+
 // StitchDelete handles DELETE /stitches/{id} via Datastar SSE.
 func StitchDelete(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -189,7 +354,16 @@ func StitchDelete(db *sql.DB) http.HandlerFunc {
 		sse := datastar.NewSSE(w, r)
 
 		if err := model.DeleteStitch(db, id, user.ID); err != nil {
-			sse.PatchElementTempl(view.StitchError("Cannot delete this stitch. It may be built-in or not yours."))
+			var inUse *model.StitchInUseError
+			if errors.As(err, &inUse) {
+				noun := "instructions"
+				if inUse.Count == 1 {
+					noun = "instruction"
+				}
+				sse.PatchElementTempl(view.StitchError(fmt.Sprintf("This stitch is used in %d %s. Change or remove it there before deleting.", inUse.Count, noun)))
+			} else {
+				sse.PatchElementTempl(view.StitchError("Cannot delete this stitch. It may be built-in or not yours."))
+			}
 			return
 		}
 
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stitchmap/stitchmap/internal/model"
+)
+
+func TestStitchSearch(t *testing.T) {
+	db, user := newTestUser(t)
+	stitches, err := model.ListStitchesForUser(db, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scID int64
+	for _, s := range stitches {
+		if s.Abbreviation == "sc" {
+			scID = s.ID
+		}
+	}
+
+	search := func(signal string, id int64) string {
+		t.Helper()
+		q := url.Values{
+			"signal":   {signal},
+			"id":       {fmt.Sprint(id)},
+			"q":        {"double"},
+			"datastar": {fmt.Sprintf(`{%q:"%d"}`, signal, scID)},
+		}
+		r := httptest.NewRequest(http.MethodGet, "/stitches/search?"+q.Encode(), nil)
+		r.Header.Set("Datastar-Request", "true")
+		r = r.WithContext(context.WithValue(r.Context(), userContextKey, user))
+		w := httptest.NewRecorder()
+		StitchSearch(db)(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", signal, w.Code)
+		}
+		return w.Body.String()
+	}
+
+	// sc doesn't match "double", so the current choice is filtered out.
+	add := search("addInstrStitchID", 12)
+	if !strings.Contains(add, `id="addInstrStitchID-12-select"`) {
+		t.Errorf("add form: picker for row 12 not patched:\n%s", add)
+	}
+	if !strings.Contains(add, "datastar-patch-signals") {
+		t.Error("add form: first match not selected")
+	}
+
+	edit := search("editInstrStitchID", 34)
+	if !strings.Contains(edit, `id="editInstrStitchID-34-select"`) {
+		t.Errorf("edit form: picker for instruction 34 not patched:\n%s", edit)
+	}
+	if strings.Contains(edit, "datastar-patch-signals") {
+		t.Error("edit form: search replaced the instruction's stitch")
+	}
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/starfederation/datastar-go/datastar"
+	"github.com/stitchmap/stitchmap/internal/model"
+	"github.com/stitchmap/stitchmap/internal/view"
+)
+
+type tagSignals struct {
+	Name string `json:"tagName"`
+}
+
+// PatternTagAdd handles POST /patterns/{id}/tags via SSE.
+func PatternTagAdd(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		patternID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		if !checkPatternOwnership(db, patternID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		signals := &tagSignals{}
+		if err := datastar.ReadSignals(r, signals); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		errMsg := ""
+		if _, err := model.AddTagToPattern(db, patternID, user.ID, signals.Name); err != nil {
+			if errors.Is(err, model.ErrInvalidTag) {
+				errMsg = fmt.Sprintf("Tags must be 1-%d characters.", model.MaxTagLength)
+			} else {
+				errMsg = "Failed to add tag."
+			}
+		}
+
+		tags, _ := model.ListTagsForPattern(db, patternID)
+		sse.PatchElementTempl(view.PatternTags(patternID, tags, errMsg))
+	}
+}
+
+// PatternTagRemove handles DELETE /patterns/{id}/tags/{tagID} via SSE.
+func PatternTagRemove(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		patternID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		tagID, _ := strconv.ParseInt(r.PathValue("tagID"), 10, 64)
+
+		if !checkPatternOwnership(db, patternID, user.ID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		errMsg := ""
+		if err := model.RemoveTagFromPattern(db, patternID, tagID); err != nil {
+			errMsg = "Failed to remove tag."
+		}
+
+		tags, _ := model.ListTagsForPattern(db, patternID)
+		sse.PatchElementTempl(view.PatternTags(patternID, tags, errMsg))
+	}
+}
@@ -2,8 +2,11 @@ package handler
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/starfederation/datastar-go/datastar"
 	"github.com/stitchmap/stitchmap/internal/model"
@@ -11,7 +14,9 @@ import (
 )
 
 // WorkStart handles GET /patterns/{id}/work.
-// Finds or creates an active session and renders the work mode page.
+// With ?session=ID it opens that session. Otherwise it resumes the pattern's only active
+// session, creates one if there are none, or shows a picker if there are several
+// (or if ?pick is given).
 func WorkStart(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user := UserFromContext(r.Context())
@@ -34,35 +39,101 @@ func WorkStart(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Find or create active session.
-		session, err := model.FindActiveSession(db, user.ID, patternID)
-		if err != nil {
-			http.Error(w, "Failed to look up session", http.StatusInternalServerError)
-			return
+		var session *model.WorkSession
+		if raw := r.URL.Query().Get("session"); raw != "" {
+			sessionID, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid session ID", http.StatusBadRequest)
+				return
+			}
+			session, err = model.FindSessionByID(db, sessionID)
+			if err != nil || session.UserID != user.ID || session.PatternID != patternID {
+				http.NotFound(w, r)
+				return
+			}
+		} else {
+			active, err := model.ListActiveSessionsForPattern(db, user.ID, patternID)
+			if err != nil {
+				http.Error(w, "Failed to look up session", http.StatusInternalServerError)
+				return
+			}
+			if len(active) > 1 || (r.URL.Query().Has("pick") && len(active) > 0) {
+				renderTempl(w, r, http.StatusOK, view.WorkSessionPickerPage(pattern, active, user.Email))
+				return
+			}
+			if len(active) == 1 {
+				session = &active[0]
+			}
 		}
 
 		if session == nil {
-			// Create a new session.
-			session, err = model.CreateWorkSession(db, user.ID, patternID)
+			// Create a new session, starting at the first stitch.
+			session, err = model.StartWorkSession(db, user.ID, patternID, "", sections)
+			if errors.Is(err, model.ErrNothingToTrack) {
+				renderTempl(w, r, http.StatusOK, view.WorkNoInstructionsPage(pattern, user.Email))
+				return
+			}
 			if err != nil {
 				http.Error(w, "Failed to create session", http.StatusInternalServerError)
 				return
 			}
-			// Initialize progress to first stitch.
-			if err := model.InitProgress(db, session.ID, sections); err != nil {
-				// Pattern has no instructions yet.
+		} else if err := model.RepairProgress(db, session.ID); err != nil {
+			// The pattern was edited since the session was last opened and has
+			// nothing left to work through.
+			if errors.Is(err, model.ErrNothingToTrack) {
 				renderTempl(w, r, http.StatusOK, view.WorkNoInstructionsPage(pattern, user.Email))
 				return
 			}
+			http.Error(w, "Failed to load work state", http.StatusInternalServerError)
+			return
 		}
 
-		state, err := loadWorkState(db, session, sections, pattern.Name)
+		state, err := loadWorkState(db, session, sections, pattern)
 		if err != nil {
 			http.Error(w, "Failed to load work state", http.StatusInternalServerError)
 			return
 		}
 
-		renderTempl(w, r, http.StatusOK, view.WorkPage(state, user.Email))
+		notes, _ := model.ListWorkNotes(db, session.ID)
+		renderTempl(w, r, http.StatusOK, view.WorkPage(state, notes, user.Email))
+	}
+}
+
+// WorkNew handles POST /patterns/{id}/work/new.
+// Starts an additional session on the pattern, e.g. for a second copy worked in parallel.
+func WorkNew(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		patternID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+
+		pattern, err := model.FindPatternByID(db, patternID)
+		if err != nil || pattern.UserID != user.ID {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, sections, err := model.LoadPatternFull(db, patternID)
+		if err != nil {
+			http.Error(w, "Failed to load pattern", http.StatusInternalServerError)
+			return
+		}
+
+		label := strings.TrimSpace(r.FormValue("label"))
+		session, err := model.StartWorkSession(db, user.ID, patternID, label, sections)
+		if errors.Is(err, model.ErrNothingToTrack) {
+			renderTempl(w, r, http.StatusOK, view.WorkNoInstructionsPage(pattern, user.Email))
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to create session", http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/patterns/%d/work?session=%d", patternID, session.ID), http.StatusSeeOther)
 	}
 }
 
@@ -78,7 +149,26 @@ func WorkAdvance(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		completed, err := model.AdvanceProgress(db, sessionID)
+		// Carrying on with a paused session resumes it.
+		if session.PausedAt != nil {
+			if err := model.ResumeSession(db, sessionID); err != nil {
+				http.Error(w, "Failed to resume", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		// ?n= advances several stitches at once, e.g. to finish a long round.
+		var completed bool
+		if nParam := r.URL.Query().Get("n"); nParam != "" {
+			n, convErr := strconv.Atoi(nParam)
+			if convErr != nil || n < 1 {
+				http.Error(w, "n must be a positive number", http.StatusBadRequest)
+				return
+			}
+			completed, err = model.AdvanceProgressBy(db, sessionID, n)
+		} else {
+			completed, err = model.AdvanceProgress(db, sessionID)
+		}
 		if err != nil {
 			http.Error(w, "Failed to advance", http.StatusInternalServerError)
 			return
@@ -99,7 +189,7 @@ func WorkAdvance(db *sql.DB) http.HandlerFunc {
 				Completed:   true,
 			}
 		} else {
-			state, _ = loadWorkState(db, session, sections, pattern.Name)
+			state, _ = loadWorkState(db, session, sections, pattern)
 		}
 
 		sse := datastar.NewSSE(w, r)
@@ -122,17 +212,155 @@ func WorkUndo(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		if err := model.UndoProgress(db, sessionID); err != nil {
+		// One load serves both the undo and the render after it.
+		pattern, sections, err := model.LoadPatternFull(db, session.PatternID)
+		if err != nil {
+			http.Error(w, "Failed to undo", http.StatusInternalServerError)
+			return
+		}
+		if err := model.UndoProgress(db, sessionID, sections); err != nil {
 			http.Error(w, "Failed to undo", http.StatusInternalServerError)
 			return
 		}
 
 		// Reload session (completed_at may have been cleared by undo).
+		session, _ = model.FindSessionByID(db, sessionID)
+		state, _ := loadWorkState(db, session, sections, pattern)
+
+		sse := datastar.NewSSE(w, r)
+		sse.PatchElementTempl(
+			view.WorkDisplay(state),
+			datastar.WithSelectorID("work-display"),
+		)
+	}
+}
+
+// WorkRedo handles POST /sessions/{id}/redo.
+// Replays the most recent undo.
+func WorkRedo(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		sessionID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		session, err := model.FindSessionByID(db, sessionID)
+		if err != nil || session.UserID != user.ID {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if err := model.RedoProgress(db, sessionID); err != nil && !errors.Is(err, model.ErrNothingToRedo) {
+			http.Error(w, "Failed to redo", http.StatusInternalServerError)
+			return
+		}
+
+		// Reload session (completed_at may have been set again by redo).
+		session, _ = model.FindSessionByID(db, sessionID)
+
+		_, sections, _ := model.LoadPatternFull(db, session.PatternID)
+		pattern, _ := model.FindPatternByID(db, session.PatternID)
+		state, _ := loadWorkState(db, session, sections, pattern)
+
+		sse := datastar.NewSSE(w, r)
+		sse.PatchElementTempl(
+			view.WorkDisplay(state),
+			datastar.WithSelectorID("work-display"),
+		)
+	}
+}
+
+// WorkBookmark handles POST /sessions/{id}/bookmark, saving the current position.
+func WorkBookmark(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		sessionID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		session, err := model.FindSessionByID(db, sessionID)
+		if err != nil || session.UserID != user.ID {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if err := model.SetBookmark(db, sessionID); err != nil {
+			http.Error(w, "Failed to set bookmark", http.StatusInternalServerError)
+			return
+		}
+
+		_, sections, _ := model.LoadPatternFull(db, session.PatternID)
+		pattern, _ := model.FindPatternByID(db, session.PatternID)
+		state, _ := loadWorkState(db, session, sections, pattern)
+
+		sse := datastar.NewSSE(w, r)
+		sse.PatchElementTempl(
+			view.WorkDisplay(state),
+			datastar.WithSelectorID("work-display"),
+		)
+	}
+}
+
+// WorkBookmarkJump handles POST /sessions/{id}/bookmark/jump, returning to the
+// bookmarked position.
+func WorkBookmarkJump(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		sessionID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		session, err := model.FindSessionByID(db, sessionID)
+		if err != nil || session.UserID != user.ID {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if err := model.JumpToBookmark(db, sessionID); err != nil && !errors.Is(err, model.ErrNoBookmark) {
+			http.Error(w, "Failed to jump to bookmark", http.StatusInternalServerError)
+			return
+		}
+
+		// Reload session (completed_at is cleared by the jump).
+		session, _ = model.FindSessionByID(db, sessionID)
+
+		_, sections, _ := model.LoadPatternFull(db, session.PatternID)
+		pattern, _ := model.FindPatternByID(db, session.PatternID)
+		state, _ := loadWorkState(db, session, sections, pattern)
+
+		sse := datastar.NewSSE(w, r)
+		sse.PatchElementTempl(
+			view.WorkDisplay(state),
+			datastar.WithSelectorID("work-display"),
+		)
+	}
+}
+
+// WorkPause handles POST /sessions/{id}/pause.
+func WorkPause(db *sql.DB) http.HandlerFunc {
+	return workPauseHandler(db, model.PauseSession)
+}
+
+// WorkResume handles POST /sessions/{id}/resume.
+func WorkResume(db *sql.DB) http.HandlerFunc {
+	return workPauseHandler(db, model.ResumeSession)
+}
+
+func workPauseHandler(db *sql.DB, fn func(*sql.DB, int64) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		sessionID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		session, err := model.FindSessionByID(db, sessionID)
+		if err != nil || session.UserID != user.ID {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if err := fn(db, sessionID); err != nil {
+			http.Error(w, "Failed to update session", http.StatusInternalServerError)
+			return
+		}
+
 		session, _ = model.FindSessionByID(db, sessionID)
 
 		_, sections, _ := model.LoadPatternFull(db, session.PatternID)
 		pattern, _ := model.FindPatternByID(db, session.PatternID)
-		state, _ := loadWorkState(db, session, sections, pattern.Name)
+		state, _ := loadWorkState(db, session, sections, pattern)
 
 		sse := datastar.NewSSE(w, r)
 		sse.PatchElementTempl(
@@ -142,11 +370,176 @@ func WorkUndo(db *sql.DB) http.HandlerFunc {
 	}
 }
 
+// WorkDelete handles DELETE /sessions/{id} via SSE.
+// Discards a session entirely (e.g. one started on the wrong pattern) and returns to the dashboard.
+func WorkDelete(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		sessionID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		if err := model.DeleteWorkSession(db, sessionID, user.ID); err != nil {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+		sse.Redirect("/")
+	}
+}
+
+// WorkRestart handles POST /sessions/{id}/restart.
+// Resets the session to the first stitch so the pattern can be worked again.
+func WorkRestart(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		sessionID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		session, err := model.FindSessionByID(db, sessionID)
+		if err != nil || session.UserID != user.ID {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		_, sections, err := model.LoadPatternFull(db, session.PatternID)
+		if err != nil {
+			http.Error(w, "Failed to load pattern", http.StatusInternalServerError)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		if err := model.RestartSession(db, sessionID, sections); err != nil {
+			msg := "Failed to restart session."
+			switch {
+			case errors.Is(err, model.ErrNothingToTrack):
+				msg = "This pattern has no stitches to work through."
+			}
+			sse.PatchElementTempl(view.WorkError(msg), datastar.WithSelectorID("work-display"), datastar.WithModePrepend())
+			return
+		}
+
+		session, _ = model.FindSessionByID(db, sessionID)
+		pattern, _ := model.FindPatternByID(db, session.PatternID)
+		state, _ := loadWorkState(db, session, sections, pattern)
+
+		sse.PatchElementTempl(
+			view.WorkDisplay(state),
+			datastar.WithSelectorID("work-display"),
+		)
+	}
+}
+
+type workNoteSignals struct {
+	Text string `json:"workNote"`
+}
+
+// WorkNoteCreate handles POST /sessions/{id}/notes.
+func WorkNoteCreate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		sessionID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		session, err := model.FindSessionByID(db, sessionID)
+		if err != nil || session.UserID != user.ID {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		signals := &workNoteSignals{}
+		if err := datastar.ReadSignals(r, signals); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		text := strings.TrimSpace(signals.Text)
+		sse := datastar.NewSSE(w, r)
+
+		errMsg := ""
+		if text == "" {
+			errMsg = "Note can't be empty."
+		} else if _, err := model.AddWorkNote(db, sessionID, text); err != nil {
+			errMsg = "Failed to save note."
+		}
+
+		notes, _ := model.ListWorkNotes(db, sessionID)
+		sse.PatchElementTempl(view.WorkNotesPanel(sessionID, notes, errMsg))
+	}
+}
+
+// WorkNoteDelete handles DELETE /sessions/{id}/notes/{noteID}.
+func WorkNoteDelete(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		sessionID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		noteID, _ := strconv.ParseInt(r.PathValue("noteID"), 10, 64)
+
+		session, err := model.FindSessionByID(db, sessionID)
+		if err != nil || session.UserID != user.ID {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+
+		errMsg := ""
+		if err := model.DeleteWorkNote(db, noteID, user.ID); err != nil {
+			errMsg = "Failed to delete note."
+		}
+
+		notes, _ := model.ListWorkNotes(db, sessionID)
+		sse.PatchElementTempl(view.WorkNotesPanel(sessionID, notes, errMsg))
+	}
+}
+
 // loadWorkState builds a WorkDisplayState from the current session + pattern data.
-func loadWorkState(db *sql.DB, session *model.WorkSession, sections []model.PatternSection, patternName string) (model.WorkDisplayState, error) {
+func loadWorkState(db *sql.DB, session *model.WorkSession, sections []model.PatternSection, pattern *model.Pattern) (model.WorkDisplayState, error) {
 	progress, err := model.GetProgress(db, session.ID)
 	if err != nil {
 		return model.WorkDisplayState{}, err
 	}
-	return model.BuildWorkDisplayState(session, progress, sections, patternName), nil
+	state := model.BuildWorkDisplayState(session, progress, sections, pattern.Name)
+	state.ApproxLengthInches = model.RowsToInches(state.RowsCompleted, pattern.GaugeRows)
+	state.AvgRowDuration, _ = model.AverageRowDuration(db, session.ID)
+	state.CanRedo = model.CanRedo(db, session.ID)
+	if bookmark, err := model.GetBookmark(db, session.ID); err == nil {
+		state.BookmarkLabel = model.PositionLabel(sections, bookmark)
+	}
+	prefs, _ := model.GetPreferences(db, session.UserID)
+	state.CountDown = prefs.CountDirection == model.CountDown
+	state.ShowSymbols = prefs.ShowSymbols
+	return state, nil
+}
+
+// WorkExportCSV handles GET /sessions/{id}/export.csv as a file download of the
+// session's row-by-row progress and timings.
+func WorkExportCSV(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		sessionID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+
+		session, err := model.FindSessionByID(db, sessionID)
+		if err != nil || session.UserID != user.ID {
+			http.NotFound(w, r)
+			return
+		}
+		pattern, err := model.FindPatternByID(db, session.PatternID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		data, err := model.ExportSessionCSV(db, sessionID)
+		if err != nil {
+			http.Error(w, "Failed to export session", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-session-%d.csv"`, model.ExportFilename(pattern.Name), sessionID))
+		w.Write(data)
+	}
 }
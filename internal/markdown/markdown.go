@@ -0,0 +1,63 @@
+// Package markdown renders the free-text fields of a pattern (descriptions and
+// section and row notes) from Markdown to HTML that is safe to put on the page.
+// Patterns can be imported from other users, so the output is always sanitized.
+package markdown
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	renderer = goldmark.New(goldmark.WithParser(newParser())) // raw HTML in the source is dropped
+	policy   = bluemonday.UGCPolicy()
+)
+
+// newParser is goldmark's CommonMark parser except that only "_" marks emphasis.
+// Crochet notation uses "*" for repeats ("*2 sc, inc* x6"), which CommonMark would
+// turn into italics and so hide the markers; "*" is left as written.
+func newParser() parser.Parser {
+	var inlines []util.PrioritizedValue
+	for _, v := range parser.DefaultInlineParsers() {
+		if p := v.Value.(parser.InlineParser); bytes.IndexByte(p.Trigger(), '*') >= 0 {
+			v.Value = underscoreEmphasis{p}
+		}
+		inlines = append(inlines, v)
+	}
+	return parser.NewParser(
+		parser.WithBlockParsers(parser.DefaultBlockParsers()...),
+		parser.WithInlineParsers(inlines...),
+		parser.WithParagraphTransformers(parser.DefaultParagraphTransformers()...),
+	)
+}
+
+// underscoreEmphasis is the emphasis parser triggered by "_" alone.
+type underscoreEmphasis struct {
+	parser.InlineParser
+}
+
+func (underscoreEmphasis) Trigger() []byte {
+	return []byte{'_'}
+}
+
+// ToHTML renders src as sanitized HTML. Text that comes out as a single paragraph
+// is returned without its <p> wrapper, so a plain one-line note still fits inline
+// where it was shown as text before.
+func ToHTML(src string) string {
+	var buf bytes.Buffer
+	if err := renderer.Convert([]byte(src), &buf); err != nil {
+		return policy.Sanitize(src)
+	}
+	out := strings.TrimSpace(policy.Sanitize(buf.String()))
+	if inner, ok := strings.CutPrefix(out, "<p>"); ok {
+		if inner, ok := strings.CutSuffix(inner, "</p>"); ok && !strings.Contains(inner, "<p>") {
+			return inner
+		}
+	}
+	return out
+}
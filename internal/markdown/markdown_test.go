@@ -0,0 +1,20 @@
+package markdown
+
+import "testing"
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"*2 sc, inc* x6", "*2 sc, inc* x6"},
+		{"**sc 6** around", "**sc 6** around"},
+		{"work _loosely_", "work <em>loosely</em>"},
+		{"__stop__ here", "<strong>stop</strong> here"},
+		{"* sc\n* inc", "<ul>\n<li>sc</li>\n<li>inc</li>\n</ul>"},
+	}
+	for _, tt := range tests {
+		if got := ToHTML(tt.src); got != tt.want {
+			t.Errorf("ToHTML(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrDisableSelf is returned when an admin tries to disable their own account.
+var ErrDisableSelf = errors.New("you can't disable your own account")
+
+// AdminUserSummary is one account on the admin users page.
+type AdminUserSummary struct {
+	User
+	Patterns      int
+	Stitches      int // custom stitches
+	WorkSessions  int
+	LoginSessions int // unexpired login sessions
+}
+
+// ListUsersForAdmin returns every account with its counts, oldest first.
+func ListUsersForAdmin(db *sql.DB) ([]AdminUserSummary, error) {
+	rows, err := db.Query(`
+		SELECT u.id, u.email, u.password_hash, u.created_at, u.is_admin, u.disabled_at,
+		       (SELECT COUNT(*) FROM patterns WHERE user_id = u.id),
+		       (SELECT COUNT(*) FROM stitches WHERE user_id = u.id),
+		       (SELECT COUNT(*) FROM work_sessions WHERE user_id = u.id),
+		       (SELECT COUNT(*) FROM sessions WHERE user_id = u.id AND expires_at > ?)
+		FROM users u
+		ORDER BY u.id
+	`, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []AdminUserSummary
+	for rows.Next() {
+		var s AdminUserSummary
+		var createdAt string
+		var disabledAt sql.NullString
+		if err := rows.Scan(&s.ID, &s.Email, &s.PasswordHash, &createdAt, &s.IsAdmin, &disabledAt,
+			&s.Patterns, &s.Stitches, &s.WorkSessions, &s.LoginSessions); err != nil {
+			return nil, fmt.Errorf("scan user: %w", err)
+		}
+		s.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		if disabledAt.Valid {
+			t, _ := time.Parse(time.RFC3339, disabledAt.String)
+			s.DisabledAt = &t
+		}
+		users = append(users, s)
+	}
+	return users, rows.Err()
+}
+
+// DisableUser disables an account and logs it out everywhere. Its API tokens are
+// kept but refused while the account is disabled. adminID is the admin doing it,
+// who can't disable themselves.
+func DisableUser(db *sql.DB, adminID, userID int64) error {
+	if adminID == userID {
+		return ErrDisableSelf
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users SET disabled_at = COALESCE(disabled_at, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+		WHERE id = ?
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("disable user: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.Exec("DELETE FROM sessions WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("delete sessions: %w", err)
+	}
+	return tx.Commit()
+}
+
+// EnableUser lifts an account's disable; its owner can log in again.
+func EnableUser(db *sql.DB, userID int64) error {
+	result, err := db.Exec("UPDATE users SET disabled_at = NULL WHERE id = ?", userID)
+	if err != nil {
+		return fmt.Errorf("enable user: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// MakeAdmin gives the account with the given email admin rights. It returns
+// sql.ErrNoRows if there is no such account.
+func MakeAdmin(db *sql.DB, email string) error {
+	user, err := FindUserByEmail(db, email)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec("UPDATE users SET is_admin = 1 WHERE id = ?", user.ID); err != nil {
+		return fmt.Errorf("make admin: %w", err)
+	}
+	return nil
+}
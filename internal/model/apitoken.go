@@ -0,0 +1,108 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// apiTokenPrefix marks StitchMap API tokens so they are recognizable in config files.
+const apiTokenPrefix = "sm_"
+
+// APIToken is a personal access token for the JSON API. The token itself is never
+// stored; Prefix is kept so the user can tell their tokens apart.
+type APIToken struct {
+	ID         int64
+	UserID     int64
+	Label      string
+	Prefix     string // first characters of the raw token, e.g. "sm_3f9a1c"
+	CreatedAt  time.Time
+	LastUsedAt *time.Time // nil if never used
+}
+
+// CreateAPIToken mints a new token for the user. The raw token is returned only here.
+func CreateAPIToken(db *sql.DB, userID int64, label string) (*APIToken, string, error) {
+	secret, err := generateToken()
+	if err != nil {
+		return nil, "", err
+	}
+	token := apiTokenPrefix + secret
+	prefix := token[:len(apiTokenPrefix)+6]
+
+	result, err := db.Exec(
+		"INSERT INTO api_tokens (user_id, label, token_hash, prefix) VALUES (?, ?, ?, ?)",
+		userID, label, hashToken(token), prefix,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("insert api token: %w", err)
+	}
+
+	id, _ := result.LastInsertId()
+	return &APIToken{
+		ID:        id,
+		UserID:    userID,
+		Label:     label,
+		Prefix:    prefix,
+		CreatedAt: time.Now().UTC(),
+	}, token, nil
+}
+
+// FindUserByAPIToken returns the owner of a token and records the token as used.
+func FindUserByAPIToken(db *sql.DB, token string) (*User, error) {
+	var id, userID int64
+	err := db.QueryRow(
+		"SELECT id, user_id FROM api_tokens WHERE token_hash = ?",
+		hashToken(token),
+	).Scan(&id, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	db.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?",
+		time.Now().UTC().Format(time.RFC3339), id)
+
+	return FindUserByID(db, userID)
+}
+
+// ListAPITokens returns the user's tokens, newest first.
+func ListAPITokens(db *sql.DB, userID int64) ([]APIToken, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, label, prefix, created_at, last_used_at
+		FROM api_tokens WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list api tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []APIToken
+	for rows.Next() {
+		var t APIToken
+		var createdAt string
+		var lastUsedAt sql.NullString
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Label, &t.Prefix, &createdAt, &lastUsedAt); err != nil {
+			return nil, fmt.Errorf("scan api token: %w", err)
+		}
+		t.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		if lastUsedAt.Valid {
+			if lu, err := time.Parse(time.RFC3339, lastUsedAt.String); err == nil {
+				t.LastUsedAt = &lu
+			}
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeAPIToken deletes one of the user's tokens.
+func RevokeAPIToken(db *sql.DB, id, userID int64) error {
+	result, err := db.Exec("DELETE FROM api_tokens WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return fmt.Errorf("revoke api token: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -2,72 +2,160 @@ package model
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 )
 
 type Session struct {
-	ID        string
-	UserID    int64
-	CreatedAt time.Time
-	ExpiresAt time.Time
+	ID         string
+	UserID     int64
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+}
+
+// Key is a stable public identifier for the session, safe to put in URLs and
+// page markup without exposing the session token itself.
+func (s Session) Key() string {
+	return hashToken(s.ID)[:16]
 }
 
 const sessionDuration = 30 * 24 * time.Hour // 30 days
 
-func CreateSession(db *sql.DB, userID int64) (*Session, error) {
+const passwordResetDuration = time.Hour
+
+// ErrInvalidResetToken is returned for unknown, expired, or already-used reset tokens.
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// generateToken returns a random 32-byte token, hex-encoded.
+func generateToken() (string, error) {
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
-		return nil, fmt.Errorf("generate token: %w", err)
+		return "", fmt.Errorf("generate token: %w", err)
 	}
+	return hex.EncodeToString(tokenBytes), nil
+}
 
-	token := hex.EncodeToString(tokenBytes)
-	expiresAt := time.Now().UTC().Add(sessionDuration)
+// hashToken returns the hex SHA-256 of a token, for tokens that must not be stored in plaintext.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
 
-	_, err := db.Exec(
-		"INSERT INTO sessions (id, user_id, expires_at) VALUES (?, ?, ?)",
-		token, userID, expiresAt.Format(time.RFC3339),
+func CreateSession(db *sql.DB, userID int64, userAgent string) (*Session, error) {
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(sessionDuration)
+
+	_, err = db.Exec(
+		"INSERT INTO sessions (id, user_id, user_agent, last_seen_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+		token, userID, userAgent, now.Format(time.RFC3339), expiresAt.Format(time.RFC3339),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("insert session: %w", err)
 	}
 
 	return &Session{
-		ID:        token,
-		UserID:    userID,
-		CreatedAt: time.Now().UTC(),
-		ExpiresAt: expiresAt,
+		ID:         token,
+		UserID:     userID,
+		UserAgent:  userAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
 	}, nil
 }
 
 // FindSession looks up a session by token and returns it if valid (not expired).
 func FindSession(db *sql.DB, token string) (*Session, error) {
 	s := &Session{}
-	var createdAt, expiresAt string
+	var createdAt, lastSeenAt, expiresAt string
 	err := db.QueryRow(
-		"SELECT id, user_id, created_at, expires_at FROM sessions WHERE id = ? AND expires_at > ?",
+		"SELECT id, user_id, user_agent, created_at, last_seen_at, expires_at FROM sessions WHERE id = ? AND expires_at > ?",
 		token, time.Now().UTC().Format(time.RFC3339),
-	).Scan(&s.ID, &s.UserID, &createdAt, &expiresAt)
+	).Scan(&s.ID, &s.UserID, &s.UserAgent, &createdAt, &lastSeenAt, &expiresAt)
 	if err != nil {
 		return nil, err
 	}
 	s.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	s.LastSeenAt, _ = time.Parse(time.RFC3339, lastSeenAt)
 	s.ExpiresAt, _ = time.Parse(time.RFC3339, expiresAt)
 
-	// Roll the expiry forward on activity.
-	newExpiry := time.Now().UTC().Add(sessionDuration).Format(time.RFC3339)
-	db.Exec("UPDATE sessions SET expires_at = ? WHERE id = ?", newExpiry, token)
+	// Roll the expiry forward and record activity.
+	now := time.Now().UTC()
+	db.Exec("UPDATE sessions SET expires_at = ?, last_seen_at = ? WHERE id = ?",
+		now.Add(sessionDuration).Format(time.RFC3339), now.Format(time.RFC3339), token)
 
 	return s, nil
 }
 
+// ListSessionsForUser returns the user's unexpired sessions, most recently active first.
+func ListSessionsForUser(db *sql.DB, userID int64) ([]Session, error) {
+	rows, err := db.Query(
+		`SELECT id, user_id, user_agent, created_at, last_seen_at, expires_at
+		 FROM sessions WHERE user_id = ? AND expires_at > ?
+		 ORDER BY last_seen_at DESC`,
+		userID, time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var s Session
+		var createdAt, lastSeenAt, expiresAt string
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &createdAt, &lastSeenAt, &expiresAt); err != nil {
+			return nil, fmt.Errorf("scan session: %w", err)
+		}
+		s.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		s.LastSeenAt, _ = time.Parse(time.RFC3339, lastSeenAt)
+		s.ExpiresAt, _ = time.Parse(time.RFC3339, expiresAt)
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// FindSessionByKey returns the user's session whose Key matches key.
+func FindSessionByKey(db *sql.DB, userID int64, key string) (*Session, error) {
+	sessions, err := ListSessionsForUser(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sessions {
+		if s.Key() == key {
+			return &s, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
 func DeleteSession(db *sql.DB, token string) error {
 	_, err := db.Exec("DELETE FROM sessions WHERE id = ?", token)
 	return err
 }
 
+// DeleteSessionsForUser logs a user out everywhere.
+func DeleteSessionsForUser(db *sql.DB, userID int64) error {
+	_, err := db.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+	return err
+}
+
+// DeleteSessionsForUserExcept logs a user out of every session but keepToken.
+func DeleteSessionsForUserExcept(db *sql.DB, userID int64, keepToken string) error {
+	_, err := db.Exec("DELETE FROM sessions WHERE user_id = ? AND id != ?", userID, keepToken)
+	return err
+}
+
 func DeleteExpiredSessions(db *sql.DB) (int64, error) {
 	result, err := db.Exec(
 		"DELETE FROM sessions WHERE expires_at <= ?",
@@ -78,3 +166,84 @@ func DeleteExpiredSessions(db *sql.DB) (int64, error) {
 	}
 	return result.RowsAffected()
 }
+
+// --- Password reset tokens ---
+
+// CreatePasswordResetToken issues a single-use reset token valid for one hour.
+// Any earlier outstanding tokens for the user are invalidated.
+func CreatePasswordResetToken(db *sql.DB, userID int64) (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM password_reset_tokens WHERE user_id = ?", userID); err != nil {
+		return "", fmt.Errorf("clear reset tokens: %w", err)
+	}
+
+	expiresAt := time.Now().UTC().Add(passwordResetDuration)
+	if _, err := tx.Exec(
+		"INSERT INTO password_reset_tokens (token_hash, user_id, expires_at) VALUES (?, ?, ?)",
+		hashToken(token), userID, expiresAt.Format(time.RFC3339),
+	); err != nil {
+		return "", fmt.Errorf("insert reset token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConsumePasswordResetToken validates a reset token and deletes it so it cannot be reused.
+// Returns the user ID the token was issued for, or ErrInvalidResetToken.
+func ConsumePasswordResetToken(db *sql.DB, token string) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	var expiresAt string
+	err = tx.QueryRow(
+		"SELECT user_id, expires_at FROM password_reset_tokens WHERE token_hash = ?",
+		hashToken(token),
+	).Scan(&userID, &expiresAt)
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidResetToken
+	}
+	if err != nil {
+		return 0, fmt.Errorf("find reset token: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM password_reset_tokens WHERE token_hash = ?", hashToken(token)); err != nil {
+		return 0, fmt.Errorf("delete reset token: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	if exp, _ := time.Parse(time.RFC3339, expiresAt); !time.Now().UTC().Before(exp) {
+		return 0, ErrInvalidResetToken
+	}
+	return userID, nil
+}
+
+// DeleteExpiredPasswordResetTokens removes reset tokens past their expiry.
+func DeleteExpiredPasswordResetTokens(db *sql.DB) (int64, error) {
+	result, err := db.Exec(
+		"DELETE FROM password_reset_tokens WHERE expires_at <= ?",
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,352 @@
+package model
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// BackupFileResult is the outcome of restoring one file of a backup zip.
+type BackupFileResult struct {
+	File    string
+	Pattern *Pattern // the created pattern; nil if the file failed
+	Err     error
+}
+
+// Limits on a backup zip: each JSON file once unpacked, the number of files, and all
+// of them unpacked together.
+const (
+	MaxBackupEntry   = 8 << 20
+	MaxBackupEntries = 500
+	MaxBackupTotal   = 64 << 20
+)
+
+// Errors returned by ImportAllPatternsZip for a backup it won't read at all.
+var (
+	ErrBackupNotZip       = errors.New("not a zip")
+	ErrBackupTooManyFiles = errors.New("backup has too many files")
+	ErrBackupTooLarge     = errors.New("backup is too large once unzipped")
+)
+
+// ErrInvalidImport is wrapped by ImportPattern when the pattern data doesn't pass
+// the checks the editor would apply.
+var ErrInvalidImport = errors.New("invalid pattern data")
+
+// resolveImportStitches maps the lowercased abbreviation of every instruction's
+// stitch to one of the user's stitches, creating a custom stitch through tx for each
+// abbreviation the user doesn't have yet, named after the instruction's stitch name.
+// It returns the map and the number of stitches created.
+func resolveImportStitches(tx *sql.Tx, userID int64, sections []PatternSection) (map[string]int64, int, error) {
+	byAbbr, err := stitchIDsByAbbr(tx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	created := 0
+	var walk func([]RowInstruction) error
+	walk = func(instructions []RowInstruction) error {
+		for _, ri := range instructions {
+			if err := walk(ri.Children); err != nil {
+				return err
+			}
+			abbr := strings.TrimSpace(ri.StitchAbbr)
+			key := strings.ToLower(abbr)
+			if _, ok := byAbbr[key]; ok || key == "" || ri.IsGroup {
+				continue
+			}
+			name := strings.TrimSpace(ri.StitchName)
+			if name == "" {
+				name = abbr
+			}
+			stitch, err := insertStitch(tx, userID, name, abbr, "", "", "", "")
+			if err != nil {
+				return err
+			}
+			byAbbr[key] = stitch.ID
+			created++
+		}
+		return nil
+	}
+	for _, section := range sections {
+		for _, row := range section.Rows {
+			if err := walk(row.Instructions); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	return byAbbr, created, nil
+}
+
+// ImportPattern creates a whole pattern (details, sections, rows and instruction
+// trees) in one transaction. Instructions are linked to the user's stitches by
+// abbreviation; a custom stitch is created in the same transaction for any the user
+// doesn't have, so a pattern that fails to import leaves no stitches behind. IDs and
+// positions in the input are ignored and everything is inserted in slice order. It
+// returns the new pattern and the number of stitches created.
+func ImportPattern(db *sql.DB, userID int64, pattern *Pattern, sections []PatternSection) (*Pattern, int, error) {
+	if err := checkImport(pattern, sections); err != nil {
+		return nil, 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	byAbbr, created, err := resolveImportStitches(tx, userID, sections)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	meta := pattern.Meta
+	result, err := tx.Exec(`
+		INSERT INTO patterns (user_id, name, description, gauge_stitches, gauge_rows, difficulty, hook_size, yarn_weight, finished_size)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, userID, pattern.Name, pattern.Description, pattern.GaugeStitches, pattern.GaugeRows,
+		meta.Difficulty, meta.HookSize, meta.YarnWeight, meta.FinishedSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("insert pattern: %w", err)
+	}
+	patternID, _ := result.LastInsertId()
+
+	for si, section := range sections {
+		result, err := tx.Exec(
+			"INSERT INTO pattern_sections (pattern_id, position, name, notes) VALUES (?, ?, ?, ?)",
+			patternID, si+1, section.Name, section.Notes,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("insert section: %w", err)
+		}
+		sectionID, _ := result.LastInsertId()
+
+		for ri, row := range section.Rows {
+			result, err := tx.Exec(`
+				INSERT INTO rows (section_id, position, label, type, expected_stitch_count,
+				                  turning_chain_count, turning_chain_counts_as_stitch, repeat_count, notes, count_only, check_previous)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, sectionID, ri+1, row.Label, row.Type, row.ExpectedStitchCount,
+				row.TurningChainCount, row.TurningChainCountsAsStitch, row.RepeatCount, row.Notes, row.CountOnly, row.CheckPrevious)
+			if err != nil {
+				return nil, 0, fmt.Errorf("insert row: %w", err)
+			}
+			rowID, _ := result.LastInsertId()
+			if err := insertInstructionTree(tx, rowID, nil, 0, row.Instructions, byAbbr); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, err
+	}
+	p, err := FindPatternByID(db, patternID)
+	return p, created, err
+}
+
+// checkImport applies the editor's validation to a pattern about to be imported,
+// normalizing the pattern details in place.
+func checkImport(pattern *Pattern, sections []PatternSection) error {
+	pattern.Name = strings.TrimSpace(pattern.Name)
+	if pattern.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidImport)
+	}
+	if pattern.GaugeStitches < 0 || pattern.GaugeRows < 0 {
+		return fmt.Errorf("%w: gauge must not be negative", ErrInvalidImport)
+	}
+	if err := pattern.Meta.Normalize(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	for _, section := range sections {
+		for _, row := range section.Rows {
+			if !IsValidRowType(row.Type) {
+				return fmt.Errorf("%w: %v %q", ErrInvalidImport, ErrInvalidRowType, row.Type)
+			}
+			if row.RepeatCount < 1 || row.RepeatCount > MaxRowRepeat ||
+				row.ExpectedStitchCount < 0 || row.TurningChainCount < 0 {
+				return fmt.Errorf("%w: row counts out of range", ErrInvalidImport)
+			}
+			if err := checkImportInstructions(row.Instructions, nil); err != nil {
+				return err
+			}
+			toEnd := 0
+			for _, ri := range row.Instructions {
+				if ri.ToEnd {
+					toEnd++
+				}
+			}
+			if toEnd > 1 {
+				return fmt.Errorf("%w: only one instruction per row can be worked to end", ErrInvalidImport)
+			}
+		}
+	}
+	return nil
+}
+
+func checkImportInstructions(instructions []RowInstruction, parent *RowInstruction) error {
+	for _, ri := range instructions {
+		var stitchID *int64
+		if ri.StitchAbbr != "" {
+			stitchID = new(int64) // resolved later; only its presence matters here
+		}
+		if err := checkInstructionPlacement(parent, ri.IsGroup, stitchID, ri.Note); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		if ri.Count < 0 || ri.GroupRepeat < 0 || (ri.Consumes != nil && *ri.Consumes < 0) {
+			return fmt.Errorf("%w: negative count", ErrInvalidImport)
+		}
+		if err := CheckInstructionCounts(ri.Count, ri.GroupRepeat, ri.Consumes); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		if ri.ToEnd {
+			var parentID *int64
+			if parent != nil {
+				parentID = &parent.ID
+			}
+			if err := checkToEnd(parentID, ri.IsGroup, stitchID); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+			}
+		}
+		if len(ri.Children) > 0 && !ri.IsGroup {
+			return fmt.Errorf("%w: %v", ErrInvalidImport, ErrParentNotGroup)
+		}
+		if err := checkImportInstructions(ri.Children, &ri); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportAllPatternsZip writes every pattern the user owns to w as a zip, one
+// PatternJSON file per pattern named <slug>.json (see ExportFilename), adding -2,
+// -3, ... to slugs that are already taken. The zip is streamed, so an error partway
+// through leaves w holding a truncated zip.
+func ExportAllPatternsZip(db *sql.DB, userID int64, w io.Writer) error {
+	patterns, err := ListPatternsByUser(db, userID)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+	used := make(map[string]bool)
+	for _, p := range patterns {
+		pattern, sections, err := LoadPatternFull(db, p.ID)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(NewPatternJSON(pattern, sections), "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode pattern %d: %w", p.ID, err)
+		}
+
+		base := ExportFilename(pattern.Name)
+		name := base
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[name] = true
+
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name + ".json", Method: zip.Deflate, Modified: pattern.UpdatedAt})
+		if err != nil {
+			return fmt.Errorf("add %s to zip: %w", name, err)
+		}
+		if _, err := f.Write(data); err != nil {
+			return fmt.Errorf("write %s to zip: %w", name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// ExportFilename turns a pattern name into a safe download filename.
+func ExportFilename(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '_':
+			b.WriteRune('-')
+		}
+	}
+	if s := strings.Trim(b.String(), "-"); s != "" {
+		return s
+	}
+	return "pattern"
+}
+
+// ImportAllPatternsZip restores a zip made by ExportAllPatternsZip. Each JSON file
+// becomes a new pattern through ImportPattern; a file that can't be read or imported
+// is reported in its BackupFileResult without stopping the rest. It returns the
+// results in zip order and the number of stitches created, or ErrBackupNotZip,
+// ErrBackupTooManyFiles or ErrBackupTooLarge without importing anything.
+func ImportAllPatternsZip(db *sql.DB, userID int64, data []byte) ([]BackupFileResult, int, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, 0, ErrBackupNotZip
+	}
+	if len(zr.File) > MaxBackupEntries {
+		return nil, 0, ErrBackupTooManyFiles
+	}
+	var total uint64
+	for _, f := range zr.File {
+		total += f.UncompressedSize64
+	}
+	if total > MaxBackupTotal {
+		return nil, 0, ErrBackupTooLarge
+	}
+
+	// The sizes in the zip can't be trusted, so the bytes actually unpacked are
+	// counted against the same total.
+	var results []BackupFileResult
+	created := 0
+	budget := int64(MaxBackupTotal)
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		res := BackupFileResult{File: f.Name}
+		pj, err := readBackupEntry(f, &budget)
+		if err != nil {
+			res.Err = err
+		} else {
+			pattern, sections := pj.Unpack()
+			var n int
+			res.Pattern, n, res.Err = ImportPattern(db, userID, pattern, sections)
+			created += n
+		}
+		results = append(results, res)
+	}
+	return results, created, nil
+}
+
+// readBackupEntry decodes one file of a backup zip, taking the bytes it unpacks
+// off budget.
+func readBackupEntry(f *zip.File, budget *int64) (PatternJSON, error) {
+	var pj PatternJSON
+	if !strings.EqualFold(path.Ext(f.Name), ".json") {
+		return pj, errors.New("not a JSON file")
+	}
+	if f.UncompressedSize64 > MaxBackupEntry {
+		return pj, errors.New("file is too large")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return pj, fmt.Errorf("open: %w", err)
+	}
+	defer rc.Close()
+	limit := min(MaxBackupEntry, *budget)
+	lr := &io.LimitedReader{R: rc, N: limit}
+	err = json.NewDecoder(lr).Decode(&pj)
+	*budget -= limit - lr.N
+	if err != nil && lr.N == 0 {
+		return pj, ErrBackupTooLarge
+	}
+	if err != nil {
+		return pj, fmt.Errorf("not a pattern export: %w", err)
+	}
+	return pj, nil
+}
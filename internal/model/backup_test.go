@@ -0,0 +1,159 @@
+package model
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestImportPatternCreatesStitchesWithThePattern(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "a@b.com")
+	customStitches := func() int {
+		t.Helper()
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM stitches WHERE user_id = ?", user.ID).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	pattern := func(instructions ...RowInstruction) (*Pattern, []PatternSection) {
+		return &Pattern{Name: "Imported"}, []PatternSection{{Name: "Body", Rows: []Row{{
+			Type: "row", RepeatCount: 1, ExpectedStitchCount: 6, Instructions: instructions,
+		}}}}
+	}
+
+	// Invalid: two instructions worked to end. Nothing may be created, not even
+	// the stitch the pattern would have needed.
+	p, sections := pattern(
+		RowInstruction{StitchAbbr: "bobble", StitchName: "Bobble", Count: 1, ToEnd: true},
+		RowInstruction{StitchAbbr: "sc", Count: 1, ToEnd: true},
+	)
+	if _, _, err := ImportPattern(db, user.ID, p, sections); !errors.Is(err, ErrInvalidImport) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidImport)
+	}
+	if n := customStitches(); n != 0 {
+		t.Fatalf("a rejected import created %d stitches", n)
+	}
+
+	// Valid: the unknown stitch is created once, however often it is used, and
+	// built-ins are matched case-insensitively.
+	p, sections = pattern(
+		RowInstruction{StitchAbbr: "Bobble", StitchName: "Bobble", Count: 2},
+		RowInstruction{IsGroup: true, GroupRepeat: 2, Children: []RowInstruction{
+			{StitchAbbr: "bobble", Count: 1}, {StitchAbbr: "SC", Count: 1},
+		}},
+	)
+	imported, created, err := ImportPattern(db, user.ID, p, sections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 1 || customStitches() != 1 {
+		t.Fatalf("created %d stitches (%d stored), want 1", created, customStitches())
+	}
+	_, loaded, err := LoadPatternFull(db, imported.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	instrs := loaded[0].Rows[0].Instructions
+	if instrs[0].StitchAbbr != "Bobble" || instrs[1].Children[0].StitchID == nil ||
+		*instrs[1].Children[0].StitchID != *instrs[0].StitchID || instrs[1].Children[1].StitchAbbr != "sc" {
+		t.Fatalf("stitches not linked: %+v", instrs)
+	}
+
+	// A second import reuses the stitch the first one created.
+	p, sections = pattern(RowInstruction{StitchAbbr: "bobble", Count: 6})
+	if _, created, err := ImportPattern(db, user.ID, p, sections); err != nil || created != 0 {
+		t.Fatalf("second import: created %d, err %v", created, err)
+	}
+}
+
+// comparablePatternJSON clears what an import doesn't carry over: IDs, positions,
+// timestamps and the stitch IDs, which belong to the importing user.
+func comparablePatternJSON(pj PatternJSON) PatternJSON {
+	pj.ID, pj.CreatedAt, pj.UpdatedAt = 0, time.Time{}, time.Time{}
+	var clear func([]InstructionJSON)
+	clear = func(instructions []InstructionJSON) {
+		for i := range instructions {
+			instructions[i].ID, instructions[i].Position, instructions[i].StitchID = 0, 0, nil
+			clear(instructions[i].Children)
+		}
+	}
+	for si := range pj.Sections {
+		pj.Sections[si].ID, pj.Sections[si].Position = 0, 0
+		for ri := range pj.Sections[si].Rows {
+			pj.Sections[si].Rows[ri].ID, pj.Sections[si].Rows[ri].Position = 0, 0
+			clear(pj.Sections[si].Rows[ri].Instructions)
+		}
+	}
+	return pj
+}
+
+func TestExportAllPatternsZipRoundTrips(t *testing.T) {
+	db := newTestDB(t)
+	owner := newTestUser(t, db, "a@b.com")
+	two := 2
+	for _, size := range []string{"child", "adult"} {
+		pattern := &Pattern{Name: "Hat", Description: "A warm hat", GaugeStitches: 14, GaugeRows: 16,
+			Meta: PatternMeta{Difficulty: DifficultyBeginner, HookSize: "5 mm", FinishedSize: size}}
+		sections := []PatternSection{{Name: "Crown", Notes: "Worked in the round", Rows: []Row{
+			{Type: "joined_round", RepeatCount: 1, ExpectedStitchCount: 6, Label: "Magic ring", Instructions: []RowInstruction{
+				{StitchAbbr: "sc", Count: 6, Into: "ring"},
+			}},
+			{Type: "joined_round", RepeatCount: 2, ExpectedStitchCount: 12, CheckPrevious: true, Instructions: []RowInstruction{
+				{IsGroup: true, GroupRepeat: 3, Children: []RowInstruction{
+					{StitchAbbr: "bobble", StitchName: "Bobble", Count: 1, Color: "#ff0000", Consumes: &two},
+					{Note: "place marker"},
+				}},
+				{StitchAbbr: "sc", Count: 1, ToEnd: true},
+			}},
+		}}}
+		if _, _, err := ImportPattern(db, owner.ID, pattern, sections); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var zipped bytes.Buffer
+	if err := ExportAllPatternsZip(db, owner.ID, &zipped); err != nil {
+		t.Fatal(err)
+	}
+	restorer := newTestUser(t, db, "c@d.com")
+	results, created, err := ImportAllPatternsZip(db, restorer.ID, zipped.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 1 {
+		t.Errorf("created %d stitches, want 1", created)
+	}
+
+	exported, err := ListPatternsByUser(db, owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(exported) {
+		t.Fatalf("restored %d files, want %d", len(results), len(exported))
+	}
+	files := make(map[string]bool)
+	for i, res := range results {
+		if res.Err != nil {
+			t.Fatalf("%s: %v", res.File, res.Err)
+		}
+		files[res.File] = true
+		want, wantSections, err := LoadPatternFull(db, exported[i].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, gotSections, err := LoadPatternFull(db, res.Pattern.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w, g := comparablePatternJSON(NewPatternJSON(want, wantSections)), comparablePatternJSON(NewPatternJSON(got, gotSections)); !reflect.DeepEqual(w, g) {
+			t.Errorf("%s restored as\n%+v\nwant\n%+v", res.File, g, w)
+		}
+	}
+	if !files["hat.json"] || !files["hat-2.json"] {
+		t.Errorf("zip files %v, want hat.json and hat-2.json", files)
+	}
+}
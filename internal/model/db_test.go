@@ -0,0 +1,71 @@
+package model
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stitchmap/stitchmap/internal/database"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestDB opens a fresh, fully migrated database in a temporary directory, with
+// the built-in stitches seeded. It is closed when the test ends.
+func newTestDB(t testing.TB) *sql.DB {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := SeedBuiltinStitches(db); err != nil {
+		t.Fatalf("seed stitches: %v", err)
+	}
+	return db
+}
+
+// newTestUser creates a user with a cheap password hash.
+func newTestUser(t testing.TB, db *sql.DB, email string) *User {
+	t.Helper()
+	if err := SetBcryptCost(bcrypt.MinCost); err != nil {
+		t.Fatal(err)
+	}
+	user, err := CreateUser(db, email, "password1")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// builtinStitchID returns the ID of a built-in stitch by abbreviation.
+func builtinStitchID(t testing.TB, db *sql.DB, abbr string) *int64 {
+	t.Helper()
+	var id int64
+	if err := db.QueryRow("SELECT id FROM stitches WHERE user_id IS NULL AND abbreviation = ?", abbr).Scan(&id); err != nil {
+		t.Fatalf("find stitch %s: %v", abbr, err)
+	}
+	return &id
+}
+
+// mustRow creates a plain one-repeat row with the given expected stitch count.
+func mustRow(t testing.TB, db *sql.DB, sectionID int64, expected int) *Row {
+	t.Helper()
+	row, err := CreateRow(db, sectionID, "", "row", expected, 0, false, 1, "", false, false)
+	if err != nil {
+		t.Fatalf("create row: %v", err)
+	}
+	return row
+}
+
+// mustInstruction adds a top-level instruction of count stitches of stitchID.
+func mustInstruction(t testing.TB, db *sql.DB, rowID int64, stitchID *int64, count int) *RowInstruction {
+	t.Helper()
+	ri, err := CreateInstruction(db, rowID, stitchID, count, "", "", "", nil)
+	if err != nil {
+		t.Fatalf("create instruction: %v", err)
+	}
+	return ri
+}
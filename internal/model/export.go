@@ -0,0 +1,227 @@
+package model
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// RenderPatternPDF lays the pattern out as a printable US Letter PDF: the name and
+// description, difficulty, size and gauge if set, the materials list, then each section
+// as a heading followed by one line per row in the same notation as RenderPatternSummary.
+func RenderPatternPDF(pattern *Pattern, sections []PatternSection) ([]byte, error) {
+	doc := gofpdf.New("P", "pt", "Letter", "")
+	doc.SetMargins(54, 54, 54)
+	doc.SetAutoPageBreak(true, 54)
+	doc.SetTitle(pattern.Name, true)
+	doc.SetCreator("StitchMap", true)
+	doc.AddPage()
+
+	// The core Helvetica fonts are single-byte; tr maps UTF-8 to their cp1252 encoding.
+	tr := doc.UnicodeTranslatorFromDescriptor("")
+	heading := func(text string, size float64) {
+		doc.SetFont("Helvetica", "B", size)
+		doc.MultiCell(0, size*1.3, tr(text), "", "L", false)
+	}
+	paragraph := func(text string, size, indent float64) {
+		doc.SetFont("Helvetica", "", size)
+		doc.SetX(doc.GetX() + indent)
+		doc.MultiCell(0, size*1.3, tr(text), "", "L", false)
+	}
+
+	heading(pattern.Name, 20)
+	if pattern.Description != "" {
+		doc.Ln(4)
+		paragraph(pattern.Description, 11, 0)
+	}
+	doc.Ln(4)
+	if pattern.Meta.Difficulty != "" {
+		paragraph("Difficulty: "+DifficultyLabel(pattern.Meta.Difficulty), 10, 0)
+	}
+	if pattern.Meta.FinishedSize != "" {
+		paragraph("Finished size: "+pattern.Meta.FinishedSize, 10, 0)
+	}
+	if pattern.GaugeStitches > 0 {
+		paragraph("Gauge: "+GaugeText(pattern), 10, 0)
+	}
+
+	if materials := patternMaterials(pattern, sections); len(materials) > 0 {
+		doc.Ln(6)
+		heading("Materials", 14)
+		for _, line := range materials {
+			paragraph("- "+line, 11, 12)
+		}
+	}
+
+	for _, section := range sections {
+		doc.Ln(6)
+		heading(section.Name, 14)
+		for _, line := range sectionNotationLines(section) {
+			paragraph(line, 11, 12)
+		}
+		if section.Notes != "" {
+			doc.Ln(2)
+			paragraph("Note: "+section.Notes, 10, 12)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Output(&buf); err != nil {
+		return nil, fmt.Errorf("render pdf: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// patternMaterials lists what to gather before starting: the hook and yarn weight if
+// set, then every yarn color the instructions use, in the order they first appear.
+func patternMaterials(pattern *Pattern, sections []PatternSection) []string {
+	var lines []string
+	if pattern.Meta.HookSize != "" {
+		lines = append(lines, "Hook: "+pattern.Meta.HookSize)
+	}
+	if pattern.Meta.YarnWeight != "" {
+		lines = append(lines, "Yarn: "+pattern.Meta.YarnWeight)
+	}
+
+	seen := make(map[string]bool)
+	var colors []string
+	var walk func([]RowInstruction)
+	walk = func(instructions []RowInstruction) {
+		for _, ri := range instructions {
+			if ri.Color != "" && !seen[strings.ToLower(ri.Color)] {
+				seen[strings.ToLower(ri.Color)] = true
+				colors = append(colors, ri.Color)
+			}
+			walk(ri.Children)
+		}
+	}
+	for _, section := range sections {
+		for _, row := range section.Rows {
+			walk(row.Instructions)
+		}
+	}
+	if len(colors) > 0 {
+		lines = append(lines, "Colors: "+strings.Join(colors, ", "))
+	}
+	return lines
+}
+
+// ReadViewRow is one row of the read-only pattern view, already labeled and
+// written out in notation.
+type ReadViewRow struct {
+	Label        string
+	Instructions string
+	StitchCount  int
+	Notes        string
+}
+
+// ReadViewRows labels a section's rows the way work mode does and renders their
+// instructions in the same notation as the text summary, with stitches shown by
+// chart symbol where they have one if symbols is set.
+func ReadViewRows(section PatternSection, symbols bool) []ReadViewRow {
+	out := make([]ReadViewRow, 0, len(section.Rows))
+	for _, row := range section.Rows {
+		out = append(out, ReadViewRow{
+			Label:        computeRowLabel(section.Rows, row.ID),
+			Instructions: renderInstructions(row.Instructions, symbols),
+			StitchCount:  row.ExpectedStitchCount,
+			Notes:        row.Notes,
+		})
+	}
+	return out
+}
+
+// ExportSessionCSV lists every row the session has reached, in pattern order, with
+// its timing: section, row label, repeat (1-based), status, completed-at and working
+// seconds. Rows without timing data (worked before timings were recorded, or skipped
+// over with undo) still appear with the timing columns left blank.
+func ExportSessionCSV(db *sql.DB, sessionID int64) ([]byte, error) {
+	session, err := FindSessionByID(db, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	_, sections, err := LoadPatternFull(db, session.PatternID)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := GetProgress(db, sessionID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get progress: %w", err)
+	}
+
+	type timingKey struct {
+		rowID  int64
+		repeat int
+	}
+	type timing struct {
+		seconds     int64
+		completedAt string
+	}
+	timings := make(map[timingKey]timing)
+	rows, err := db.Query(`
+		SELECT row_id, row_repeat_index, active_seconds, COALESCE(completed_at, '')
+		FROM row_timings WHERE session_id = ?
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("list row timings: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var k timingKey
+		var t timing
+		if err := rows.Scan(&k.rowID, &k.repeat, &t.seconds, &t.completedAt); err != nil {
+			return nil, fmt.Errorf("scan row timing: %w", err)
+		}
+		timings[k] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"section", "row", "repeat", "status", "completed_at", "active_seconds"})
+
+	// Everything before the current position has been worked. A completed session
+	// (or one with no progress to compare against) has reached every row.
+	reachedAll := session.CompletedAt != nil || progress == nil
+	for _, section := range sections {
+		for _, row := range section.Rows {
+			if rowStitchCount(&row) == 0 {
+				continue
+			}
+			for rep := 0; rep < row.RepeatCount; rep++ {
+				status := "done"
+				if !reachedAll && row.ID == progress.RowID && rep == progress.RowRepeatIndex {
+					status = "in progress"
+				}
+
+				record := []string{
+					section.Name,
+					computeCurrentRowLabel(section.Rows, row.ID, rep),
+					fmt.Sprintf("%d/%d", rep+1, row.RepeatCount),
+					status, "", "",
+				}
+				if t, ok := timings[timingKey{row.ID, rep}]; ok {
+					record[4] = t.completedAt
+					record[5] = strconv.FormatInt(t.seconds, 10)
+				}
+				w.Write(record)
+
+				if status == "in progress" {
+					w.Flush()
+					return buf.Bytes(), w.Error()
+				}
+			}
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
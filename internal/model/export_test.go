@@ -0,0 +1,44 @@
+package model
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPatternMaterials(t *testing.T) {
+	pattern := &Pattern{Meta: PatternMeta{HookSize: "4 mm", YarnWeight: "worsted"}}
+	sections := []PatternSection{
+		{Rows: []Row{{Instructions: []RowInstruction{
+			{Color: "Cream"},
+			{IsGroup: true, Color: "Rust", Children: []RowInstruction{{Color: "Sage"}, {}}},
+		}}}},
+		{Rows: []Row{{Instructions: []RowInstruction{{Color: "cream"}, {Color: "Rust"}}}}},
+	}
+	want := []string{"Hook: 4 mm", "Yarn: worsted", "Colors: Cream, Rust, Sage"}
+	if got := patternMaterials(pattern, sections); !reflect.DeepEqual(got, want) {
+		t.Errorf("patternMaterials = %q, want %q", got, want)
+	}
+	if got := patternMaterials(&Pattern{}, nil); got != nil {
+		t.Errorf("patternMaterials of a bare pattern = %q, want none", got)
+	}
+}
+
+func TestRenderPatternPDF(t *testing.T) {
+	pattern := &Pattern{
+		Name:        "Café Hat ×2",
+		Description: "A slouchy hat – worked in the round.",
+		Meta:        PatternMeta{HookSize: "5 mm", Difficulty: "easy"},
+	}
+	sections := []PatternSection{{Name: "Crown", Notes: "Use a marker.", Rows: []Row{
+		{RepeatCount: 1, Type: "joined_round", ExpectedStitchCount: 6,
+			Instructions: []RowInstruction{{Count: 6, StitchAbbr: "sc", Color: "Navy"}}},
+	}}}
+	data, err := RenderPatternPDF(pattern, sections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) || !bytes.Contains(data, []byte("%%EOF")) {
+		t.Fatalf("not a complete PDF: %.40q...", data)
+	}
+}
@@ -0,0 +1,62 @@
+package model
+
+import "fmt"
+
+// GaugeSwatchInches is the swatch width/height that gauge values are measured over.
+const GaugeSwatchInches = 4
+
+// GaugeText describes a pattern's gauge, e.g. "18 sts × 20 rows = 4 in".
+func GaugeText(p *Pattern) string {
+	s := fmt.Sprintf("%d sts", p.GaugeStitches)
+	if p.GaugeRows > 0 {
+		s += fmt.Sprintf(" × %d rows", p.GaugeRows)
+	}
+	return s + fmt.Sprintf(" = %d in", GaugeSwatchInches)
+}
+
+// RowsToInches converts a number of worked rows to an approximate length using the
+// row gauge. It returns 0 when the row gauge is unset.
+func RowsToInches(rows, gaugeRows int) float64 {
+	if gaugeRows <= 0 || rows <= 0 {
+		return 0
+	}
+	return float64(rows) * GaugeSwatchInches / float64(gaugeRows)
+}
+
+// ResizeStitchCount scales a stitch count from one gauge to another, rounding to the
+// nearest whole stitch. Counts never drop below 1, and an unset gauge leaves the count unchanged.
+func ResizeStitchCount(original, originalGauge, targetGauge int) int {
+	if originalGauge <= 0 || targetGauge <= 0 {
+		return original
+	}
+	resized := (original*targetGauge*2 + originalGauge) / (originalGauge * 2)
+	if resized < 1 && original > 0 {
+		resized = 1
+	}
+	return resized
+}
+
+// ResizePreviewRow is one row of a gauge resize preview.
+type ResizePreviewRow struct {
+	SectionName string
+	RowLabel    string
+	Original    int
+	Resized     int
+}
+
+// ResizePreview recomputes every row's expected stitch count for a target gauge.
+// It works off already-loaded sections and never touches the database.
+func ResizePreview(sections []PatternSection, originalGauge, targetGauge int) []ResizePreviewRow {
+	var out []ResizePreviewRow
+	for _, section := range sections {
+		for _, row := range section.Rows {
+			out = append(out, ResizePreviewRow{
+				SectionName: section.Name,
+				RowLabel:    computeRowLabel(section.Rows, row.ID),
+				Original:    row.ExpectedStitchCount,
+				Resized:     ResizeStitchCount(row.ExpectedStitchCount, originalGauge, targetGauge),
+			})
+		}
+	}
+	return out
+}
@@ -0,0 +1,204 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// InstructionTemplate is a saved instruction tree that can be inserted into any row.
+// Instructions have no IDs or positions; stitches are matched by abbreviation when
+// the template is inserted, so it works across patterns.
+type InstructionTemplate struct {
+	ID           int64
+	UserID       int64
+	Name         string
+	Instructions []RowInstruction // StitchAbbr set, StitchID nil
+	CreatedAt    time.Time
+}
+
+// MaxTemplateNameLength caps instruction template names.
+const MaxTemplateNameLength = 60
+
+// ErrTemplateNameTaken is returned when the user already has a template with that name.
+var ErrTemplateNameTaken = errors.New("an instruction template with that name already exists")
+
+// templateInstruction is the stored form of one instruction in a template.
+type templateInstruction struct {
+	Abbr        string                `json:"abbr,omitempty"`
+	Count       int                   `json:"count"`
+	Into        string                `json:"into,omitempty"`
+	Note        string                `json:"note,omitempty"`
+	Color       string                `json:"color,omitempty"`
+	Consumes    *int                  `json:"consumes,omitempty"`
+	IsGroup     bool                  `json:"is_group,omitempty"`
+	GroupRepeat int                   `json:"group_repeat,omitempty"`
+	Children    []templateInstruction `json:"children,omitempty"`
+}
+
+func toTemplateInstructions(instructions []RowInstruction) []templateInstruction {
+	out := make([]templateInstruction, 0, len(instructions))
+	for _, ri := range instructions {
+		out = append(out, templateInstruction{
+			Abbr:        ri.StitchAbbr,
+			Count:       ri.Count,
+			Into:        ri.Into,
+			Note:        ri.Note,
+			Color:       ri.Color,
+			Consumes:    ri.Consumes,
+			IsGroup:     ri.IsGroup,
+			GroupRepeat: ri.GroupRepeat,
+			Children:    toTemplateInstructions(ri.Children),
+		})
+	}
+	return out
+}
+
+func fromTemplateInstructions(stored []templateInstruction) []RowInstruction {
+	out := make([]RowInstruction, 0, len(stored))
+	for i, ti := range stored {
+		out = append(out, RowInstruction{
+			Position:    i + 1,
+			StitchAbbr:  ti.Abbr,
+			Count:       ti.Count,
+			Into:        ti.Into,
+			Note:        ti.Note,
+			Color:       ti.Color,
+			Consumes:    ti.Consumes,
+			IsGroup:     ti.IsGroup,
+			GroupRepeat: max(ti.GroupRepeat, 1),
+			Children:    fromTemplateInstructions(ti.Children),
+		})
+	}
+	return out
+}
+
+// SaveInstructionTemplate stores subtree under name for the user. subtree is usually
+// a single group with its children, as loaded by ListInstructionsForRow.
+func SaveInstructionTemplate(db *sql.DB, userID int64, name string, subtree []RowInstruction) (*InstructionTemplate, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > MaxTemplateNameLength {
+		return nil, fmt.Errorf("template name must be 1 to %d characters", MaxTemplateNameLength)
+	}
+
+	data, err := json.Marshal(toTemplateInstructions(subtree))
+	if err != nil {
+		return nil, fmt.Errorf("encode template: %w", err)
+	}
+
+	// The unique index on (user_id, name) rejects a duplicate, even one racing this insert.
+	result, err := db.Exec(
+		"INSERT INTO instruction_templates (user_id, name, data) VALUES (?, ?, ?)",
+		userID, name, string(data),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint") {
+			return nil, ErrTemplateNameTaken
+		}
+		return nil, fmt.Errorf("insert instruction template: %w", err)
+	}
+	id, _ := result.LastInsertId()
+	return &InstructionTemplate{
+		ID:           id,
+		UserID:       userID,
+		Name:         name,
+		Instructions: fromTemplateInstructions(toTemplateInstructions(subtree)),
+		CreatedAt:    time.Now().UTC(),
+	}, nil
+}
+
+// ListInstructionTemplates returns the user's templates by name.
+func ListInstructionTemplates(db *sql.DB, userID int64) ([]InstructionTemplate, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, name, data, created_at
+		FROM instruction_templates WHERE user_id = ?
+		ORDER BY name COLLATE NOCASE ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list instruction templates: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []InstructionTemplate
+	for rows.Next() {
+		t, err := scanInstructionTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *t)
+	}
+	return templates, rows.Err()
+}
+
+// FindInstructionTemplate fetches one of the user's templates.
+func FindInstructionTemplate(db *sql.DB, id, userID int64) (*InstructionTemplate, error) {
+	return scanInstructionTemplate(db.QueryRow(`
+		SELECT id, user_id, name, data, created_at
+		FROM instruction_templates WHERE id = ? AND user_id = ?
+	`, id, userID))
+}
+
+func scanInstructionTemplate(row interface{ Scan(...any) error }) (*InstructionTemplate, error) {
+	t := &InstructionTemplate{}
+	var data, createdAt string
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &data, &createdAt); err != nil {
+		return nil, err
+	}
+	var stored []templateInstruction
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return nil, fmt.Errorf("decode instruction template %d: %w", t.ID, err)
+	}
+	t.Instructions = fromTemplateInstructions(stored)
+	t.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	return t, nil
+}
+
+// DeleteInstructionTemplate removes one of the user's templates.
+func DeleteInstructionTemplate(db *sql.DB, id, userID int64) error {
+	if _, err := db.Exec("DELETE FROM instruction_templates WHERE id = ? AND user_id = ?", id, userID); err != nil {
+		return fmt.Errorf("delete instruction template: %w", err)
+	}
+	return nil
+}
+
+// InsertTemplateIntoRow appends a template's instructions to the end of a row in one
+// transaction, with fresh IDs and positions. Abbreviations are matched against the
+// template owner's stitches like a text import; unmatched ones become instructions
+// with no stitch and an UnknownStitchNote.
+func InsertTemplateIntoRow(db *sql.DB, rowID, templateID int64) error {
+	var userID int64
+	if err := db.QueryRow("SELECT user_id FROM instruction_templates WHERE id = ?", templateID).Scan(&userID); err != nil {
+		return fmt.Errorf("instruction template not found: %w", err)
+	}
+	tmpl, err := FindInstructionTemplate(db, templateID, userID)
+	if err != nil {
+		return err
+	}
+	byAbbr, err := stitchIDsByAbbr(db, userID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var maxPos sql.NullInt64
+	if err := tx.QueryRow(
+		"SELECT MAX(position) FROM row_instructions WHERE row_id = ? AND parent_id IS NULL", rowID,
+	).Scan(&maxPos); err != nil {
+		return fmt.Errorf("find last instruction: %w", err)
+	}
+
+	if err := insertInstructionTree(tx, rowID, nil, int(maxPos.Int64), tmpl.Instructions, byAbbr); err != nil {
+		return err
+	}
+
+	touchPatternUpdatedAtForRow(tx, rowID)
+	return tx.Commit()
+}
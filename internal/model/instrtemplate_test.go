@@ -0,0 +1,65 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInsertTemplateIntoRow(t *testing.T) {
+	db, row := testRowFor(t)
+	sc := builtinStitchID(t, db, "sc")
+	inc := builtinStitchID(t, db, "inc")
+	mustInstruction(t, db, row.ID, sc, 2)
+
+	var userID int64
+	if err := db.QueryRow(`
+		SELECT p.user_id FROM patterns p JOIN pattern_sections ps ON ps.pattern_id = p.id WHERE ps.id = ?
+	`, row.SectionID).Scan(&userID); err != nil {
+		t.Fatal(err)
+	}
+	subtree := []RowInstruction{{IsGroup: true, GroupRepeat: 6, Color: "Rust", Children: []RowInstruction{
+		{StitchAbbr: "sc", Count: 2},
+		{StitchAbbr: "inc", Count: 1, Note: "loosely"},
+		{StitchAbbr: "bobble", Count: 1},
+	}}}
+	tmpl, err := SaveInstructionTemplate(db, userID, "Increase round", subtree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveInstructionTemplate(db, userID, "Increase round", subtree); !errors.Is(err, ErrTemplateNameTaken) {
+		t.Fatalf("saving a second template of the same name: got %v, want %v", err, ErrTemplateNameTaken)
+	}
+
+	for range 2 {
+		if err := InsertTemplateIntoRow(db, row.ID, tmpl.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := ListInstructionsForRow(db, row.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("row has %d instructions, want the original and two copies", len(got))
+	}
+	for i, ri := range got {
+		if ri.Position != i+1 {
+			t.Errorf("instruction %d at position %d", i, ri.Position)
+		}
+	}
+	for _, group := range got[1:] {
+		if !group.IsGroup || group.GroupRepeat != 6 || group.Color != "Rust" || len(group.Children) != 3 {
+			t.Fatalf("inserted %+v, want a x6 Rust group of 3", group)
+		}
+		c := group.Children
+		if *c[0].StitchID != *sc || c[0].Count != 2 || *c[1].StitchID != *inc || c[1].Note != "loosely" {
+			t.Errorf("children %+v, want sc 2 and inc (loosely)", c)
+		}
+		if c[2].StitchID != nil || c[2].Note != UnknownStitchNote+"bobble" {
+			t.Errorf("unknown stitch inserted as %+v", c[2])
+		}
+		if c[0].Position != 1 || c[1].Position != 2 || c[2].Position != 3 {
+			t.Errorf("child positions %d, %d, %d", c[0].Position, c[1].Position, c[2].Position)
+		}
+	}
+}
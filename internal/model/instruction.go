@@ -2,41 +2,81 @@ package model
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 )
 
 // RowInstruction is a single instruction step within a row/round.
 type RowInstruction struct {
-	ID          int64
-	RowID       int64
-	Position    int
-	StitchID    *int64  // nullable — nil for non-stitch instructions or group headers
-	StitchName  string  // populated via JOIN
-	StitchAbbr  string  // populated via JOIN
-	Count       int
-	Into        string
-	IsGroup     bool
-	ParentID    *int64 // nullable — nil for top-level instructions
-	GroupRepeat int
-	Note        string
-	Children    []RowInstruction // populated for group headers
+	ID           int64
+	RowID        int64
+	Position     int
+	StitchID     *int64 // nullable — nil for non-stitch instructions or group headers
+	StitchName   string // populated via JOIN
+	StitchAbbr   string // populated via JOIN
+	StitchColor  string // the stitch's display color, populated via JOIN
+	StitchDesc   string // the stitch's description, populated via JOIN
+	StitchSymbol string // the stitch's chart symbol, populated via JOIN; "" if none
+	Count        int
+	Into         string
+	IsGroup      bool
+	ParentID     *int64 // nullable — nil for top-level instructions
+	GroupRepeat  int
+	Note         string
+	Color        string           // yarn color; "" inherits from the enclosing group
+	Consumes     *int             // stitches of the previous row worked into; nil means Count
+	ToEnd        bool             // Count is resolved from the previous row; see ResolveToEnd
+	Children     []RowInstruction // populated for group headers
 }
 
+// BaseStitches is how many stitches of the previous row one repeat of the instruction
+// works into: Consumes if set, otherwise one per stitch made. Groups sum their
+// children times the group repeat.
+func (ri RowInstruction) BaseStitches() int {
+	if ri.IsGroup {
+		n := 0
+		for _, c := range ri.Children {
+			n += c.BaseStitches()
+		}
+		return n * ri.GroupRepeat
+	}
+	if ri.Consumes != nil {
+		return *ri.Consumes
+	}
+	return ri.Count
+}
+
+// StitchLabel is how the instruction's stitch is shown: its chart symbol when
+// symbols is set and the stitch has one, else its abbreviation.
+func (ri RowInstruction) StitchLabel(symbols bool) string {
+	if symbols && ri.StitchSymbol != "" {
+		return ri.StitchSymbol
+	}
+	return ri.StitchAbbr
+}
+
+// MaxInstructionColorLength caps the free-text yarn color on an instruction.
+const MaxInstructionColorLength = 40
+
 const instructionSelectCols = `
 	ri.id, ri.row_id, ri.position, ri.stitch_id,
-	COALESCE(s.name, ''), COALESCE(s.abbreviation, ''),
-	ri.count, ri."into", ri.is_group, ri.parent_id, ri.group_repeat, ri.note
+	COALESCE(s.name, ''), COALESCE(s.abbreviation, ''), COALESCE(s.color, ''), COALESCE(s.description, ''),
+	COALESCE(s.symbol, ''),
+	ri.count, ri."into", ri.is_group, ri.parent_id, ri.group_repeat, ri.note, ri.color, ri.consumes, ri.to_end
 `
 
 func scanInstruction(row interface{ Scan(...any) error }) (*RowInstruction, error) {
 	ri := &RowInstruction{}
 	var stitchID sql.NullInt64
 	var parentID sql.NullInt64
+	var consumes sql.NullInt64
 	var isGroup int
 	err := row.Scan(
 		&ri.ID, &ri.RowID, &ri.Position, &stitchID,
-		&ri.StitchName, &ri.StitchAbbr,
-		&ri.Count, &ri.Into, &isGroup, &parentID, &ri.GroupRepeat, &ri.Note,
+		&ri.StitchName, &ri.StitchAbbr, &ri.StitchColor, &ri.StitchDesc, &ri.StitchSymbol,
+		&ri.Count, &ri.Into, &isGroup, &parentID, &ri.GroupRepeat, &ri.Note, &ri.Color, &consumes, &ri.ToEnd,
 	)
 	if err != nil {
 		return nil, err
@@ -49,12 +89,16 @@ func scanInstruction(row interface{ Scan(...any) error }) (*RowInstruction, erro
 		v := parentID.Int64
 		ri.ParentID = &v
 	}
+	if consumes.Valid {
+		v := int(consumes.Int64)
+		ri.Consumes = &v
+	}
 	ri.IsGroup = isGroup != 0
 	return ri, nil
 }
 
 // ListTopLevelInstructions returns top-level instructions (parent_id IS NULL) for a row.
-func ListTopLevelInstructions(db *sql.DB, rowID int64) ([]RowInstruction, error) {
+func ListTopLevelInstructions(db readQueryer, rowID int64) ([]RowInstruction, error) {
 	rows, err := db.Query(`
 		SELECT `+instructionSelectCols+`
 		FROM row_instructions ri
@@ -79,7 +123,7 @@ func ListTopLevelInstructions(db *sql.DB, rowID int64) ([]RowInstruction, error)
 }
 
 // ListChildInstructions returns child instructions for a group.
-func ListChildInstructions(db *sql.DB, parentID int64) ([]RowInstruction, error) {
+func ListChildInstructions(db readQueryer, parentID int64) ([]RowInstruction, error) {
 	rows, err := db.Query(`
 		SELECT `+instructionSelectCols+`
 		FROM row_instructions ri
@@ -103,8 +147,32 @@ func ListChildInstructions(db *sql.DB, parentID int64) ([]RowInstruction, error)
 	return result, rows.Err()
 }
 
-// ListInstructionsForRow returns all instructions for a row, with children nested inside groups.
-func ListInstructionsForRow(db *sql.DB, rowID int64) ([]RowInstruction, error) {
+// ListInstructionsForRow returns all instructions for a row, with children nested
+// inside groups and any "to end" instruction's count resolved against the previous
+// row (see ResolveToEnd).
+func ListInstructionsForRow(db readQueryer, rowID int64) ([]RowInstruction, error) {
+	instructions, err := listRowInstructionTree(db, rowID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasToEnd(instructions) {
+		return instructions, nil
+	}
+	prevCount, err := PreviousRowStitchCount(db, rowID)
+	if err != nil {
+		return nil, err
+	}
+	var expected int
+	if err := db.QueryRow("SELECT expected_stitch_count FROM rows WHERE id = ?", rowID).Scan(&expected); err != nil {
+		return nil, fmt.Errorf("find row: %w", err)
+	}
+	ResolveToEnd(instructions, prevCount, expected)
+	return instructions, nil
+}
+
+// listRowInstructionTree loads a row's instruction tree as stored, without resolving
+// "to end" counts.
+func listRowInstructionTree(db readQueryer, rowID int64) ([]RowInstruction, error) {
 	top, err := ListTopLevelInstructions(db, rowID)
 	if err != nil {
 		return nil, err
@@ -121,6 +189,47 @@ func ListInstructionsForRow(db *sql.DB, rowID int64) ([]RowInstruction, error) {
 	return top, nil
 }
 
+// ResolveToEnd sets the count of a row's "to end" instruction ("sc to end") to the
+// stitches it has left to work into: prevCount, the previous row's stitches, less
+// what every other instruction in the row works into (BaseStitches, so a group
+// takes its children times its repeat). When prevCount is 0, as on the first row of
+// a section, it falls back to expected, the row's expected stitch count, less the
+// stitches the other instructions make. With neither, the stored count is kept.
+//
+// Only a top-level stitch instruction can be worked to end, and only one per row:
+// a group's repeat already says how often it is worked, and an instruction inside
+// one would have no single "end" to run to. The count never goes below 0.
+func ResolveToEnd(instructions []RowInstruction, prevCount, expected int) {
+	idx := -1
+	used, made := 0, 0
+	for i, ri := range instructions {
+		if ri.ToEnd && !ri.IsGroup && idx < 0 {
+			idx = i
+			continue
+		}
+		used += ri.BaseStitches()
+		made += instructionStitchPos(ri)
+	}
+	if idx < 0 {
+		return
+	}
+	switch {
+	case prevCount > 0:
+		instructions[idx].Count = max(prevCount-used, 0)
+	case expected > 0:
+		instructions[idx].Count = max(expected-made, 0)
+	}
+}
+
+func hasToEnd(instructions []RowInstruction) bool {
+	for _, ri := range instructions {
+		if ri.ToEnd {
+			return true
+		}
+	}
+	return false
+}
+
 // FindInstructionByID fetches a single instruction by ID.
 func FindInstructionByID(db *sql.DB, id int64) (*RowInstruction, error) {
 	row := db.QueryRow(`
@@ -133,31 +242,138 @@ func FindInstructionByID(db *sql.DB, id int64) (*RowInstruction, error) {
 }
 
 // CreateInstruction inserts a new top-level instruction in a row.
-func CreateInstruction(db *sql.DB, rowID int64, stitchID *int64, count int, into string, note string) (*RowInstruction, error) {
-	return insertInstruction(db, rowID, nil, stitchID, count, into, false, 1, note)
+func CreateInstruction(db *sql.DB, rowID int64, stitchID *int64, count int, into, note, color string, consumes *int) (*RowInstruction, error) {
+	return insertInstruction(db, rowID, nil, stitchID, count, into, false, 1, note, color, consumes, false)
+}
+
+// CreateToEndInstruction inserts a new top-level stitch worked "to end" ("sc to end"),
+// clearing the mark from any other instruction in the row in the same transaction;
+// that one keeps the count it last resolved to. The new one's count is resolved when
+// the row is loaded (see ResolveToEnd). It returns ErrToEndNoStitch if stitchID is nil.
+func CreateToEndInstruction(db *sql.DB, rowID int64, stitchID *int64, into, note, color string) (*RowInstruction, error) {
+	return insertInstruction(db, rowID, nil, stitchID, 1, into, false, 1, note, color, nil, true)
 }
 
 // CreateGroupInstruction inserts a new group header (is_group=true) in a row.
-func CreateGroupInstruction(db *sql.DB, rowID int64, groupRepeat int, note string) (*RowInstruction, error) {
-	return insertInstruction(db, rowID, nil, nil, 1, "", true, groupRepeat, note)
+func CreateGroupInstruction(db *sql.DB, rowID int64, groupRepeat int, note, color string) (*RowInstruction, error) {
+	return insertInstruction(db, rowID, nil, nil, 1, "", true, groupRepeat, note, color, nil, false)
 }
 
-// CreateChildInstruction inserts a child instruction inside a group.
-func CreateChildInstruction(db *sql.DB, parentID int64, stitchID *int64, count int, into string, note string) (*RowInstruction, error) {
+// CreateChildInstruction inserts a child instruction inside a group. It returns
+// ErrParentNotGroup if parentID is an ordinary instruction.
+func CreateChildInstruction(db *sql.DB, parentID int64, stitchID *int64, count int, into, note, color string, consumes *int) (*RowInstruction, error) {
 	parent, err := FindInstructionByID(db, parentID)
 	if err != nil {
 		return nil, fmt.Errorf("parent instruction not found: %w", err)
 	}
-	return insertInstruction(db, parent.RowID, &parentID, stitchID, count, into, false, 1, note)
+	return insertInstruction(db, parent.RowID, &parentID, stitchID, count, into, false, 1, note, color, consumes, false)
 }
 
-func insertInstruction(db *sql.DB, rowID int64, parentID *int64, stitchID *int64, count int, into string, isGroup bool, groupRepeat int, note string) (*RowInstruction, error) {
+// CheckInstructionCounts returns ErrCountTooLarge if an instruction's count,
+// group repeat or "works into" count is over its limit.
+func CheckInstructionCounts(count, groupRepeat int, consumes *int) error {
+	if count > MaxInstructionCount || groupRepeat > MaxGroupRepeat ||
+		(consumes != nil && *consumes > MaxInstructionCount) {
+		return ErrCountTooLarge
+	}
+	return nil
+}
+
+// Errors for instruction trees work mode can't follow. Groups are one level deep:
+// they sit at the top of a row, and everything inside one is a stitch or a note.
+var (
+	ErrParentNotGroup = errors.New("instructions can only be nested inside a group")
+	ErrNestedGroup    = errors.New("groups can't be nested inside other groups")
+	ErrEmptyChild     = errors.New("an instruction inside a group needs a stitch or a note")
+	ErrToEndInGroup   = errors.New("only a stitch at the top of a row can be worked to end")
+	ErrToEndNoStitch  = errors.New("an instruction worked to end needs a stitch")
+)
+
+// checkToEnd returns the error for marking an instruction "to end" that can't be,
+// or nil if it can (see ResolveToEnd).
+func checkToEnd(parentID *int64, isGroup bool, stitchID *int64) error {
+	if isGroup || parentID != nil {
+		return ErrToEndInGroup
+	}
+	if stitchID == nil {
+		return ErrToEndNoStitch
+	}
+	return nil
+}
+
+// clearOtherToEnd drops the "to end" mark from every instruction in a row but id,
+// since a row has at most one.
+func clearOtherToEnd(tx *sql.Tx, rowID, id int64) error {
+	return freezeToEnd(tx, rowID, func(other int64) bool { return other != id })
+}
+
+// freezeToEnd drops the "to end" mark from the row's instructions that match. The
+// stored count of a "to end" instruction is only a placeholder, so each one's count
+// is first fixed at what it resolves to now: the row keeps working the same stitches.
+func freezeToEnd(tx *sql.Tx, rowID int64, match func(id int64) bool) error {
+	instructions, err := ListInstructionsForRow(tx, rowID)
+	if err != nil {
+		return err
+	}
+	for _, ri := range instructions {
+		if !ri.ToEnd || !match(ri.ID) {
+			continue
+		}
+		if _, err := tx.Exec("UPDATE row_instructions SET count = ?, to_end = 0 WHERE id = ?", ri.Count, ri.ID); err != nil {
+			return fmt.Errorf("clear to end: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkInstructionPlacement enforces the tree shape for an instruction about to be
+// placed under parent, which is nil for a top-level instruction.
+func checkInstructionPlacement(parent *RowInstruction, isGroup bool, stitchID *int64, note string) error {
+	if parent == nil {
+		return nil
+	}
+	if !parent.IsGroup {
+		return ErrParentNotGroup
+	}
+	if isGroup {
+		return ErrNestedGroup
+	}
+	if stitchID == nil && strings.TrimSpace(note) == "" {
+		return ErrEmptyChild
+	}
+	return nil
+}
+
+func insertInstruction(db *sql.DB, rowID int64, parentID *int64, stitchID *int64, count int, into string, isGroup bool, groupRepeat int, note, color string, consumes *int, toEnd bool) (*RowInstruction, error) {
+	if err := CheckInstructionCounts(count, groupRepeat, consumes); err != nil {
+		return nil, err
+	}
+	if toEnd {
+		if err := checkToEnd(parentID, isGroup, stitchID); err != nil {
+			return nil, err
+		}
+		consumes = nil
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
+	var parent *RowInstruction
+	if parentID != nil {
+		parent = &RowInstruction{ID: *parentID}
+		err := tx.QueryRow("SELECT row_id, is_group FROM row_instructions WHERE id = ?", *parentID).
+			Scan(&parent.RowID, &parent.IsGroup)
+		if err != nil || parent.RowID != rowID {
+			return nil, ErrParentNotGroup
+		}
+	}
+	if err := checkInstructionPlacement(parent, isGroup, stitchID, note); err != nil {
+		return nil, err
+	}
+
 	// Get next position within (row_id, parent_id) scope.
 	var maxPos sql.NullInt64
 	if parentID == nil {
@@ -181,13 +397,20 @@ func insertInstruction(db *sql.DB, rowID int64, parentID *int64, stitchID *int64
 		isGroupInt = 1
 	}
 
+	// Clear any other "to end" first, while its count still resolves as it did.
+	if toEnd {
+		if err := clearOtherToEnd(tx, rowID, 0); err != nil {
+			return nil, err
+		}
+	}
 	result, err := tx.Exec(`
-		INSERT INTO row_instructions (row_id, position, stitch_id, count, "into", is_group, parent_id, group_repeat, note)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, rowID, nextPos, stitchID, count, into, isGroupInt, parentID, groupRepeat, note)
+		INSERT INTO row_instructions (row_id, position, stitch_id, count, "into", is_group, parent_id, group_repeat, note, color, consumes, to_end)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rowID, nextPos, stitchID, count, into, isGroupInt, parentID, groupRepeat, note, color, consumes, toEnd)
 	if err != nil {
 		return nil, fmt.Errorf("insert instruction: %w", err)
 	}
+	id, _ := result.LastInsertId()
 
 	// Touch parent pattern.
 	touchPatternUpdatedAtForRow(tx, rowID)
@@ -196,7 +419,6 @@ func insertInstruction(db *sql.DB, rowID int64, parentID *int64, stitchID *int64
 		return nil, err
 	}
 
-	id, _ := result.LastInsertId()
 	ri := &RowInstruction{
 		ID:          id,
 		RowID:       rowID,
@@ -208,12 +430,62 @@ func insertInstruction(db *sql.DB, rowID int64, parentID *int64, stitchID *int64
 		ParentID:    parentID,
 		GroupRepeat: groupRepeat,
 		Note:        note,
+		Color:       color,
+		Consumes:    consumes,
+		ToEnd:       toEnd,
 	}
 	return ri, nil
 }
 
-// UpdateInstruction updates a non-group instruction's fields.
-func UpdateInstruction(db *sql.DB, id int64, stitchID *int64, count int, into, note string) error {
+// insertInstructionTree inserts copies of an instruction tree under rowID, and under
+// parentID for a group's children, with fresh IDs. Top-level positions follow afterPos
+// and children are numbered from 1. If byAbbr is set, each stitch is looked up by its
+// StitchAbbr, as for a text import, and one that doesn't match keeps its abbreviation
+// in an UnknownStitchNote; otherwise StitchID is used as is. It is the one place that
+// copies, backup imports, text imports and templates write instruction trees.
+func insertInstructionTree(tx execer, rowID int64, parentID *int64, afterPos int, instructions []RowInstruction, byAbbr map[string]int64) error {
+	for i, ri := range instructions {
+		stitchID, note := ri.StitchID, ri.Note
+		if byAbbr != nil {
+			stitchID = nil
+			abbr := strings.TrimSpace(ri.StitchAbbr)
+			if id, ok := byAbbr[strings.ToLower(abbr)]; ok && !ri.IsGroup {
+				stitchID = &id
+			} else if abbr != "" && !ri.IsGroup {
+				note = strings.TrimSpace(UnknownStitchNote + abbr + " " + note)
+			}
+		}
+		groupRepeat := 1
+		if ri.IsGroup {
+			stitchID = nil
+			groupRepeat = max(ri.GroupRepeat, 1)
+		}
+		result, err := tx.Exec(`
+			INSERT INTO row_instructions (row_id, position, stitch_id, count, "into", is_group, parent_id, group_repeat, note, color, consumes, to_end)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, rowID, afterPos+i+1, stitchID, ri.Count, ri.Into, ri.IsGroup, parentID, groupRepeat, note, ri.Color, ri.Consumes, ri.ToEnd)
+		if err != nil {
+			return fmt.Errorf("insert instruction: %w", err)
+		}
+		if ri.IsGroup {
+			id, _ := result.LastInsertId()
+			if err := insertInstructionTree(tx, rowID, &id, 0, ri.Children, byAbbr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// UpdateInstruction updates a non-group instruction's fields. Marking it "to end"
+// drops its "works into" count, which the resolved count replaces, and clears the
+// mark from any other instruction in the row; it returns ErrToEndInGroup for an
+// instruction inside a group and ErrToEndNoStitch for one with no stitch.
+func UpdateInstruction(db *sql.DB, id int64, stitchID *int64, count int, into, note, color string, consumes *int, toEnd bool) error {
+	if err := CheckInstructionCounts(count, 1, consumes); err != nil {
+		return err
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		return err
@@ -221,14 +493,29 @@ func UpdateInstruction(db *sql.DB, id int64, stitchID *int64, count int, into, n
 	defer tx.Rollback()
 
 	var rowID int64
-	if err := tx.QueryRow("SELECT row_id FROM row_instructions WHERE id = ?", id).Scan(&rowID); err != nil {
+	var parentID sql.NullInt64
+	var isGroup bool
+	if err := tx.QueryRow("SELECT row_id, parent_id, is_group FROM row_instructions WHERE id = ?", id).Scan(&rowID, &parentID, &isGroup); err != nil {
 		return fmt.Errorf("instruction not found: %w", err)
 	}
+	if toEnd {
+		var parent *int64
+		if parentID.Valid {
+			parent = &parentID.Int64
+		}
+		if err := checkToEnd(parent, isGroup, stitchID); err != nil {
+			return err
+		}
+		consumes = nil
+		if err := clearOtherToEnd(tx, rowID, id); err != nil {
+			return err
+		}
+	}
 
 	if _, err := tx.Exec(`
-		UPDATE row_instructions SET stitch_id = ?, count = ?, "into" = ?, note = ?
+		UPDATE row_instructions SET stitch_id = ?, count = ?, "into" = ?, note = ?, color = ?, consumes = ?, to_end = ?
 		WHERE id = ?
-	`, stitchID, count, into, note, id); err != nil {
+	`, stitchID, count, into, note, color, consumes, toEnd, id); err != nil {
 		return fmt.Errorf("update instruction: %w", err)
 	}
 
@@ -236,8 +523,12 @@ func UpdateInstruction(db *sql.DB, id int64, stitchID *int64, count int, into, n
 	return tx.Commit()
 }
 
-// UpdateGroupInstruction updates a group header's repeat and note fields.
-func UpdateGroupInstruction(db *sql.DB, id int64, groupRepeat int, note string) error {
+// UpdateGroupInstruction updates a group header's repeat, note and color fields.
+func UpdateGroupInstruction(db *sql.DB, id int64, groupRepeat int, note, color string) error {
+	if err := CheckInstructionCounts(1, groupRepeat, nil); err != nil {
+		return err
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		return err
@@ -250,9 +541,9 @@ func UpdateGroupInstruction(db *sql.DB, id int64, groupRepeat int, note string)
 	}
 
 	if _, err := tx.Exec(`
-		UPDATE row_instructions SET group_repeat = ?, note = ?
+		UPDATE row_instructions SET group_repeat = ?, note = ?, color = ?
 		WHERE id = ? AND is_group = 1
-	`, groupRepeat, note, id); err != nil {
+	`, groupRepeat, note, color, id); err != nil {
 		return fmt.Errorf("update group instruction: %w", err)
 	}
 
@@ -298,6 +589,53 @@ func DeleteInstruction(db *sql.DB, id int64) error {
 	return tx.Commit()
 }
 
+// DeleteAllInstructionsForRow removes every instruction in a row, groups included.
+func DeleteAllInstructionsForRow(db *sql.DB, rowID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM row_instructions WHERE row_id = ? AND parent_id IS NOT NULL", rowID); err != nil {
+		return fmt.Errorf("delete group children: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM row_instructions WHERE row_id = ?", rowID); err != nil {
+		return fmt.Errorf("delete instructions: %w", err)
+	}
+
+	touchPatternUpdatedAtForRow(tx, rowID)
+	return tx.Commit()
+}
+
+// DeleteGroupChildren empties a group, leaving the group itself (with its repeat
+// and note) in place.
+func DeleteGroupChildren(db *sql.DB, groupID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var rowID int64
+	var isGroup bool
+	if err := tx.QueryRow(
+		"SELECT row_id, is_group FROM row_instructions WHERE id = ?", groupID,
+	).Scan(&rowID, &isGroup); err != nil {
+		return fmt.Errorf("instruction not found: %w", err)
+	}
+	if !isGroup {
+		return fmt.Errorf("instruction %d is not a group", groupID)
+	}
+
+	if _, err := tx.Exec("DELETE FROM row_instructions WHERE parent_id = ?", groupID); err != nil {
+		return fmt.Errorf("delete group children: %w", err)
+	}
+
+	touchPatternUpdatedAtForRow(tx, rowID)
+	return tx.Commit()
+}
+
 // MoveInstructionUp moves an instruction one position up among its siblings.
 func MoveInstructionUp(db *sql.DB, id int64) error {
 	return swapInstructionPosition(db, id, -1)
@@ -348,7 +686,7 @@ func swapInstructionPosition(db *sql.DB, id int64, delta int) error {
 	}
 
 	// Swap via temp position.
-	if _, err := tx.Exec("UPDATE row_instructions SET position = -1 WHERE id = ?", id); err != nil {
+	if _, err := tx.Exec("UPDATE row_instructions SET position = -id WHERE id = ?", id); err != nil {
 		return err
 	}
 	if _, err := tx.Exec("UPDATE row_instructions SET position = ? WHERE id = ?", position, otherID); err != nil {
@@ -362,6 +700,335 @@ func swapInstructionPosition(db *sql.DB, id int64, delta int) error {
 	return tx.Commit()
 }
 
+// ReorderInstructions rewrites positions for one sibling scope of a row: the top-level
+// instructions when parentID is nil, otherwise the children of that group.
+// orderedIDs must contain every instruction in that scope exactly once, so
+// instructions can't be moved into or out of a group this way.
+func ReorderInstructions(db *sql.DB, rowID int64, parentID *int64, orderedIDs []int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var parent int64
+	if parentID != nil {
+		parent = *parentID
+	}
+	if err := reorderPositions(tx, "row_instructions", "row_id = ? AND COALESCE(parent_id, 0) = ?", []any{rowID, parent}, orderedIDs); err != nil {
+		return err
+	}
+
+	touchPatternUpdatedAtForRow(tx, rowID)
+	return tx.Commit()
+}
+
+// ErrNotInGroup is returned by MoveInstructionOutOfGroup for a top-level instruction.
+var ErrNotInGroup = errors.New("instruction is not inside a group")
+
+// MoveInstructionIntoGroup moves an instruction, with its stitch, count, note and
+// color, to the end of a group in the same row. The siblings it leaves close up
+// behind it. A "to end" instruction stops being one, its count fixed at what it
+// resolved to before the move. It returns ErrParentNotGroup if groupID isn't a group
+// of that row, and ErrNestedGroup or ErrEmptyChild if the instruction can't sit
+// inside a group.
+func MoveInstructionIntoGroup(db *sql.DB, instructionID, groupID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ri, err := findInstructionPlacement(tx, instructionID)
+	if err != nil {
+		return err
+	}
+	group, err := findInstructionPlacement(tx, groupID)
+	if err != nil || group.RowID != ri.RowID {
+		return ErrParentNotGroup
+	}
+	if err := checkInstructionPlacement(group, ri.IsGroup, ri.StitchID, ri.Note); err != nil {
+		return err
+	}
+	if ri.ParentID != nil && *ri.ParentID == groupID {
+		return nil
+	}
+
+	if err := freezeToEnd(tx, ri.RowID, func(id int64) bool { return id == instructionID }); err != nil {
+		return err
+	}
+	var maxPos sql.NullInt64
+	tx.QueryRow(
+		"SELECT MAX(position) FROM row_instructions WHERE row_id = ? AND parent_id = ?",
+		ri.RowID, groupID,
+	).Scan(&maxPos)
+	if _, err := tx.Exec(
+		"UPDATE row_instructions SET parent_id = ?, position = ? WHERE id = ?",
+		groupID, maxPos.Int64+1, instructionID,
+	); err != nil {
+		return fmt.Errorf("move instruction: %w", err)
+	}
+	if err := shiftInstructions(tx, ri.RowID, parentScope(ri.ParentID), ri.Position+1, -1); err != nil {
+		return err
+	}
+
+	touchPatternUpdatedAtForRow(tx, ri.RowID)
+	return tx.Commit()
+}
+
+// MoveInstructionOutOfGroup moves an instruction out of its group to the top level
+// of the row, just after the group, keeping its stitch, count, note and color. It
+// returns ErrNotInGroup if the instruction is already top-level.
+func MoveInstructionOutOfGroup(db *sql.DB, instructionID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ri, err := findInstructionPlacement(tx, instructionID)
+	if err != nil {
+		return err
+	}
+	if ri.ParentID == nil {
+		return ErrNotInGroup
+	}
+	group, err := findInstructionPlacement(tx, *ri.ParentID)
+	if err != nil {
+		return err
+	}
+
+	if err := shiftInstructions(tx, ri.RowID, 0, group.Position+1, +1); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"UPDATE row_instructions SET parent_id = NULL, position = ? WHERE id = ?",
+		group.Position+1, instructionID,
+	); err != nil {
+		return fmt.Errorf("move instruction: %w", err)
+	}
+	if err := shiftInstructions(tx, ri.RowID, group.ID, ri.Position+1, -1); err != nil {
+		return err
+	}
+
+	touchPatternUpdatedAtForRow(tx, ri.RowID)
+	return tx.Commit()
+}
+
+// Errors from GroupInstructions for a selection that can't be wrapped in a group.
+var (
+	ErrEmptySelection         = errors.New("no instructions selected")
+	ErrSelectionNotContiguous = errors.New("selected instructions must be next to each other at the top level of the row")
+	ErrSelectionHasGroup      = errors.New("a selection to group can't include a group")
+)
+
+// GroupInstructions wraps top-level instructions of a row in a new group repeated
+// repeat times. The group takes the place of the first selected instruction and the
+// selection moves into it in row order, keeping each instruction's fields. "To end"
+// can't apply inside a group (see ResolveToEnd), so a selected "to end" instruction
+// keeps the count it resolved to before grouping as a fixed one. The selection must
+// be a contiguous run of the row's top-level instructions (ErrSelectionNotContiguous)
+// with no groups in it (ErrSelectionHasGroup).
+func GroupInstructions(db *sql.DB, rowID int64, instructionIDs []int64, repeat int) (*RowInstruction, error) {
+	if len(instructionIDs) == 0 {
+		return nil, ErrEmptySelection
+	}
+	if err := CheckInstructionCounts(1, repeat, nil); err != nil {
+		return nil, err
+	}
+	repeat = max(repeat, 1)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	group := &RowInstruction{RowID: rowID, Count: 1, IsGroup: true, GroupRepeat: repeat}
+	var selected []*RowInstruction
+	seen := make(map[int64]bool)
+	for _, id := range instructionIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ri, err := findInstructionPlacement(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if ri.RowID != rowID || ri.ParentID != nil {
+			return nil, ErrSelectionNotContiguous
+		}
+		if ri.IsGroup {
+			return nil, ErrSelectionHasGroup
+		}
+		if err := checkInstructionPlacement(group, false, ri.StitchID, ri.Note); err != nil {
+			return nil, err
+		}
+		selected = append(selected, ri)
+	}
+	slices.SortFunc(selected, func(a, b *RowInstruction) int { return a.Position - b.Position })
+	for i := 1; i < len(selected); i++ {
+		if selected[i].Position != selected[i-1].Position+1 {
+			return nil, ErrSelectionNotContiguous
+		}
+	}
+	first, last := selected[0].Position, selected[len(selected)-1].Position
+
+	if err := freezeToEnd(tx, rowID, func(id int64) bool { return seen[id] }); err != nil {
+		return nil, err
+	}
+
+	// Park the selection on negative positions so the group can take the first slot.
+	for _, ri := range selected {
+		if _, err := tx.Exec("UPDATE row_instructions SET position = -id WHERE id = ?", ri.ID); err != nil {
+			return nil, fmt.Errorf("move instruction: %w", err)
+		}
+	}
+	result, err := tx.Exec(`
+		INSERT INTO row_instructions (row_id, position, count, is_group, group_repeat)
+		VALUES (?, ?, 1, 1, ?)
+	`, rowID, first, repeat)
+	if err != nil {
+		return nil, fmt.Errorf("insert group: %w", err)
+	}
+	group.ID, _ = result.LastInsertId()
+	group.Position = first
+	for i, ri := range selected {
+		if _, err := tx.Exec(
+			"UPDATE row_instructions SET parent_id = ?, position = ? WHERE id = ?",
+			group.ID, i+1, ri.ID,
+		); err != nil {
+			return nil, fmt.Errorf("move instruction: %w", err)
+		}
+	}
+	if len(selected) > 1 {
+		if err := shiftInstructions(tx, rowID, 0, last+1, -(len(selected) - 1)); err != nil {
+			return nil, err
+		}
+	}
+
+	touchPatternUpdatedAtForRow(tx, rowID)
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// UngroupInstruction dissolves a group: its children move, once each and in order,
+// to the top level of the row where the group was, and the group header is deleted.
+// The group's repeat and note are discarded; its color carries over to children
+// that were inheriting it, so the stitches keep the yarn they were worked in.
+func UngroupInstruction(db *sql.DB, groupID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	group, err := findInstructionPlacement(tx, groupID)
+	if err != nil {
+		return err
+	}
+	if !group.IsGroup {
+		return fmt.Errorf("instruction %d is not a group", groupID)
+	}
+	var color string
+	if err := tx.QueryRow("SELECT color FROM row_instructions WHERE id = ?", groupID).Scan(&color); err != nil {
+		return fmt.Errorf("load group: %w", err)
+	}
+
+	rows, err := tx.Query("SELECT id FROM row_instructions WHERE parent_id = ? ORDER BY position", groupID)
+	if err != nil {
+		return fmt.Errorf("list group children: %w", err)
+	}
+	var childIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan group child: %w", err)
+		}
+		childIDs = append(childIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Lay the children out just after the header, then drop the header and close up.
+	if len(childIDs) > 0 {
+		if err := shiftInstructions(tx, group.RowID, 0, group.Position+1, len(childIDs)); err != nil {
+			return err
+		}
+	}
+	for i, id := range childIDs {
+		if _, err := tx.Exec(`
+			UPDATE row_instructions
+			SET parent_id = NULL, position = ?, color = CASE WHEN color = '' THEN ? ELSE color END
+			WHERE id = ?
+		`, group.Position+1+i, color, id); err != nil {
+			return fmt.Errorf("ungroup: %w", err)
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM row_instructions WHERE id = ?", groupID); err != nil {
+		return fmt.Errorf("delete group: %w", err)
+	}
+	if err := shiftInstructions(tx, group.RowID, 0, group.Position+1, -1); err != nil {
+		return err
+	}
+
+	touchPatternUpdatedAtForRow(tx, group.RowID)
+	return tx.Commit()
+}
+
+// findInstructionPlacement loads the fields of an instruction that decide where it
+// can go: its row, position, parent, group-ness, stitch and note.
+func findInstructionPlacement(tx *sql.Tx, id int64) (*RowInstruction, error) {
+	ri := &RowInstruction{ID: id}
+	var parentID, stitchID sql.NullInt64
+	if err := tx.QueryRow(
+		"SELECT row_id, position, parent_id, is_group, stitch_id, note FROM row_instructions WHERE id = ?", id,
+	).Scan(&ri.RowID, &ri.Position, &parentID, &ri.IsGroup, &stitchID, &ri.Note); err != nil {
+		return nil, fmt.Errorf("instruction not found: %w", err)
+	}
+	if parentID.Valid {
+		ri.ParentID = &parentID.Int64
+	}
+	if stitchID.Valid {
+		ri.StitchID = &stitchID.Int64
+	}
+	return ri, nil
+}
+
+// parentScope is the parent_id of a sibling scope as the position index sees it,
+// with 0 for the top level.
+func parentScope(parentID *int64) int64 {
+	if parentID == nil {
+		return 0
+	}
+	return *parentID
+}
+
+// shiftInstructions moves the instructions of one sibling scope (parent 0 for the top
+// level) at position from or later by delta, going through negative positions to
+// stay clear of the unique index.
+func shiftInstructions(tx *sql.Tx, rowID, parent int64, from, delta int) error {
+	if _, err := tx.Exec(
+		"UPDATE row_instructions SET position = -(position + ?) WHERE row_id = ? AND COALESCE(parent_id, 0) = ? AND position >= ?",
+		delta, rowID, parent, from,
+	); err != nil {
+		return fmt.Errorf("shift instructions: %w", err)
+	}
+	if _, err := tx.Exec(
+		"UPDATE row_instructions SET position = -position WHERE row_id = ? AND COALESCE(parent_id, 0) = ? AND position < 0",
+		rowID, parent,
+	); err != nil {
+		return fmt.Errorf("shift instructions: %w", err)
+	}
+	return nil
+}
+
 // GetRowIDForInstruction returns the row_id for an instruction, for ownership checks.
 func GetRowIDForInstruction(db *sql.DB, instructionID int64) (int64, error) {
 	var rowID int64
@@ -381,3 +1048,32 @@ func touchPatternUpdatedAtForRow(tx *sql.Tx, rowID int64) {
 		touchPatternUpdatedAt(tx, patternID)
 	}
 }
+
+// DistinctIntoValuesForUser returns the "into" placements the user has used across
+// their patterns, most used first, for suggesting in the instruction forms.
+func DistinctIntoValuesForUser(db *sql.DB, userID int64) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT ri."into"
+		FROM row_instructions ri
+		JOIN rows r ON r.id = ri.row_id
+		JOIN pattern_sections ps ON ps.id = r.section_id
+		JOIN patterns p ON p.id = ps.pattern_id
+		WHERE p.user_id = ? AND ri."into" != ''
+		GROUP BY ri."into"
+		ORDER BY COUNT(*) DESC, ri."into"
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list into values: %w", err)
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("scan into value: %w", err)
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,371 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// testRowFor creates a user, pattern and section and returns an empty row in it.
+func testRowFor(t *testing.T) (*sql.DB, *Row) {
+	t.Helper()
+	db := newTestDB(t)
+	user := newTestUser(t, db, "a@b.com")
+	pattern, err := CreatePattern(db, user.ID, "Hat", "", PatternMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	section, err := CreateSection(db, pattern.ID, "Body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, mustRow(t, db, section.ID, 0)
+}
+
+func TestDeleteGroupCascadesToChildrenOnEveryConnection(t *testing.T) {
+	db, row := testRowFor(t)
+	sc := builtinStitchID(t, db, "sc")
+
+	// Hold a few connections open so the pool has to hand out fresh ones, and
+	// delete a group through each of them.
+	ctx := context.Background()
+	for i := range 3 {
+		group, err := CreateGroupInstruction(db, row.ID, 2, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range 2 {
+			if _, err := CreateChildInstruction(db, group.ID, sc, 1, "", "", "", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		var on int
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&on); err != nil {
+			t.Fatal(err)
+		}
+		if on != 1 {
+			t.Fatalf("connection %d: foreign_keys = %d", i, on)
+		}
+		if _, err := conn.ExecContext(ctx, "DELETE FROM row_instructions WHERE id = ?", group.ID); err != nil {
+			t.Fatal(err)
+		}
+		var children int
+		if err := db.QueryRow("SELECT COUNT(*) FROM row_instructions WHERE parent_id = ?", group.ID).Scan(&children); err != nil {
+			t.Fatal(err)
+		}
+		if children != 0 {
+			t.Fatalf("connection %d: %d children left after deleting their group", i, children)
+		}
+	}
+
+	group, err := CreateGroupInstruction(db, row.ID, 2, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateChildInstruction(db, group.ID, sc, 1, "", "", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteInstruction(db, group.ID); err != nil {
+		t.Fatal(err)
+	}
+	var left int
+	if err := db.QueryRow("SELECT COUNT(*) FROM row_instructions WHERE row_id = ?", row.ID).Scan(&left); err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Fatalf("%d instructions left after DeleteInstruction on the group", left)
+	}
+}
+
+func TestCheckInstructionPlacement(t *testing.T) {
+	stitch := int64(1)
+	group := &RowInstruction{ID: 1, IsGroup: true}
+	plain := &RowInstruction{ID: 2, StitchID: &stitch}
+	tests := []struct {
+		name     string
+		parent   *RowInstruction
+		isGroup  bool
+		stitchID *int64
+		note     string
+		want     error
+	}{
+		{"top-level stitch", nil, false, &stitch, "", nil},
+		{"top-level group", nil, true, nil, "", nil},
+		{"stitch in a group", group, false, &stitch, "", nil},
+		{"note in a group", group, false, nil, "turn", nil},
+		{"under a plain instruction", plain, false, &stitch, "", ErrParentNotGroup},
+		{"group under a plain instruction", plain, true, nil, "", ErrParentNotGroup},
+		{"group in a group", group, true, nil, "", ErrNestedGroup},
+		{"blank child", group, false, nil, "  ", ErrEmptyChild},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkInstructionPlacement(tt.parent, tt.isGroup, tt.stitchID, tt.note); err != tt.want {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateChildUnderNonGroupIsRejected(t *testing.T) {
+	db, row := testRowFor(t)
+	sc := builtinStitchID(t, db, "sc")
+	plain := mustInstruction(t, db, row.ID, sc, 3)
+
+	if _, err := CreateChildInstruction(db, plain.ID, sc, 1, "", "", "", nil); !errors.Is(err, ErrParentNotGroup) {
+		t.Fatalf("child under a plain instruction: got %v, want %v", err, ErrParentNotGroup)
+	}
+	var children int
+	if err := db.QueryRow("SELECT COUNT(*) FROM row_instructions WHERE parent_id = ?", plain.ID).Scan(&children); err != nil {
+		t.Fatal(err)
+	}
+	if children != 0 {
+		t.Fatalf("%d children stored under a plain instruction", children)
+	}
+
+	group, err := CreateGroupInstruction(db, row.ID, 2, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateChildInstruction(db, group.ID, nil, 1, "", "", "", nil); !errors.Is(err, ErrEmptyChild) {
+		t.Fatalf("blank child in a group: got %v, want %v", err, ErrEmptyChild)
+	}
+	if _, err := CreateChildInstruction(db, group.ID, sc, 1, "", "", "", nil); err != nil {
+		t.Fatalf("stitch in a group: %v", err)
+	}
+}
+
+func TestResolveToEnd(t *testing.T) {
+	two := 2
+	toEnd := func() RowInstruction { return RowInstruction{ID: 1, Count: 1, ToEnd: true} }
+	group := func(repeat int, counts ...int) RowInstruction {
+		g := RowInstruction{ID: 2, IsGroup: true, GroupRepeat: repeat}
+		for _, c := range counts {
+			g.Children = append(g.Children, RowInstruction{Count: c})
+		}
+		return g
+	}
+	tests := []struct {
+		name         string
+		instructions []RowInstruction
+		prev         int
+		expected     int
+		want         int // resolved count of the instruction with ID 1
+	}{
+		{"alone", []RowInstruction{toEnd()}, 12, 0, 12},
+		{"after a stitch", []RowInstruction{{ID: 3, Count: 4}, toEnd()}, 12, 0, 8},
+		{"after a works-into count", []RowInstruction{{ID: 3, Count: 3, Consumes: &two}, toEnd()}, 12, 0, 10},
+		{"after a group", []RowInstruction{group(3, 1, 2), toEnd()}, 18, 0, 9},
+		{"before a group", []RowInstruction{toEnd(), group(2, 1, 1)}, 10, 0, 6},
+		{"first row of a section, from expected", []RowInstruction{group(2, 1, 1), toEnd()}, 0, 10, 6},
+		{"first row of a section, after a works-into count", []RowInstruction{{ID: 3, Count: 2, Consumes: &two}, toEnd()}, 0, 5, 3},
+		{"negative remainder", []RowInstruction{{ID: 3, Count: 15}, toEnd()}, 12, 0, 0},
+		{"negative remainder from expected", []RowInstruction{group(4, 2, 2), toEnd()}, 0, 10, 0},
+		{"no previous count or expected", []RowInstruction{{ID: 3, Count: 4}, toEnd()}, 0, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ResolveToEnd(tt.instructions, tt.prev, tt.expected)
+			for _, ri := range tt.instructions {
+				if ri.ID == 1 && ri.Count != tt.want {
+					t.Fatalf("to end count = %d, want %d", ri.Count, tt.want)
+				}
+			}
+		})
+	}
+
+	// Only the first top-level stitch marked to end is resolved; a group never is.
+	instrs := []RowInstruction{{ID: 4, IsGroup: true, GroupRepeat: 2, ToEnd: true}, toEnd(), {ID: 5, Count: 7, ToEnd: true}}
+	ResolveToEnd(instrs, 20, 0)
+	if instrs[0].Count != 0 || instrs[1].Count != 13 || instrs[2].Count != 7 {
+		t.Fatalf("resolved %d, %d, %d; want 0, 13, 7", instrs[0].Count, instrs[1].Count, instrs[2].Count)
+	}
+}
+
+func TestToEndInstructionRules(t *testing.T) {
+	db, row := testRowFor(t)
+	sc := builtinStitchID(t, db, "sc")
+
+	if _, err := CreateToEndInstruction(db, row.ID, nil, "", "a note", ""); !errors.Is(err, ErrToEndNoStitch) {
+		t.Fatalf("to end with no stitch: got %v, want %v", err, ErrToEndNoStitch)
+	}
+	first, err := CreateToEndInstruction(db, row.ID, sc, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := CreateToEndInstruction(db, row.ID, sc, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ri, _ := FindInstructionByID(db, first.ID); ri.ToEnd {
+		t.Fatal("a second to end instruction left the first one marked")
+	}
+
+	group, err := CreateGroupInstruction(db, row.ID, 2, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := CreateChildInstruction(db, group.ID, sc, 1, "", "", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateInstruction(db, child.ID, sc, 1, "", "", "", nil, true); !errors.Is(err, ErrToEndInGroup) {
+		t.Fatalf("to end inside a group: got %v, want %v", err, ErrToEndInGroup)
+	}
+	if err := UpdateInstruction(db, first.ID, nil, 1, "", "note", "", nil, true); !errors.Is(err, ErrToEndNoStitch) {
+		t.Fatalf("to end update with no stitch: got %v, want %v", err, ErrToEndNoStitch)
+	}
+	if ri, _ := FindInstructionByID(db, second.ID); !ri.ToEnd {
+		t.Fatal("a rejected update cleared the row's to end mark")
+	}
+	two := 2
+	if err := UpdateInstruction(db, first.ID, sc, 1, "", "", "", &two, true); err != nil {
+		t.Fatal(err)
+	}
+	if ri, _ := FindInstructionByID(db, first.ID); !ri.ToEnd || ri.Consumes != nil {
+		t.Fatalf("updated to end instruction: to end %v, consumes %v", ri.ToEnd, ri.Consumes)
+	}
+	if ri, _ := FindInstructionByID(db, second.ID); ri.ToEnd {
+		t.Fatal("updating an instruction to end left another one marked")
+	}
+}
+
+func TestLeavingToEndKeepsTheResolvedCount(t *testing.T) {
+	db, first := testRowFor(t)
+	sc := builtinStitchID(t, db, "sc")
+	mustInstruction(t, db, first.ID, sc, 10)
+
+	// The second row works 2 sc twice in a group, then "sc to end" over the 6 left.
+	row := mustRow(t, db, first.SectionID, 10)
+	group, err := CreateGroupInstruction(db, row.ID, 2, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateChildInstruction(db, group.ID, sc, 2, "", "", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	toEnd, err := CreateToEndInstruction(db, row.ID, sc, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCount := func(what string, id int64, want int) {
+		t.Helper()
+		ri, err := FindInstructionByID(db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ri.ToEnd || ri.Count != want {
+			t.Fatalf("%s: to end %v, count %d; want false, %d", what, ri.ToEnd, ri.Count, want)
+		}
+	}
+
+	// Another "to end" takes the mark; the first keeps working its 6.
+	other, err := CreateToEndInstruction(db, row.ID, sc, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCount("replaced to end", toEnd.ID, 6)
+
+	if err := DeleteInstruction(db, toEnd.ID); err != nil {
+		t.Fatal(err)
+	}
+	wrapper, err := GroupInstructions(db, row.ID, []int64{other.ID}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCount("grouped", other.ID, 6)
+
+	if err := DeleteInstruction(db, wrapper.ID); err != nil {
+		t.Fatal(err)
+	}
+	moved, err := CreateToEndInstruction(db, row.ID, sc, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := MoveInstructionIntoGroup(db, moved.ID, group.ID); err != nil {
+		t.Fatal(err)
+	}
+	checkCount("moved into a group", moved.ID, 6)
+}
+
+// checkPermutation fails unless query, run with args, returns the positions 1..n,
+// n being the number of rows, in some order.
+func checkPermutation(t *testing.T, db *sql.DB, what string, query string, args ...any) {
+	t.Helper()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var positions []int
+	for rows.Next() {
+		var pos int
+		if err := rows.Scan(&pos); err != nil {
+			t.Fatal(err)
+		}
+		positions = append(positions, pos)
+	}
+	slices.Sort(positions)
+	for i, pos := range positions {
+		if pos != i+1 {
+			t.Fatalf("%s positions %v are not a permutation of 1..%d", what, positions, len(positions))
+		}
+	}
+}
+
+func TestConcurrentMovesKeepPositionsAPermutation(t *testing.T) {
+	db, row := testRowFor(t)
+	sc := builtinStitchID(t, db, "sc")
+	patternID, err := GetPatternIDForRow(db, row.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 6
+	var instrIDs, rowIDs, sectionIDs []int64
+	for i := range n {
+		instrIDs = append(instrIDs, mustInstruction(t, db, row.ID, sc, i+1).ID)
+		rowIDs = append(rowIDs, mustRow(t, db, row.SectionID, i).ID)
+		section, err := CreateSection(db, patternID, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sectionIDs = append(sectionIDs, section.ID)
+	}
+
+	moves := []func(*sql.DB, int64) error{MoveInstructionUp, MoveInstructionDown}
+	rowMoves := []func(*sql.DB, int64) error{MoveRowUp, MoveRowDown}
+	sectionMoves := []func(*sql.DB, int64) error{MoveSectionUp, MoveSectionDown}
+	var mu sync.Mutex
+	i := 0
+	next := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		i++
+		return i
+	}
+	runConcurrently(t, 60, func() error {
+		k := next()
+		if err := moves[k%2](db, instrIDs[k%n]); err != nil {
+			return err
+		}
+		if err := rowMoves[k/2%2](db, rowIDs[(k*5)%n]); err != nil {
+			return err
+		}
+		return sectionMoves[k/3%2](db, sectionIDs[(k*7)%n])
+	})
+
+	checkPermutation(t, db, "instruction",
+		"SELECT position FROM row_instructions WHERE row_id = ? AND parent_id IS NULL", row.ID)
+	checkPermutation(t, db, "row", "SELECT position FROM rows WHERE section_id = ?", row.SectionID)
+	checkPermutation(t, db, "section", "SELECT position FROM pattern_sections WHERE pattern_id = ?", patternID)
+}
@@ -0,0 +1,134 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RowMarker flags a stitch within a row. Work mode reports it in
+// WorkDisplayState.ReachedMarker when that stitch comes up, on every repeat of the row.
+type RowMarker struct {
+	ID        int64
+	RowID     int64
+	AtStitch  int // 1-based position within one repeat of the row
+	Note      string
+	CreatedAt time.Time
+}
+
+// ErrInvalidMarkerStitch is returned when a marker is placed outside the row's stitches.
+var ErrInvalidMarkerStitch = errors.New("marker stitch is outside the row")
+
+// ListRowMarkers returns a row's markers in stitch order.
+func ListRowMarkers(db *sql.DB, rowID int64) ([]RowMarker, error) {
+	rows, err := db.Query(`
+		SELECT id, row_id, at_stitch, note, created_at
+		FROM row_markers WHERE row_id = ?
+		ORDER BY at_stitch ASC, id ASC
+	`, rowID)
+	if err != nil {
+		return nil, fmt.Errorf("list row markers: %w", err)
+	}
+	defer rows.Close()
+
+	var markers []RowMarker
+	for rows.Next() {
+		m, err := scanRowMarker(rows)
+		if err != nil {
+			return nil, err
+		}
+		markers = append(markers, m)
+	}
+	return markers, rows.Err()
+}
+
+// scanRowMarker reads the columns id, row_id, at_stitch, note, created_at.
+func scanRowMarker(rows *sql.Rows) (RowMarker, error) {
+	var m RowMarker
+	var createdAt string
+	if err := rows.Scan(&m.ID, &m.RowID, &m.AtStitch, &m.Note, &createdAt); err != nil {
+		return m, fmt.Errorf("scan row marker: %w", err)
+	}
+	m.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	return m, nil
+}
+
+// CreateRowMarker adds a marker at a stitch of the row. atStitch must fall within one
+// repeat of the row as work mode steps through it.
+func CreateRowMarker(db *sql.DB, rowID int64, atStitch int, note string) (*RowMarker, error) {
+	row, err := FindRowByID(db, rowID)
+	if err != nil {
+		return nil, fmt.Errorf("row not found: %w", err)
+	}
+	if row.Instructions, err = ListInstructionsForRow(db, rowID); err != nil {
+		return nil, err
+	}
+	if atStitch < 1 || atStitch > rowStitchCount(row) {
+		return nil, ErrInvalidMarkerStitch
+	}
+
+	result, err := db.Exec(
+		"INSERT INTO row_markers (row_id, at_stitch, note) VALUES (?, ?, ?)",
+		rowID, atStitch, note,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert row marker: %w", err)
+	}
+	id, _ := result.LastInsertId()
+	return &RowMarker{
+		ID:        id,
+		RowID:     rowID,
+		AtStitch:  atStitch,
+		Note:      note,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// DeleteRowMarker removes a marker from a row.
+func DeleteRowMarker(db *sql.DB, id, rowID int64) error {
+	result, err := db.Exec("DELETE FROM row_markers WHERE id = ? AND row_id = ?", id, rowID)
+	if err != nil {
+		return fmt.Errorf("delete row marker: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return fmt.Errorf("marker not found")
+	}
+	return nil
+}
+
+// insertRowMarkers copies markers onto a row, keeping their IDs when keepIDs is set
+// (restoring a deleted row) or assigning new ones (duplicating).
+func insertRowMarkers(tx *sql.Tx, rowID int64, markers []RowMarker, keepIDs bool) error {
+	for _, m := range markers {
+		var id any
+		if keepIDs {
+			id = m.ID
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO row_markers (id, row_id, at_stitch, note, created_at) VALUES (?, ?, ?, ?, ?)",
+			id, rowID, m.AtStitch, m.Note, m.CreatedAt.Format(time.RFC3339),
+		); err != nil {
+			return fmt.Errorf("copy row marker: %w", err)
+		}
+	}
+	return nil
+}
+
+// markerNotesAt joins the notes of the markers placed at a stitch, or returns nil if
+// there are none.
+func markerNotesAt(markers []RowMarker, atStitch int) *string {
+	var notes []string
+	for _, m := range markers {
+		if m.AtStitch == atStitch {
+			notes = append(notes, m.Note)
+		}
+	}
+	if len(notes) == 0 {
+		return nil
+	}
+	joined := strings.Join(notes, " · ")
+	return &joined
+}
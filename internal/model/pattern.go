@@ -2,20 +2,28 @@ package model
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 )
 
 type Pattern struct {
-	ID           int64
-	UserID       int64
-	Name         string
-	Description  string
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	SectionCount int // populated by list queries
-	RowCount     int // populated by list queries
+	ID            int64
+	UserID        int64
+	Name          string
+	Description   string
+	GaugeStitches int // stitches per GaugeSwatchInches; 0 if not set
+	GaugeRows     int // rows per GaugeSwatchInches; 0 if not set
+	Meta          PatternMeta
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	SectionCount  int        // populated by list queries
+	RowCount      int        // populated by list queries
+	LastWorkedAt  *time.Time // latest work session activity; populated by list queries, nil if never worked
+	IsFavorite    bool       // pinned to the top of the dashboard
 }
 
 type PatternSection struct {
@@ -55,12 +63,15 @@ type Row struct {
 	TurningChainCountsAsStitch bool
 	RepeatCount                int
 	Notes                      string
+	CountOnly                  bool             // tracked as one step per repeat in work mode, not per stitch
+	CheckPrevious              bool             // validate BaseStitches against the previous row's stitch count
 	Instructions               []RowInstruction // populated when loading full pattern
+	Markers                    []RowMarker      // populated when loading full pattern
 }
 
 // --- Pattern CRUD ---
 
-func CreatePattern(db *sql.DB, userID int64, name, description string) (*Pattern, error) {
+func CreatePattern(db *sql.DB, userID int64, name, description string, meta PatternMeta) (*Pattern, error) {
 	tx, err := db.Begin()
 	if err != nil {
 		return nil, fmt.Errorf("begin tx: %w", err)
@@ -68,8 +79,9 @@ func CreatePattern(db *sql.DB, userID int64, name, description string) (*Pattern
 	defer tx.Rollback()
 
 	result, err := tx.Exec(
-		"INSERT INTO patterns (user_id, name, description) VALUES (?, ?, ?)",
-		userID, name, description,
+		`INSERT INTO patterns (user_id, name, description, difficulty, hook_size, yarn_weight, finished_size)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		userID, name, description, meta.Difficulty, meta.HookSize, meta.YarnWeight, meta.FinishedSize,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("insert pattern: %w", err)
@@ -96,6 +108,7 @@ func CreatePattern(db *sql.DB, userID int64, name, description string) (*Pattern
 		UserID:      userID,
 		Name:        name,
 		Description: description,
+		Meta:        meta,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}, nil
@@ -104,39 +117,103 @@ func CreatePattern(db *sql.DB, userID int64, name, description string) (*Pattern
 func ListPatternsByUser(db *sql.DB, userID int64) ([]Pattern, error) {
 	rows, err := db.Query(`
 		SELECT
-			p.id, p.user_id, p.name, p.description, p.created_at, p.updated_at,
+			p.id, p.user_id, p.name, p.description, p.is_favorite, p.created_at, p.updated_at,
 			(SELECT COUNT(*) FROM pattern_sections WHERE pattern_id = p.id) as section_count,
-			(SELECT COUNT(*) FROM rows r JOIN pattern_sections ps ON r.section_id = ps.id WHERE ps.pattern_id = p.id) as row_count
+			(SELECT COUNT(*) FROM rows r JOIN pattern_sections ps ON r.section_id = ps.id WHERE ps.pattern_id = p.id) as row_count,
+			w.last_worked_at
 		FROM patterns p
+		LEFT JOIN (
+			SELECT pattern_id, MAX(last_active_at) AS last_worked_at
+			FROM work_sessions WHERE user_id = ? GROUP BY pattern_id
+		) w ON w.pattern_id = p.id
 		WHERE p.user_id = ?
-		ORDER BY p.updated_at DESC
-	`, userID)
+		ORDER BY p.is_favorite DESC, p.updated_at DESC
+	`, userID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("list patterns: %w", err)
 	}
+	return scanPatternList(rows)
+}
+
+// ListPatternsByTag is like ListPatternsByUser but only returns patterns carrying the
+// named tag (matched case-insensitively).
+func ListPatternsByTag(db *sql.DB, userID int64, tag string) ([]Pattern, error) {
+	rows, err := db.Query(`
+		SELECT
+			p.id, p.user_id, p.name, p.description, p.is_favorite, p.created_at, p.updated_at,
+			(SELECT COUNT(*) FROM pattern_sections WHERE pattern_id = p.id) as section_count,
+			(SELECT COUNT(*) FROM rows r JOIN pattern_sections ps ON r.section_id = ps.id WHERE ps.pattern_id = p.id) as row_count,
+			w.last_worked_at
+		FROM patterns p
+		JOIN pattern_tags pt ON pt.pattern_id = p.id
+		JOIN tags t ON t.id = pt.tag_id
+		LEFT JOIN (
+			SELECT pattern_id, MAX(last_active_at) AS last_worked_at
+			FROM work_sessions WHERE user_id = ? GROUP BY pattern_id
+		) w ON w.pattern_id = p.id
+		WHERE p.user_id = ? AND t.user_id = ? AND t.name = ?
+		ORDER BY p.is_favorite DESC, p.updated_at DESC
+	`, userID, userID, userID, strings.TrimSpace(tag))
+	if err != nil {
+		return nil, fmt.Errorf("list patterns by tag: %w", err)
+	}
+	return scanPatternList(rows)
+}
+
+// scanPatternList reads the rows of a pattern list query and closes them.
+func scanPatternList(rows *sql.Rows) ([]Pattern, error) {
 	defer rows.Close()
 
 	var patterns []Pattern
 	for rows.Next() {
 		var p Pattern
 		var createdAt, updatedAt string
-		if err := rows.Scan(&p.ID, &p.UserID, &p.Name, &p.Description, &createdAt, &updatedAt, &p.SectionCount, &p.RowCount); err != nil {
+		var lastWorkedAt sql.NullString
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Name, &p.Description, &p.IsFavorite, &createdAt, &updatedAt, &p.SectionCount, &p.RowCount, &lastWorkedAt); err != nil {
 			return nil, fmt.Errorf("scan pattern: %w", err)
 		}
 		p.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
 		p.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+		if lastWorkedAt.Valid {
+			t, _ := time.Parse(time.RFC3339, lastWorkedAt.String)
+			p.LastWorkedAt = &t
+		}
 		patterns = append(patterns, p)
 	}
 	return patterns, rows.Err()
 }
 
+// SortPatternsByLastWorked orders patterns by most recent work session activity,
+// with patterns that were never worked last (keeping their existing order).
+// Favorites stay ahead of everything else.
+func SortPatternsByLastWorked(patterns []Pattern) {
+	slices.SortStableFunc(patterns, func(a, b Pattern) int {
+		switch {
+		case a.IsFavorite != b.IsFavorite:
+			if a.IsFavorite {
+				return -1
+			}
+			return 1
+		case a.LastWorkedAt == nil && b.LastWorkedAt == nil:
+			return 0
+		case a.LastWorkedAt == nil:
+			return 1
+		case b.LastWorkedAt == nil:
+			return -1
+		}
+		return b.LastWorkedAt.Compare(*a.LastWorkedAt)
+	})
+}
+
 func FindPatternByID(db *sql.DB, id int64) (*Pattern, error) {
 	p := &Pattern{}
 	var createdAt, updatedAt string
 	err := db.QueryRow(`
-		SELECT id, user_id, name, description, created_at, updated_at
+		SELECT id, user_id, name, description, gauge_stitches, gauge_rows,
+		       difficulty, hook_size, yarn_weight, finished_size, is_favorite, created_at, updated_at
 		FROM patterns WHERE id = ?
-	`, id).Scan(&p.ID, &p.UserID, &p.Name, &p.Description, &createdAt, &updatedAt)
+	`, id).Scan(&p.ID, &p.UserID, &p.Name, &p.Description, &p.GaugeStitches, &p.GaugeRows,
+		&p.Meta.Difficulty, &p.Meta.HookSize, &p.Meta.YarnWeight, &p.Meta.FinishedSize, &p.IsFavorite, &createdAt, &updatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -145,11 +222,14 @@ func FindPatternByID(db *sql.DB, id int64) (*Pattern, error) {
 	return p, nil
 }
 
-func UpdatePattern(db *sql.DB, id, userID int64, name, description string) error {
+func UpdatePattern(db *sql.DB, id, userID int64, name, description string, gaugeStitches, gaugeRows int, meta PatternMeta) error {
 	result, err := db.Exec(`
-		UPDATE patterns SET name = ?, description = ?, updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		UPDATE patterns SET name = ?, description = ?, gauge_stitches = ?, gauge_rows = ?,
+		                    difficulty = ?, hook_size = ?, yarn_weight = ?, finished_size = ?,
+		                    updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
 		WHERE id = ? AND user_id = ?
-	`, name, description, id, userID)
+	`, name, description, gaugeStitches, gaugeRows,
+		meta.Difficulty, meta.HookSize, meta.YarnWeight, meta.FinishedSize, id, userID)
 	if err != nil {
 		return fmt.Errorf("update pattern: %w", err)
 	}
@@ -160,8 +240,35 @@ func UpdatePattern(db *sql.DB, id, userID int64, name, description string) error
 	return nil
 }
 
+// SetPatternFavorite pins or unpins a pattern on the dashboard. It doesn't count as
+// an edit, so updated_at is left alone.
+func SetPatternFavorite(db *sql.DB, id, userID int64, favorite bool) error {
+	result, err := db.Exec("UPDATE patterns SET is_favorite = ? WHERE id = ? AND user_id = ?", favorite, id, userID)
+	if err != nil {
+		return fmt.Errorf("set pattern favorite: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return fmt.Errorf("pattern not found or not owned by user")
+	}
+	return nil
+}
+
 func DeletePattern(db *sql.DB, id, userID int64) error {
-	result, err := db.Exec("DELETE FROM patterns WHERE id = ? AND user_id = ?", id, userID)
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"DELETE FROM ui_section_state WHERE section_id IN (SELECT id FROM pattern_sections WHERE pattern_id = ?) AND user_id = ?",
+		id, userID,
+	); err != nil {
+		return fmt.Errorf("delete section state: %w", err)
+	}
+
+	result, err := tx.Exec("DELETE FROM patterns WHERE id = ? AND user_id = ?", id, userID)
 	if err != nil {
 		return fmt.Errorf("delete pattern: %w", err)
 	}
@@ -169,7 +276,17 @@ func DeletePattern(db *sql.DB, id, userID int64) error {
 	if n == 0 {
 		return fmt.Errorf("pattern not found or not owned by user")
 	}
-	return nil
+
+	if _, err := tx.Exec("DELETE FROM share_tokens WHERE pattern_id = ?", id); err != nil {
+		return fmt.Errorf("delete share links: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM pattern_tags WHERE pattern_id = ?", id); err != nil {
+		return fmt.Errorf("delete pattern tags: %w", err)
+	}
+	if err := deleteUnusedTags(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func touchPatternUpdatedAt(tx *sql.Tx, patternID int64) {
@@ -284,6 +401,9 @@ func DeleteSection(db *sql.DB, id int64) error {
 		return fmt.Errorf("section not found: %w", err)
 	}
 
+	if _, err := tx.Exec("DELETE FROM ui_section_state WHERE section_id = ?", id); err != nil {
+		return fmt.Errorf("delete section state: %w", err)
+	}
 	if _, err := tx.Exec("DELETE FROM pattern_sections WHERE id = ?", id); err != nil {
 		return fmt.Errorf("delete section: %w", err)
 	}
@@ -300,6 +420,91 @@ func DeleteSection(db *sql.DB, id int64) error {
 	return tx.Commit()
 }
 
+// DuplicateSection copies a section with all its rows and their instructions, placing
+// the copy directly after the original (e.g. for identical left and right sleeves).
+func DuplicateSection(db *sql.DB, sectionID int64) (*PatternSection, error) {
+	src, err := FindSectionByID(db, sectionID)
+	if err != nil {
+		return nil, fmt.Errorf("section not found: %w", err)
+	}
+	rows, err := ListRowsBySection(db, sectionID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		if rows[i].Instructions, err = ListInstructionsForRow(db, rows[i].ID); err != nil {
+			return nil, err
+		}
+		if rows[i].Markers, err = ListRowMarkers(db, rows[i].ID); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Make room after the original, going through negative positions to stay clear
+	// of the unique index.
+	pos := src.Position + 1
+	if _, err := tx.Exec(
+		"UPDATE pattern_sections SET position = -(position + 1) WHERE pattern_id = ? AND position >= ?",
+		src.PatternID, pos,
+	); err != nil {
+		return nil, fmt.Errorf("shift sections: %w", err)
+	}
+	if _, err := tx.Exec(
+		"UPDATE pattern_sections SET position = -position WHERE pattern_id = ? AND position < 0",
+		src.PatternID,
+	); err != nil {
+		return nil, fmt.Errorf("shift sections: %w", err)
+	}
+
+	name := src.Name + " (copy)"
+	result, err := tx.Exec(
+		"INSERT INTO pattern_sections (pattern_id, position, name, notes) VALUES (?, ?, ?, ?)",
+		src.PatternID, pos, name, src.Notes,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert section: %w", err)
+	}
+	newID, _ := result.LastInsertId()
+
+	for _, row := range rows {
+		result, err := tx.Exec(`
+			INSERT INTO rows (section_id, position, label, type, expected_stitch_count,
+			                  turning_chain_count, turning_chain_counts_as_stitch, repeat_count, notes, count_only, check_previous)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, newID, row.Position, row.Label, row.Type, row.ExpectedStitchCount,
+			row.TurningChainCount, row.TurningChainCountsAsStitch, row.RepeatCount, row.Notes, row.CountOnly, row.CheckPrevious)
+		if err != nil {
+			return nil, fmt.Errorf("copy row: %w", err)
+		}
+		rowID, _ := result.LastInsertId()
+		if err := insertInstructionTree(tx, rowID, nil, 0, row.Instructions, nil); err != nil {
+			return nil, err
+		}
+		if err := insertRowMarkers(tx, rowID, row.Markers, false); err != nil {
+			return nil, err
+		}
+	}
+
+	touchPatternUpdatedAt(tx, src.PatternID)
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &PatternSection{
+		ID:        newID,
+		PatternID: src.PatternID,
+		Position:  pos,
+		Name:      name,
+		Notes:     src.Notes,
+	}, nil
+}
+
 func MoveSectionUp(db *sql.DB, id int64) error {
 	return swapSectionPosition(db, id, -1)
 }
@@ -336,8 +541,9 @@ func swapSectionPosition(db *sql.DB, id int64, delta int) error {
 		return nil // no neighbor to swap with
 	}
 
-	// Use a temporary position to avoid unique constraint violation.
-	if _, err := tx.Exec("UPDATE pattern_sections SET position = -1 WHERE id = ?", id); err != nil {
+	// Use a temporary position to avoid unique constraint violation. -id can't clash
+	// with a real position or with another item parked mid-swap.
+	if _, err := tx.Exec("UPDATE pattern_sections SET position = -id WHERE id = ?", id); err != nil {
 		return err
 	}
 	if _, err := tx.Exec("UPDATE pattern_sections SET position = ? WHERE id = ?", position, otherID); err != nil {
@@ -351,12 +557,77 @@ func swapSectionPosition(db *sql.DB, id int64, delta int) error {
 	return tx.Commit()
 }
 
+// ErrReorderMismatch is returned when a reorder request's IDs are not exactly the current set of items.
+var ErrReorderMismatch = errors.New("reorder IDs do not match current items")
+
+// ReorderSections rewrites section positions in the given order.
+// orderedIDs must contain every section of the pattern exactly once.
+func ReorderSections(db *sql.DB, patternID int64, orderedIDs []int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := reorderPositions(tx, "pattern_sections", "pattern_id = ?", []any{patternID}, orderedIDs); err != nil {
+		return err
+	}
+
+	touchPatternUpdatedAt(tx, patternID)
+	return tx.Commit()
+}
+
+// reorderPositions assigns positions 1..n to orderedIDs within one scope of table
+// (the rows matching scopeWhere). It first checks orderedIDs is exactly the scope's
+// current ID set, then moves everything to negative temp positions so the unique
+// position index is never violated mid-update.
+func reorderPositions(tx *sql.Tx, table, scopeWhere string, scopeArgs []any, orderedIDs []int64) error {
+	rows, err := tx.Query("SELECT id FROM "+table+" WHERE "+scopeWhere, scopeArgs...)
+	if err != nil {
+		return fmt.Errorf("list %s: %w", table, err)
+	}
+	current := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan %s: %w", table, err)
+		}
+		current[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(orderedIDs) != len(current) {
+		return ErrReorderMismatch
+	}
+	seen := make(map[int64]bool, len(orderedIDs))
+	for _, id := range orderedIDs {
+		if !current[id] || seen[id] {
+			return ErrReorderMismatch
+		}
+		seen[id] = true
+	}
+
+	if _, err := tx.Exec("UPDATE "+table+" SET position = -position WHERE "+scopeWhere, scopeArgs...); err != nil {
+		return fmt.Errorf("reorder %s: %w", table, err)
+	}
+	for i, id := range orderedIDs {
+		if _, err := tx.Exec("UPDATE "+table+" SET position = ? WHERE id = ?", i+1, id); err != nil {
+			return fmt.Errorf("reorder %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
 // --- Row CRUD ---
 
 func ListRowsBySection(db *sql.DB, sectionID int64) ([]Row, error) {
 	dbRows, err := db.Query(`
 		SELECT id, section_id, position, label, type, expected_stitch_count,
-		       turning_chain_count, turning_chain_counts_as_stitch, repeat_count, notes
+		       turning_chain_count, turning_chain_counts_as_stitch, repeat_count, notes, count_only, check_previous
 		FROM rows
 		WHERE section_id = ?
 		ORDER BY position ASC
@@ -371,7 +642,7 @@ func ListRowsBySection(db *sql.DB, sectionID int64) ([]Row, error) {
 		var r Row
 		if err := dbRows.Scan(&r.ID, &r.SectionID, &r.Position, &r.Label, &r.Type,
 			&r.ExpectedStitchCount, &r.TurningChainCount, &r.TurningChainCountsAsStitch,
-			&r.RepeatCount, &r.Notes); err != nil {
+			&r.RepeatCount, &r.Notes, &r.CountOnly, &r.CheckPrevious); err != nil {
 			return nil, fmt.Errorf("scan row: %w", err)
 		}
 		result = append(result, r)
@@ -383,18 +654,183 @@ func FindRowByID(db *sql.DB, id int64) (*Row, error) {
 	r := &Row{}
 	err := db.QueryRow(`
 		SELECT id, section_id, position, label, type, expected_stitch_count,
-		       turning_chain_count, turning_chain_counts_as_stitch, repeat_count, notes
+		       turning_chain_count, turning_chain_counts_as_stitch, repeat_count, notes, count_only, check_previous
 		FROM rows WHERE id = ?
 	`, id).Scan(&r.ID, &r.SectionID, &r.Position, &r.Label, &r.Type,
 		&r.ExpectedStitchCount, &r.TurningChainCount, &r.TurningChainCountsAsStitch,
-		&r.RepeatCount, &r.Notes)
+		&r.RepeatCount, &r.Notes, &r.CountOnly, &r.CheckPrevious)
 	if err != nil {
 		return nil, err
 	}
 	return r, nil
 }
 
-func CreateRow(db *sql.DB, sectionID int64, label, rowType string, stitchCount, turningChain int, turningChainCounts bool, repeatCount int, notes string) (*Row, error) {
+// PreviousRowStitchCount returns how many stitches the row before rowID in the same
+// section ends with: its expected stitch count, or the stitches its instructions make
+// when that isn't set. It returns 0 for the first row of a section.
+func PreviousRowStitchCount(db readQueryer, rowID int64) (int, error) {
+	return rowEndStitchCount(db, `
+		SELECT p.id, p.expected_stitch_count FROM rows r
+		JOIN rows p ON p.section_id = r.section_id AND p.position < r.position
+		WHERE r.id = ?
+		ORDER BY p.position DESC LIMIT 1
+	`, rowID)
+}
+
+// LastRowStitchCount is PreviousRowStitchCount for a row about to be added to the end
+// of the section: the stitches its current last row ends with, or 0 if it has none.
+func LastRowStitchCount(db *sql.DB, sectionID int64) (int, error) {
+	return rowEndStitchCount(db, `
+		SELECT id, expected_stitch_count FROM rows
+		WHERE section_id = ?
+		ORDER BY position DESC LIMIT 1
+	`, sectionID)
+}
+
+// rowEndStitchCount counts the stitches of the row query selects (id, expected stitch
+// count) for PreviousRowStitchCount and LastRowStitchCount.
+func rowEndStitchCount(db readQueryer, query string, arg int64) (int, error) {
+	var id int64
+	var expected int
+	err := db.QueryRow(query, arg).Scan(&id, &expected)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("find previous row: %w", err)
+	}
+	if expected > 0 {
+		return expected, nil
+	}
+	instructions, err := ListInstructionsForRow(db, id)
+	if err != nil {
+		return 0, fmt.Errorf("list previous row instructions: %w", err)
+	}
+	return CountStitchPos(instructions), nil
+}
+
+// ValidRowTypes lists the accepted values of Row.Type.
+var ValidRowTypes = []string{"row", "joined_round", "continuous_round"}
+
+// ErrInvalidRowType is returned when a row type isn't one of ValidRowTypes.
+var ErrInvalidRowType = errors.New("unknown row type")
+
+// IsValidRowType reports whether t is one of ValidRowTypes.
+func IsValidRowType(t string) bool {
+	return slices.Contains(ValidRowTypes, t)
+}
+
+// RowInput holds the user-editable settings of a row.
+type RowInput struct {
+	Label                      string
+	Type                       string
+	ExpectedStitchCount        int
+	TurningChainCount          int
+	TurningChainCountsAsStitch bool
+	RepeatCount                int
+	Notes                      string
+	CountOnly                  bool
+	CheckPrevious              bool
+}
+
+// Upper bounds on repeat and stitch counts. Work mode expands every repeat into
+// stitch positions, so an absurd count would have it allocate without limit.
+const (
+	MaxRowRepeat        = 1000
+	MaxGroupRepeat      = 1000
+	MaxInstructionCount = 10000
+)
+
+// ErrCountTooLarge is returned when a repeat or stitch count is over its limit.
+var ErrCountTooLarge = fmt.Errorf("row repeats are limited to %d, group repeats to %d and stitch counts to %d",
+	MaxRowRepeat, MaxGroupRepeat, MaxInstructionCount)
+
+// MaxRowRange caps how many rows CreateRowRange adds at once.
+const MaxRowRange = 200
+
+// CreateRowRange appends count distinct rows with the same settings to the end of a
+// section, in one transaction, so they can be tweaked individually later
+// (unlike a single row with RepeatCount).
+func CreateRowRange(db *sql.DB, sectionID int64, count int, template RowInput) ([]Row, error) {
+	if count < 1 || count > MaxRowRange {
+		return nil, fmt.Errorf("row count must be between 1 and %d", MaxRowRange)
+	}
+	if !IsValidRowType(template.Type) {
+		return nil, ErrInvalidRowType
+	}
+	if template.RepeatCount > MaxRowRepeat {
+		return nil, ErrCountTooLarge
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var maxPos sql.NullInt64
+	tx.QueryRow("SELECT MAX(position) FROM rows WHERE section_id = ?", sectionID).Scan(&maxPos)
+	nextPos := 1
+	if maxPos.Valid {
+		nextPos = int(maxPos.Int64) + 1
+	}
+
+	var patternID int64
+	if err := tx.QueryRow("SELECT pattern_id FROM pattern_sections WHERE id = ?", sectionID).Scan(&patternID); err != nil {
+		return nil, fmt.Errorf("section not found: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO rows (section_id, position, label, type, expected_stitch_count,
+		                   turning_chain_count, turning_chain_counts_as_stitch, repeat_count, notes, count_only, check_previous)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows := make([]Row, 0, count)
+	for i := 0; i < count; i++ {
+		pos := nextPos + i
+		result, err := stmt.Exec(sectionID, pos, template.Label, template.Type, template.ExpectedStitchCount,
+			template.TurningChainCount, template.TurningChainCountsAsStitch, template.RepeatCount, template.Notes, template.CountOnly, template.CheckPrevious)
+		if err != nil {
+			return nil, fmt.Errorf("insert row: %w", err)
+		}
+		id, _ := result.LastInsertId()
+		rows = append(rows, Row{
+			ID:                         id,
+			SectionID:                  sectionID,
+			Position:                   pos,
+			Label:                      template.Label,
+			Type:                       template.Type,
+			ExpectedStitchCount:        template.ExpectedStitchCount,
+			TurningChainCount:          template.TurningChainCount,
+			TurningChainCountsAsStitch: template.TurningChainCountsAsStitch,
+			RepeatCount:                template.RepeatCount,
+			Notes:                      template.Notes,
+			CountOnly:                  template.CountOnly,
+			CheckPrevious:              template.CheckPrevious,
+		})
+	}
+
+	touchPatternUpdatedAt(tx, patternID)
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func CreateRow(db *sql.DB, sectionID int64, label, rowType string, stitchCount, turningChain int, turningChainCounts bool, repeatCount int, notes string, countOnly, checkPrevious bool) (*Row, error) {
+	if !IsValidRowType(rowType) {
+		return nil, ErrInvalidRowType
+	}
+	if repeatCount > MaxRowRepeat {
+		return nil, ErrCountTooLarge
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		return nil, err
@@ -417,9 +853,9 @@ func CreateRow(db *sql.DB, sectionID int64, label, rowType string, stitchCount,
 
 	result, err := tx.Exec(`
 		INSERT INTO rows (section_id, position, label, type, expected_stitch_count,
-		                   turning_chain_count, turning_chain_counts_as_stitch, repeat_count, notes)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, sectionID, nextPos, label, rowType, stitchCount, turningChain, turningChainCounts, repeatCount, notes)
+		                   turning_chain_count, turning_chain_counts_as_stitch, repeat_count, notes, count_only, check_previous)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, sectionID, nextPos, label, rowType, stitchCount, turningChain, turningChainCounts, repeatCount, notes, countOnly, checkPrevious)
 	if err != nil {
 		return nil, fmt.Errorf("insert row: %w", err)
 	}
@@ -442,10 +878,19 @@ func CreateRow(db *sql.DB, sectionID int64, label, rowType string, stitchCount,
 		TurningChainCountsAsStitch: turningChainCounts,
 		RepeatCount:                repeatCount,
 		Notes:                      notes,
+		CountOnly:                  countOnly,
+		CheckPrevious:              checkPrevious,
 	}, nil
 }
 
-func UpdateRow(db *sql.DB, id int64, label, rowType string, stitchCount, turningChain int, turningChainCounts bool, repeatCount int, notes string) error {
+func UpdateRow(db *sql.DB, id int64, label, rowType string, stitchCount, turningChain int, turningChainCounts bool, repeatCount int, notes string, countOnly, checkPrevious bool) error {
+	if !IsValidRowType(rowType) {
+		return ErrInvalidRowType
+	}
+	if repeatCount > MaxRowRepeat {
+		return ErrCountTooLarge
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		return err
@@ -465,9 +910,9 @@ func UpdateRow(db *sql.DB, id int64, label, rowType string, stitchCount, turning
 	if _, err := tx.Exec(`
 		UPDATE rows SET label = ?, type = ?, expected_stitch_count = ?,
 		               turning_chain_count = ?, turning_chain_counts_as_stitch = ?,
-		               repeat_count = ?, notes = ?
+		               repeat_count = ?, notes = ?, count_only = ?, check_previous = ?
 		WHERE id = ?
-	`, label, rowType, stitchCount, turningChain, turningChainCounts, repeatCount, notes, id); err != nil {
+	`, label, rowType, stitchCount, turningChain, turningChainCounts, repeatCount, notes, countOnly, checkPrevious, id); err != nil {
 		return fmt.Errorf("update row: %w", err)
 	}
 
@@ -475,34 +920,231 @@ func UpdateRow(db *sql.DB, id int64, label, rowType string, stitchCount, turning
 	return tx.Commit()
 }
 
-func DeleteRow(db *sql.DB, id int64) error {
-	tx, err := db.Begin()
+// RowUndoWindow is how long a deleted row can be restored with RestoreRow.
+const RowUndoWindow = 30 * time.Second
+
+// ErrRowTrashExpired is returned by RestoreRow for unknown or expired undo tokens.
+var ErrRowTrashExpired = errors.New("row can no longer be restored")
+
+// ErrRowStitchDeleted is returned by RestoreRow when one of the row's stitches was
+// deleted after the row was.
+var ErrRowStitchDeleted = errors.New("a stitch the row used has been deleted")
+
+// DeleteRow deletes a row and its instructions, keeping a snapshot in the trash for
+// RowUndoWindow. It returns the token that RestoreRow takes to bring the row back.
+func DeleteRow(db *sql.DB, id, userID int64) (string, error) {
+	row, err := FindRowByID(db, id)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("row not found: %w", err)
+	}
+	// The stored instructions, not ListInstructionsForRow's: a "to end" count must
+	// come back as the placeholder it is, to be resolved against the rows around it.
+	row.Instructions, err = listRowInstructionTree(db, id)
+	if err != nil {
+		return "", err
+	}
+	row.Markers, err = ListRowMarkers(db, id)
+	if err != nil {
+		return "", err
+	}
+	snapshot, err := json.Marshal(row)
+	if err != nil {
+		return "", fmt.Errorf("snapshot row: %w", err)
+	}
+	token, err := generateToken()
+	if err != nil {
+		return "", err
 	}
-	defer tx.Rollback()
 
-	var sectionID int64
-	var position int
-	if err := tx.QueryRow("SELECT section_id, position FROM rows WHERE id = ?", id).Scan(&sectionID, &position); err != nil {
-		return fmt.Errorf("row not found: %w", err)
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
 	}
+	defer tx.Rollback()
 
 	var patternID int64
-	if err := tx.QueryRow("SELECT pattern_id FROM pattern_sections WHERE id = ?", sectionID).Scan(&patternID); err != nil {
-		return fmt.Errorf("section not found: %w", err)
+	if err := tx.QueryRow("SELECT pattern_id FROM pattern_sections WHERE id = ?", row.SectionID).Scan(&patternID); err != nil {
+		return "", fmt.Errorf("section not found: %w", err)
 	}
 
+	if _, err := tx.Exec("DELETE FROM row_instructions WHERE row_id = ?", id); err != nil {
+		return "", fmt.Errorf("delete row instructions: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM row_markers WHERE row_id = ?", id); err != nil {
+		return "", fmt.Errorf("delete row markers: %w", err)
+	}
 	if _, err := tx.Exec("DELETE FROM rows WHERE id = ?", id); err != nil {
-		return fmt.Errorf("delete row: %w", err)
+		return "", fmt.Errorf("delete row: %w", err)
 	}
 
 	// Reorder remaining rows.
 	if _, err := tx.Exec(
 		"UPDATE rows SET position = position - 1 WHERE section_id = ? AND position > ?",
-		sectionID, position,
+		row.SectionID, row.Position,
+	); err != nil {
+		return "", fmt.Errorf("reorder rows: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if _, err := tx.Exec("DELETE FROM row_trash WHERE expires_at <= ?", now.Format(time.RFC3339)); err != nil {
+		return "", fmt.Errorf("purge row trash: %w", err)
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO row_trash (token, user_id, section_id, data, expires_at) VALUES (?, ?, ?, ?, ?)",
+		token, userID, row.SectionID, string(snapshot), now.Add(RowUndoWindow).Format(time.RFC3339),
 	); err != nil {
-		return fmt.Errorf("reorder rows: %w", err)
+		return "", fmt.Errorf("trash row: %w", err)
+	}
+
+	touchPatternUpdatedAt(tx, patternID)
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RestoreRow re-inserts a row deleted by DeleteRow, with its original IDs and
+// instruction tree, at its old position (or the end, if the section has shrunk).
+// Returns ErrRowTrashExpired once the undo window has passed, and ErrRowStitchDeleted
+// if a stitch the row used is gone.
+func RestoreRow(db *sql.DB, token string, userID int64) (*Row, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var data, expiresAt string
+	err = tx.QueryRow(
+		"SELECT data, expires_at FROM row_trash WHERE token = ? AND user_id = ?",
+		token, userID,
+	).Scan(&data, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrRowTrashExpired
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find trashed row: %w", err)
+	}
+	if exp, _ := time.Parse(time.RFC3339, expiresAt); !time.Now().UTC().Before(exp) {
+		return nil, ErrRowTrashExpired
+	}
+
+	var row Row
+	if err := json.Unmarshal([]byte(data), &row); err != nil {
+		return nil, fmt.Errorf("read row snapshot: %w", err)
+	}
+
+	var patternID int64
+	if err := tx.QueryRow(`
+		SELECT ps.pattern_id FROM pattern_sections ps JOIN patterns p ON p.id = ps.pattern_id
+		WHERE ps.id = ? AND p.user_id = ?
+	`, row.SectionID, userID).Scan(&patternID); err != nil {
+		return nil, ErrRowTrashExpired // the section went away too
+	}
+
+	var rowCount int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM rows WHERE section_id = ?", row.SectionID).Scan(&rowCount); err != nil {
+		return nil, fmt.Errorf("count rows: %w", err)
+	}
+	if row.Position > rowCount+1 {
+		row.Position = rowCount + 1
+	}
+
+	// Make room, going through negative positions to stay clear of the unique index.
+	if _, err := tx.Exec(
+		"UPDATE rows SET position = -(position + 1) WHERE section_id = ? AND position >= ?",
+		row.SectionID, row.Position,
+	); err != nil {
+		return nil, fmt.Errorf("shift rows: %w", err)
+	}
+	if _, err := tx.Exec(
+		"UPDATE rows SET position = -position WHERE section_id = ? AND position < 0",
+		row.SectionID,
+	); err != nil {
+		return nil, fmt.Errorf("shift rows: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO rows (id, section_id, position, label, type, expected_stitch_count,
+		                  turning_chain_count, turning_chain_counts_as_stitch, repeat_count, notes, count_only, check_previous)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, row.ID, row.SectionID, row.Position, row.Label, row.Type, row.ExpectedStitchCount,
+		row.TurningChainCount, row.TurningChainCountsAsStitch, row.RepeatCount, row.Notes, row.CountOnly, row.CheckPrevious); err != nil {
+		return nil, fmt.Errorf("restore row: %w", err)
+	}
+
+	for _, ri := range row.Instructions {
+		if err := restoreInstruction(tx, ri); err != nil {
+			return nil, err
+		}
+		for _, child := range ri.Children {
+			if err := restoreInstruction(tx, child); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := insertRowMarkers(tx, row.ID, row.Markers, true); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM row_trash WHERE token = ?", token); err != nil {
+		return nil, fmt.Errorf("clear row trash: %w", err)
+	}
+
+	touchPatternUpdatedAt(tx, patternID)
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+func restoreInstruction(tx *sql.Tx, ri RowInstruction) error {
+	if ri.StitchID != nil {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM stitches WHERE id = ?)", *ri.StitchID).Scan(&exists); err != nil {
+			return fmt.Errorf("find stitch: %w", err)
+		}
+		if !exists {
+			return ErrRowStitchDeleted
+		}
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO row_instructions (id, row_id, position, stitch_id, count, "into", is_group, parent_id, group_repeat, note, color, consumes, to_end)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, ri.ID, ri.RowID, ri.Position, ri.StitchID, ri.Count, ri.Into, ri.IsGroup, ri.ParentID, ri.GroupRepeat, ri.Note, ri.Color, ri.Consumes, ri.ToEnd); err != nil {
+		return fmt.Errorf("restore instruction: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredRowTrash purges deleted rows whose undo window has passed.
+func DeleteExpiredRowTrash(db *sql.DB) (int64, error) {
+	result, err := db.Exec(
+		"DELETE FROM row_trash WHERE expires_at <= ?",
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ReorderRows rewrites row positions in the given order.
+// orderedIDs must contain every row of the section exactly once.
+func ReorderRows(db *sql.DB, sectionID int64, orderedIDs []int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var patternID int64
+	if err := tx.QueryRow("SELECT pattern_id FROM pattern_sections WHERE id = ?", sectionID).Scan(&patternID); err != nil {
+		return fmt.Errorf("section not found: %w", err)
+	}
+
+	if err := reorderPositions(tx, "rows", "section_id = ?", []any{sectionID}, orderedIDs); err != nil {
+		return err
 	}
 
 	touchPatternUpdatedAt(tx, patternID)
@@ -545,7 +1187,7 @@ func swapRowPosition(db *sql.DB, id int64, delta int) error {
 	}
 
 	// Temporary position to avoid unique constraint.
-	if _, err := tx.Exec("UPDATE rows SET position = -1 WHERE id = ?", id); err != nil {
+	if _, err := tx.Exec("UPDATE rows SET position = -id WHERE id = ?", id); err != nil {
 		return err
 	}
 	if _, err := tx.Exec("UPDATE rows SET position = ? WHERE id = ?", position, otherID); err != nil {
@@ -574,22 +1216,145 @@ func LoadPatternFull(db *sql.DB, patternID int64) (*Pattern, []PatternSection, e
 		return nil, nil, err
 	}
 
+	if err := loadPatternRows(db, patternID, sections); err != nil {
+		return nil, nil, err
+	}
+
+	return pattern, sections, nil
+}
+
+// loadPatternRows fills in the rows of a pattern's sections, each with its instructions
+// and markers. It reads each kind once for the whole pattern rather than per row, so
+// the cost of a load doesn't grow with the pattern's length.
+func loadPatternRows(db *sql.DB, patternID int64, sections []PatternSection) error {
+	bySection := make(map[int64]*PatternSection, len(sections))
+	for i := range sections {
+		bySection[sections[i].ID] = &sections[i]
+	}
+
+	dbRows, err := db.Query(`
+		SELECT r.id, r.section_id, r.position, r.label, r.type, r.expected_stitch_count,
+		       r.turning_chain_count, r.turning_chain_counts_as_stitch, r.repeat_count, r.notes, r.count_only, r.check_previous
+		FROM rows r
+		JOIN pattern_sections ps ON r.section_id = ps.id
+		WHERE ps.pattern_id = ?
+		ORDER BY r.section_id, r.position ASC
+	`, patternID)
+	if err != nil {
+		return fmt.Errorf("list rows: %w", err)
+	}
+	defer dbRows.Close()
+	for dbRows.Next() {
+		var r Row
+		if err := dbRows.Scan(&r.ID, &r.SectionID, &r.Position, &r.Label, &r.Type,
+			&r.ExpectedStitchCount, &r.TurningChainCount, &r.TurningChainCountsAsStitch,
+			&r.RepeatCount, &r.Notes, &r.CountOnly, &r.CheckPrevious); err != nil {
+			return fmt.Errorf("scan row: %w", err)
+		}
+		if section := bySection[r.SectionID]; section != nil {
+			section.Rows = append(section.Rows, r)
+		}
+	}
+	if err := dbRows.Err(); err != nil {
+		return err
+	}
+
+	rowsByID := make(map[int64]*Row)
 	for i := range sections {
-		sectionRows, err := ListRowsBySection(db, sections[i].ID)
+		for j := range sections[i].Rows {
+			rowsByID[sections[i].Rows[j].ID] = &sections[i].Rows[j]
+		}
+	}
+
+	instrRows, err := db.Query(`
+		SELECT `+instructionSelectCols+`
+		FROM row_instructions ri
+		LEFT JOIN stitches s ON ri.stitch_id = s.id
+		JOIN rows r ON ri.row_id = r.id
+		JOIN pattern_sections ps ON r.section_id = ps.id
+		WHERE ps.pattern_id = ?
+		ORDER BY ri.parent_id IS NOT NULL, ri.row_id, ri.position
+	`, patternID)
+	if err != nil {
+		return fmt.Errorf("list instructions: %w", err)
+	}
+	defer instrRows.Close()
+	if err := attachInstructions(instrRows, rowsByID); err != nil {
+		return err
+	}
+
+	markerRows, err := db.Query(`
+		SELECT m.id, m.row_id, m.at_stitch, m.note, m.created_at
+		FROM row_markers m
+		JOIN rows r ON m.row_id = r.id
+		JOIN pattern_sections ps ON r.section_id = ps.id
+		WHERE ps.pattern_id = ?
+		ORDER BY m.row_id, m.at_stitch ASC, m.id ASC
+	`, patternID)
+	if err != nil {
+		return fmt.Errorf("list row markers: %w", err)
+	}
+	defer markerRows.Close()
+	for markerRows.Next() {
+		m, err := scanRowMarker(markerRows)
 		if err != nil {
-			return nil, nil, err
+			return err
+		}
+		if row := rowsByID[m.RowID]; row != nil {
+			row.Markers = append(row.Markers, m)
+		}
+	}
+	if err := markerRows.Err(); err != nil {
+		return err
+	}
+
+	for i := range sections {
+		resolveSectionToEnd(sections[i].Rows)
+	}
+	return nil
+}
+
+// attachInstructions adds each instruction read from instrRows to its row in rowsByID,
+// nesting children under their group. instrRows must list top-level instructions first,
+// so every group is in place before its children, each in row and position order.
+func attachInstructions(instrRows *sql.Rows, rowsByID map[int64]*Row) error {
+	type groupRef struct {
+		row   *Row
+		index int
+	}
+	groups := make(map[int64]groupRef)
+	for instrRows.Next() {
+		ri, err := scanInstruction(instrRows)
+		if err != nil {
+			return fmt.Errorf("scan instruction: %w", err)
+		}
+		row := rowsByID[ri.RowID]
+		if row == nil {
+			continue
 		}
-		for j := range sectionRows {
-			instructions, err := ListInstructionsForRow(db, sectionRows[j].ID)
-			if err != nil {
-				return nil, nil, err
+		if ri.ParentID == nil {
+			row.Instructions = append(row.Instructions, *ri)
+			if ri.IsGroup {
+				groups[ri.ID] = groupRef{row, len(row.Instructions) - 1}
 			}
-			sectionRows[j].Instructions = instructions
+		} else if g, ok := groups[*ri.ParentID]; ok {
+			g.row.Instructions[g.index].Children = append(g.row.Instructions[g.index].Children, *ri)
 		}
-		sections[i].Rows = sectionRows
 	}
+	return instrRows.Err()
+}
 
-	return pattern, sections, nil
+// resolveSectionToEnd resolves the "to end" counts of a section's loaded rows, each
+// from the row loaded before it rather than with a query per row (see ResolveToEnd).
+func resolveSectionToEnd(rows []Row) {
+	prevCount := 0
+	for i := range rows {
+		ResolveToEnd(rows[i].Instructions, prevCount, rows[i].ExpectedStitchCount)
+		prevCount = rows[i].ExpectedStitchCount
+		if prevCount == 0 {
+			prevCount = CountStitchPos(rows[i].Instructions)
+		}
+	}
 }
 
 // GetPatternIDForSection returns the pattern_id for a section, used for ownership checks.
@@ -620,68 +1385,264 @@ func RenderPatternSummary(sections []PatternSection) string {
 		if si > 0 {
 			sb.WriteString("\n")
 		}
-		sb.WriteString(section.Name)
+		writeSectionSummary(&sb, section)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// RenderSectionSummary is RenderPatternSummary for a single section.
+func RenderSectionSummary(section PatternSection) string {
+	var sb strings.Builder
+	writeSectionSummary(&sb, section)
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// RenderRowNotation is the summary line of one row of a section, labeled as it is in
+// the section, e.g. "Rnd 5: (2 sc, inc) x6 [18]". It returns "" if the row isn't in
+// the section.
+func RenderRowNotation(section PatternSection, rowID int64) string {
+	lines := sectionNotationLines(section)
+	for i, row := range section.Rows {
+		if row.ID == rowID {
+			return lines[i]
+		}
+	}
+	return ""
+}
+
+func writeSectionSummary(sb *strings.Builder, section PatternSection) {
+	sb.WriteString(section.Name)
+	sb.WriteString("\n")
+
+	for _, line := range sectionNotationLines(section) {
+		sb.WriteString("  ")
+		sb.WriteString(line)
 		sb.WriteString("\n")
+	}
+
+	if section.Notes != "" {
+		sb.WriteString("  Note: ")
+		sb.WriteString(section.Notes)
+		sb.WriteString("\n")
+	}
+}
+
+// DefaultStitchesPerMinute is the working speed assumed for time estimates.
+const DefaultStitchesPerMinute = 20
+
+// TotalPatternStitches counts every stitch in the pattern, expanding groups and row
+// repeats the same way work mode does. Count-only rows contribute their expected
+// stitch count. It works off already-loaded sections and never touches the database.
+func TotalPatternStitches(sections []PatternSection) int {
+	total := 0
+	for _, section := range sections {
+		total += SectionStitchTotal(section)
+	}
+	return total
+}
+
+// SectionStitchTotal counts the stitches in one section the way TotalPatternStitches
+// counts the whole pattern: each row's stitches times its repeat count.
+func SectionStitchTotal(section PatternSection) int {
+	total := 0
+	for _, row := range section.Rows {
+		total += rowStitchTotal(row) * row.RepeatCount
+	}
+	return total
+}
+
+// rowStitchTotal is the number of stitches in one repeat of a row.
+func rowStitchTotal(row Row) int {
+	if row.CountOnly {
+		return row.ExpectedStitchCount
+	}
+	return CountStitchPos(row.Instructions)
+}
+
+// ConsumptionIssue is a row that works into a different number of stitches than the
+// row before it produced.
+type ConsumptionIssue struct {
+	SectionName string
+	RowLabel    string
+	WorksInto   int // BaseStitches summed over the row's instructions
+	Previous    int // the stitch count being worked into
+	Repeat      bool
+}
 
-		// Track running row number for auto-labeling (resets per section).
-		rowNum := 1
-		for _, row := range section.Rows {
-			label := row.Label
-			if label == "" {
-				prefix := "Row"
-				if row.Type == "joined_round" || row.Type == "continuous_round" {
-					prefix = "Rnd"
-				}
-				if row.RepeatCount > 1 {
-					label = fmt.Sprintf("%ss %d-%d", prefix, rowNum, rowNum+row.RepeatCount-1)
-				} else {
-					label = fmt.Sprintf("%s %d", prefix, rowNum)
-				}
+// Message describes the mismatch for the pattern summary.
+func (c ConsumptionIssue) Message() string {
+	if c.Repeat {
+		return fmt.Sprintf("%s, %s: each repeat works into %d stitches but the row makes %d.",
+			c.SectionName, c.RowLabel, c.WorksInto, c.Previous)
+	}
+	return fmt.Sprintf("%s, %s: works into %d stitches but the previous row has %d.",
+		c.SectionName, c.RowLabel, c.WorksInto, c.Previous)
+}
+
+// CheckRowConsumption compares each opted-in row's instructions against the
+// stitch count of the row before it in the same section. A repeated row is also
+// checked against itself, since every repeat after the first works into the last.
+// Count-only rows, rows without instructions and the first row of a section are
+// skipped.
+func CheckRowConsumption(sections []PatternSection) []ConsumptionIssue {
+	var issues []ConsumptionIssue
+	for _, section := range sections {
+		for i, row := range section.Rows {
+			if !row.CheckPrevious || row.CountOnly || len(row.Instructions) == 0 || i == 0 {
+				continue
+			}
+			worksInto := 0
+			for _, ri := range row.Instructions {
+				worksInto += ri.BaseStitches()
 			}
-			rowNum += row.RepeatCount
+			issue := ConsumptionIssue{
+				SectionName: section.Name,
+				RowLabel:    computeRowLabel(section.Rows, row.ID),
+				WorksInto:   worksInto,
+			}
+			if prev := section.Rows[i-1].ExpectedStitchCount; worksInto != prev {
+				issue.Previous = prev
+				issues = append(issues, issue)
+			} else if row.RepeatCount > 1 && worksInto != row.ExpectedStitchCount {
+				issue.Previous = row.ExpectedStitchCount
+				issue.Repeat = true
+				issues = append(issues, issue)
+			}
+		}
+	}
+	return issues
+}
+
+// PatternStitchTotals returns TotalPatternStitches for each of the user's patterns,
+// keyed by pattern ID. Rather than LoadPatternFull every pattern, it loads the rows
+// and instructions of all of them in two queries, then counts them the same way.
+func PatternStitchTotals(db *sql.DB, userID int64) (map[int64]int, error) {
+	dbRows, err := db.Query(`
+		SELECT ps.pattern_id, r.id, r.section_id, r.expected_stitch_count, r.repeat_count, r.count_only
+		FROM rows r
+		JOIN pattern_sections ps ON r.section_id = ps.id
+		JOIN patterns p ON ps.pattern_id = p.id
+		WHERE p.user_id = ?
+		ORDER BY ps.pattern_id, ps.position, r.position
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("pattern stitch totals: %w", err)
+	}
+	defer dbRows.Close()
+
+	patterns := make(map[int64][]PatternSection)
+	for dbRows.Next() {
+		var patternID int64
+		var r Row
+		if err := dbRows.Scan(&patternID, &r.ID, &r.SectionID, &r.ExpectedStitchCount, &r.RepeatCount, &r.CountOnly); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		sections := patterns[patternID]
+		if n := len(sections); n == 0 || sections[n-1].ID != r.SectionID {
+			sections = append(sections, PatternSection{ID: r.SectionID, PatternID: patternID})
+		}
+		last := &sections[len(sections)-1]
+		last.Rows = append(last.Rows, r)
+		patterns[patternID] = sections
+	}
+	if err := dbRows.Err(); err != nil {
+		return nil, err
+	}
 
-			sb.WriteString("  ")
-			sb.WriteString(label)
-			sb.WriteString(": ")
+	rowsByID := make(map[int64]*Row)
+	for _, sections := range patterns {
+		for i := range sections {
+			for j := range sections[i].Rows {
+				rowsByID[sections[i].Rows[j].ID] = &sections[i].Rows[j]
+			}
+		}
+	}
 
-			instrText := renderInstructions(row.Instructions)
-			sb.WriteString(instrText)
+	// Top-level instructions come first so every group is in place before its children.
+	instrRows, err := db.Query(`
+		SELECT `+instructionSelectCols+`
+		FROM row_instructions ri
+		LEFT JOIN stitches s ON ri.stitch_id = s.id
+		JOIN rows r ON ri.row_id = r.id
+		JOIN pattern_sections ps ON r.section_id = ps.id
+		JOIN patterns p ON ps.pattern_id = p.id
+		WHERE p.user_id = ?
+		ORDER BY ri.parent_id IS NOT NULL, ri.row_id, ri.position
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("pattern stitch totals: %w", err)
+	}
+	defer instrRows.Close()
+	if err := attachInstructions(instrRows, rowsByID); err != nil {
+		return nil, err
+	}
 
-			// Append stitch count.
-			sb.WriteString(fmt.Sprintf(" [%d]", row.ExpectedStitchCount))
+	totals := make(map[int64]int, len(patterns))
+	for patternID, sections := range patterns {
+		for i := range sections {
+			resolveSectionToEnd(sections[i].Rows)
+		}
+		totals[patternID] = TotalPatternStitches(sections)
+	}
+	return totals, nil
+}
 
-			if row.Notes != "" {
-				sb.WriteString(" (")
-				sb.WriteString(row.Notes)
-				sb.WriteString(")")
+// sectionNotationLines renders each row of a section as one line of pattern notation,
+// e.g. "Rnd 3: (sc, inc) x6 [18] (notes)". Shared by the text preview and the PDF export.
+func sectionNotationLines(section PatternSection) []string {
+	lines := make([]string, 0, len(section.Rows))
+
+	// Track running row number for auto-labeling (resets per section).
+	rowNum := 1
+	for _, row := range section.Rows {
+		label := row.Label
+		if label == "" {
+			prefix := "Row"
+			if row.Type == "joined_round" || row.Type == "continuous_round" {
+				prefix = "Rnd"
+			}
+			if row.RepeatCount > 1 {
+				label = fmt.Sprintf("%ss %d-%d", prefix, rowNum, rowNum+row.RepeatCount-1)
+			} else {
+				label = fmt.Sprintf("%s %d", prefix, rowNum)
 			}
-			sb.WriteString("\n")
 		}
+		rowNum += row.RepeatCount
 
-		if section.Notes != "" {
-			sb.WriteString("  Note: ")
-			sb.WriteString(section.Notes)
-			sb.WriteString("\n")
+		line := label + ": " + RenderInstructions(row.Instructions)
+
+		// Append stitch count.
+		line += fmt.Sprintf(" [%d]", row.ExpectedStitchCount)
+
+		if row.Notes != "" {
+			line += " (" + row.Notes + ")"
 		}
+		lines = append(lines, line)
 	}
-	return strings.TrimRight(sb.String(), "\n")
+	return lines
+}
+
+// RenderInstructions converts a flat+nested instruction list to crochet notation text.
+func RenderInstructions(instructions []RowInstruction) string {
+	return renderInstructions(instructions, false)
 }
 
-// renderInstructions converts a flat+nested instruction list to crochet notation text.
-func renderInstructions(instructions []RowInstruction) string {
+// renderInstructions is RenderInstructions, naming stitches by chart symbol where
+// they have one if symbols is set.
+func renderInstructions(instructions []RowInstruction, symbols bool) string {
 	parts := make([]string, 0, len(instructions))
 	for _, ri := range instructions {
-		parts = append(parts, renderInstruction(ri))
+		parts = append(parts, renderInstruction(ri, symbols))
 	}
 	return strings.Join(parts, ", ")
 }
 
 // renderInstruction converts a single instruction (possibly a group) to crochet notation.
-func renderInstruction(ri RowInstruction) string {
+func renderInstruction(ri RowInstruction, symbols bool) string {
 	if ri.IsGroup {
 		childParts := make([]string, 0, len(ri.Children))
 		for _, ch := range ri.Children {
-			childParts = append(childParts, renderInstruction(ch))
+			childParts = append(childParts, renderInstruction(ch, symbols))
 		}
 		inner := strings.Join(childParts, ", ")
 		if ri.GroupRepeat > 1 {
@@ -690,15 +1651,18 @@ func renderInstruction(ri RowInstruction) string {
 		return fmt.Sprintf("(%s)", inner)
 	}
 
-	abbr := ri.StitchAbbr
+	abbr := ri.StitchLabel(symbols)
 	if abbr == "" {
 		abbr = "?"
 	}
 
 	var sb strings.Builder
-	if ri.Count > 1 {
+	switch {
+	case ri.ToEnd:
+		sb.WriteString(abbr + " to end")
+	case ri.Count > 1:
 		sb.WriteString(fmt.Sprintf("%s %d", abbr, ri.Count))
-	} else {
+	default:
 		sb.WriteString(abbr)
 	}
 
@@ -0,0 +1,345 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// BenchmarkListPatternsByUser lists a library of 400 patterns with 4 sections of
+// 20 rows each, the size the dashboard is expected to stay fast at.
+func BenchmarkListPatternsByUser(b *testing.B) {
+	db := newTestDB(b)
+	user := newTestUser(b, db, "a@b.com")
+	tx, err := db.Begin()
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := range 400 {
+		res, err := tx.Exec("INSERT INTO patterns (user_id, name) VALUES (?, ?)", user.ID, fmt.Sprintf("Pattern %d", i))
+		if err != nil {
+			b.Fatal(err)
+		}
+		patternID, _ := res.LastInsertId()
+		for s := range 4 {
+			res, err := tx.Exec("INSERT INTO pattern_sections (pattern_id, name, position) VALUES (?, '', ?)", patternID, s+1)
+			if err != nil {
+				b.Fatal(err)
+			}
+			sectionID, _ := res.LastInsertId()
+			for r := range 20 {
+				if _, err := tx.Exec("INSERT INTO rows (section_id, position, type, expected_stitch_count) VALUES (?, ?, 'row', 0)", sectionID, r+1); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for b.Loop() {
+		patterns, err := ListPatternsByUser(db, user.ID)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(patterns) != 400 || patterns[0].RowCount != 80 {
+			b.Fatalf("listed %d patterns", len(patterns))
+		}
+	}
+}
+
+func TestPatternStitchTotalsMatchesLoadedPattern(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "a@b.com")
+	sc := builtinStitchID(t, db, "sc")
+	inc := builtinStitchID(t, db, "inc")
+
+	for _, name := range []string{"Hat", "Scarf", "Empty"} {
+		pattern, err := CreatePattern(db, user.ID, name, "", PatternMeta{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name == "Empty" {
+			continue
+		}
+		section, err := CreateSection(db, pattern.ID, "Body")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Rnd 1: sc 12.
+		r1 := mustRow(t, db, section.ID, 12)
+		mustInstruction(t, db, r1.ID, sc, 12)
+		// Rnd 2: (sc, inc) x3, sc to end, twice.
+		r2, err := CreateRow(db, section.ID, "", "row", 12, 0, false, 2, "", false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		group, err := CreateGroupInstruction(db, r2.ID, 3, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := CreateChildInstruction(db, group.ID, sc, 1, "", "", "", nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := CreateChildInstruction(db, group.ID, inc, 1, "", "", "", nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := CreateToEndInstruction(db, r2.ID, sc, "", "", ""); err != nil {
+			t.Fatal(err)
+		}
+		// A count-only row.
+		if _, err := CreateRow(db, section.ID, "", "row", 12, 0, false, 3, "", true, false); err != nil {
+			t.Fatal(err)
+		}
+		if name == "Scarf" {
+			second, err := CreateSection(db, pattern.ID, "Edging")
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := mustRow(t, db, second.ID, 4)
+			mustInstruction(t, db, r.ID, sc, 4)
+		}
+	}
+
+	totals, err := PatternStitchTotals(db, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patterns, err := ListPatternsByUser(db, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range patterns {
+		_, sections, err := LoadPatternFull(db, p.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := TotalPatternStitches(sections); totals[p.ID] != want {
+			t.Errorf("%s: PatternStitchTotals = %d, TotalPatternStitches = %d", p.Name, totals[p.ID], want)
+		}
+	}
+	// Hat: 12, then ((sc, inc) x3 and sc 6 to end) x2, then 12 x3.
+	for _, p := range patterns {
+		if p.Name == "Hat" && totals[p.ID] != 12+12*2+12*3 {
+			t.Errorf("Hat totals %d stitches, want 72", totals[p.ID])
+		}
+	}
+}
+
+func TestHugeRepeatsAreRejected(t *testing.T) {
+	db, row := testRowFor(t)
+	sc := builtinStitchID(t, db, "sc")
+	huge := 1_000_000_000
+
+	if _, err := CreateRow(db, row.SectionID, "", "row", 6, 0, false, huge, "", false, false); !errors.Is(err, ErrCountTooLarge) {
+		t.Errorf("CreateRow with a huge repeat: got %v", err)
+	}
+	if err := UpdateRow(db, row.ID, "", "row", 6, 0, false, huge, "", false, false); !errors.Is(err, ErrCountTooLarge) {
+		t.Errorf("UpdateRow with a huge repeat: got %v", err)
+	}
+	if _, err := CreateGroupInstruction(db, row.ID, huge, "", ""); !errors.Is(err, ErrCountTooLarge) {
+		t.Errorf("CreateGroupInstruction with a huge repeat: got %v", err)
+	}
+	if _, err := CreateInstruction(db, row.ID, sc, huge, "", "", "", nil); !errors.Is(err, ErrCountTooLarge) {
+		t.Errorf("CreateInstruction with a huge count: got %v", err)
+	}
+	group, err := CreateGroupInstruction(db, row.ID, MaxGroupRepeat, "", "")
+	if err != nil {
+		t.Fatalf("group at the repeat limit: %v", err)
+	}
+	if err := UpdateGroupInstruction(db, group.ID, MaxGroupRepeat+1, "", ""); !errors.Is(err, ErrCountTooLarge) {
+		t.Errorf("UpdateGroupInstruction past the limit: got %v", err)
+	}
+
+	parsed, err := ParsePatternText("Rows 1-1000000: sc 6\nRnd 2: (sc, inc) x1000000\nRnd 3: sc 6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Skipped) != 2 {
+		t.Errorf("text import skipped %d lines, want the two huge repeats", len(parsed.Skipped))
+	}
+
+	msg := ErrCountTooLarge.Error()
+	for _, want := range []string{"group repeats", strconv.Itoa(MaxGroupRepeat), strconv.Itoa(MaxRowRepeat), strconv.Itoa(MaxInstructionCount)} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("ErrCountTooLarge %q does not mention %q", msg, want)
+		}
+	}
+}
+
+func TestLoadPatternFullMatchesPerRowQueries(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "a@b.com")
+	sc := builtinStitchID(t, db, "sc")
+	inc := builtinStitchID(t, db, "inc")
+
+	pattern, err := CreatePattern(db, user.ID, "Hat", "", PatternMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Crown", "Body"} {
+		section, err := CreateSection(db, pattern.ID, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r1 := mustRow(t, db, section.ID, 6)
+		mustInstruction(t, db, r1.ID, sc, 6)
+		if _, err := CreateRowMarker(db, r1.ID, 6, "join"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := CreateRowMarker(db, r1.ID, 1, "start"); err != nil {
+			t.Fatal(err)
+		}
+		r2 := mustRow(t, db, section.ID, 12)
+		group, err := CreateGroupInstruction(db, r2.ID, 2, "", "Rust")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := CreateChildInstruction(db, group.ID, inc, 1, "", "", "", nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := CreateChildInstruction(db, group.ID, sc, 1, "", "", "", nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := CreateToEndInstruction(db, r2.ID, sc, "", "", ""); err != nil {
+			t.Fatal(err)
+		}
+		mustRow(t, db, section.ID, 0)
+	}
+
+	_, sections, err := LoadPatternFull(db, pattern.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, section := range sections {
+		rows, err := ListRowsBySection(db, section.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != len(section.Rows) {
+			t.Fatalf("section %s: loaded %d rows, want %d", section.Name, len(section.Rows), len(rows))
+		}
+		for i := range rows {
+			if rows[i].Instructions, err = ListInstructionsForRow(db, rows[i].ID); err != nil {
+				t.Fatal(err)
+			}
+			if rows[i].Markers, err = ListRowMarkers(db, rows[i].ID); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(section.Rows[i], rows[i]) {
+				t.Errorf("section %s row %d:\nloaded %+v\nwant   %+v", section.Name, i, section.Rows[i], rows[i])
+			}
+		}
+	}
+}
+
+func TestRowStitchCountsForTheNextRow(t *testing.T) {
+	db, first := testRowFor(t)
+	sc := builtinStitchID(t, db, "sc")
+	if n, err := LastRowStitchCount(db, first.SectionID+1000); err != nil || n != 0 {
+		t.Fatalf("LastRowStitchCount of a missing section = %d, %v", n, err)
+	}
+
+	// No expected count: the stitches the instructions make.
+	if _, err := db.Exec("UPDATE rows SET expected_stitch_count = 0 WHERE id = ?", first.ID); err != nil {
+		t.Fatal(err)
+	}
+	mustInstruction(t, db, first.ID, sc, 9)
+	second := mustRow(t, db, first.SectionID, 48)
+
+	tests := []struct {
+		name string
+		got  func() (int, error)
+		want int
+	}{
+		{"previous of the first row", func() (int, error) { return PreviousRowStitchCount(db, first.ID) }, 0},
+		{"previous of the second row", func() (int, error) { return PreviousRowStitchCount(db, second.ID) }, 9},
+		{"last row of the section", func() (int, error) { return LastRowStitchCount(db, first.SectionID) }, 48},
+	}
+	for _, tt := range tests {
+		if n, err := tt.got(); err != nil || n != tt.want {
+			t.Errorf("%s = %d, %v; want %d", tt.name, n, err, tt.want)
+		}
+	}
+}
+
+func TestRestoreRowBringsBackTheStoredInstructions(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "a@b.com")
+	sc := builtinStitchID(t, db, "sc")
+	bobble, err := CreateStitch(db, user.ID, "Bobble", "bob", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pattern, err := CreatePattern(db, user.ID, "Hat", "", PatternMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	section, err := CreateSection(db, pattern.ID, "Body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// No expected count, so the next row counts this one's stitches.
+	first := mustRow(t, db, section.ID, 0)
+	mustInstruction(t, db, first.ID, sc, 10)
+
+	// (bob, sc) twice, then "sc to end" over the 6 left.
+	row := mustRow(t, db, section.ID, 10)
+	group, err := CreateGroupInstruction(db, row.ID, 2, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateChildInstruction(db, group.ID, &bobble.ID, 1, "", "", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateChildInstruction(db, group.ID, sc, 1, "", "", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateToEndInstruction(db, row.ID, sc, "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := listRowInstructionTree(db, row.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := DeleteRow(db, row.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The previous row grows while this one is in the trash; "to end" follows it.
+	mustInstruction(t, db, first.ID, sc, 2)
+	if _, err := RestoreRow(db, token, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := listRowInstructionTree(db, row.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restored, stored) {
+		t.Fatalf("restored\n%+v\nwant\n%+v", restored, stored)
+	}
+	instrs, err := ListInstructionsForRow(db, row.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if toEnd := instrs[len(instrs)-1]; !toEnd.ToEnd || toEnd.Count != 8 {
+		t.Fatalf("restored to end: to end %v, count %d; want true, 8", toEnd.ToEnd, toEnd.Count)
+	}
+
+	// Once the row is gone its stitch is no longer in use and can be deleted too.
+	if token, err = DeleteRow(db, row.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteStitch(db, bobble.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RestoreRow(db, token, user.ID); !errors.Is(err, ErrRowStitchDeleted) {
+		t.Fatalf("restore without its stitch: got %v, want %v", err, ErrRowStitchDeleted)
+	}
+}
@@ -0,0 +1,185 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// PatternJSON is a whole pattern as the JSON API serves it and as a backup zip
+// stores it. It is kept separate from the model structs so that schema changes
+// don't leak into clients or old backups.
+type PatternJSON struct {
+	ID            int64         `json:"id"`
+	Name          string        `json:"name"`
+	Description   string        `json:"description"`
+	GaugeStitches int           `json:"gauge_stitches"`
+	GaugeRows     int           `json:"gauge_rows"`
+	Difficulty    string        `json:"difficulty"`
+	HookSize      string        `json:"hook_size"`
+	YarnWeight    string        `json:"yarn_weight"`
+	FinishedSize  string        `json:"finished_size"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	Sections      []SectionJSON `json:"sections"`
+}
+
+type SectionJSON struct {
+	ID       int64     `json:"id"`
+	Position int       `json:"position"`
+	Name     string    `json:"name"`
+	Notes    string    `json:"notes"`
+	Rows     []RowJSON `json:"rows"`
+}
+
+type RowJSON struct {
+	ID                         int64             `json:"id"`
+	Position                   int               `json:"position"`
+	Label                      string            `json:"label"`
+	Type                       string            `json:"type"`
+	ExpectedStitchCount        int               `json:"expected_stitch_count"`
+	TurningChainCount          int               `json:"turning_chain_count"`
+	TurningChainCountsAsStitch bool              `json:"turning_chain_counts_as_stitch"`
+	RepeatCount                int               `json:"repeat_count"`
+	Notes                      string            `json:"notes"`
+	CountOnly                  bool              `json:"count_only"`
+	CheckPrevious              bool              `json:"check_previous"`
+	Instructions               []InstructionJSON `json:"instructions"`
+}
+
+type InstructionJSON struct {
+	ID          int64             `json:"id"`
+	Position    int               `json:"position"`
+	StitchID    *int64            `json:"stitch_id"`
+	StitchAbbr  string            `json:"stitch_abbr,omitempty"`
+	StitchName  string            `json:"stitch_name,omitempty"`
+	Count       int               `json:"count"`
+	Into        string            `json:"into"`
+	IsGroup     bool              `json:"is_group"`
+	GroupRepeat int               `json:"group_repeat,omitempty"`
+	Note        string            `json:"note"`
+	Color       string            `json:"color,omitempty"`
+	Consumes    *int              `json:"consumes,omitempty"`
+	ToEnd       bool              `json:"to_end,omitempty"`
+	Children    []InstructionJSON `json:"children,omitempty"`
+}
+
+// NewPatternJSON converts a pattern loaded by LoadPatternFull.
+func NewPatternJSON(p *Pattern, sections []PatternSection) PatternJSON {
+	out := PatternJSON{
+		ID:            p.ID,
+		Name:          p.Name,
+		Description:   p.Description,
+		GaugeStitches: p.GaugeStitches,
+		GaugeRows:     p.GaugeRows,
+		Difficulty:    p.Meta.Difficulty,
+		HookSize:      p.Meta.HookSize,
+		YarnWeight:    p.Meta.YarnWeight,
+		FinishedSize:  p.Meta.FinishedSize,
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.UpdatedAt,
+		Sections:      []SectionJSON{},
+	}
+	for _, s := range sections {
+		sj := SectionJSON{ID: s.ID, Position: s.Position, Name: s.Name, Notes: s.Notes, Rows: []RowJSON{}}
+		for _, r := range s.Rows {
+			sj.Rows = append(sj.Rows, RowJSON{
+				ID:                         r.ID,
+				Position:                   r.Position,
+				Label:                      r.Label,
+				Type:                       r.Type,
+				ExpectedStitchCount:        r.ExpectedStitchCount,
+				TurningChainCount:          r.TurningChainCount,
+				TurningChainCountsAsStitch: r.TurningChainCountsAsStitch,
+				RepeatCount:                r.RepeatCount,
+				Notes:                      r.Notes,
+				CountOnly:                  r.CountOnly,
+				CheckPrevious:              r.CheckPrevious,
+				Instructions:               newInstructionsJSON(r.Instructions),
+			})
+		}
+		out.Sections = append(out.Sections, sj)
+	}
+	return out
+}
+
+func newInstructionsJSON(instructions []RowInstruction) []InstructionJSON {
+	out := []InstructionJSON{}
+	for _, ri := range instructions {
+		ij := InstructionJSON{
+			ID:         ri.ID,
+			Position:   ri.Position,
+			StitchID:   ri.StitchID,
+			StitchAbbr: ri.StitchAbbr,
+			StitchName: ri.StitchName,
+			Count:      ri.Count,
+			Into:       ri.Into,
+			IsGroup:    ri.IsGroup,
+			Note:       ri.Note,
+			Color:      ri.Color,
+			Consumes:   ri.Consumes,
+			ToEnd:      ri.ToEnd,
+		}
+		if ri.IsGroup {
+			ij.GroupRepeat = ri.GroupRepeat
+			ij.Children = newInstructionsJSON(ri.Children)
+		}
+		out = append(out, ij)
+	}
+	return out
+}
+
+// Unpack converts an exported pattern back into model types for ImportPattern.
+func (pj PatternJSON) Unpack() (*Pattern, []PatternSection) {
+	pattern := &Pattern{
+		Name:          pj.Name,
+		Description:   strings.TrimSpace(pj.Description),
+		GaugeStitches: pj.GaugeStitches,
+		GaugeRows:     pj.GaugeRows,
+		Meta: PatternMeta{
+			Difficulty:   pj.Difficulty,
+			HookSize:     pj.HookSize,
+			YarnWeight:   pj.YarnWeight,
+			FinishedSize: pj.FinishedSize,
+		},
+	}
+	var sections []PatternSection
+	for _, sj := range pj.Sections {
+		section := PatternSection{Name: sj.Name, Notes: sj.Notes}
+		for _, rj := range sj.Rows {
+			section.Rows = append(section.Rows, Row{
+				Label:                      rj.Label,
+				Type:                       rj.Type,
+				ExpectedStitchCount:        rj.ExpectedStitchCount,
+				TurningChainCount:          rj.TurningChainCount,
+				TurningChainCountsAsStitch: rj.TurningChainCountsAsStitch,
+				RepeatCount:                rj.RepeatCount,
+				Notes:                      rj.Notes,
+				CountOnly:                  rj.CountOnly,
+				CheckPrevious:              rj.CheckPrevious,
+				Instructions:               unpackInstructions(rj.Instructions),
+			})
+		}
+		sections = append(sections, section)
+	}
+	return pattern, sections
+}
+
+func unpackInstructions(instructions []InstructionJSON) []RowInstruction {
+	var out []RowInstruction
+	for _, ij := range instructions {
+		out = append(out, RowInstruction{
+			StitchAbbr:  ij.StitchAbbr,
+			StitchName:  ij.StitchName,
+			Count:       ij.Count,
+			Into:        ij.Into,
+			IsGroup:     ij.IsGroup,
+			GroupRepeat: ij.GroupRepeat,
+			Note:        ij.Note,
+			Color:       ij.Color,
+			Consumes:    ij.Consumes,
+			ToEnd:       ij.ToEnd,
+			Children:    unpackInstructions(ij.Children),
+		})
+	}
+	return out
+}
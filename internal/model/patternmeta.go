@@ -0,0 +1,76 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Difficulty levels for PatternMeta.Difficulty.
+const (
+	DifficultyBeginner     = "beginner"
+	DifficultyIntermediate = "intermediate"
+	DifficultyAdvanced     = "advanced"
+)
+
+// ValidDifficulties lists the accepted difficulty levels, easiest first.
+var ValidDifficulties = []string{DifficultyBeginner, DifficultyIntermediate, DifficultyAdvanced}
+
+// MaxPatternMetaLength caps the free-text pattern details.
+const MaxPatternMetaLength = 60
+
+// ErrInvalidPatternMeta is returned for an unknown difficulty or an over-long detail.
+var ErrInvalidPatternMeta = errors.New("invalid pattern details")
+
+// PatternMeta holds optional details about a pattern. Every field may be empty.
+type PatternMeta struct {
+	Difficulty   string // one of ValidDifficulties, or ""
+	HookSize     string // e.g. "4 mm" or "G-6"
+	YarnWeight   string // e.g. "worsted"
+	FinishedSize string // e.g. "20 cm tall"
+}
+
+// Normalize trims the fields and checks them, returning ErrInvalidPatternMeta if a
+// difficulty is set but unknown or a field is longer than MaxPatternMetaLength.
+func (m *PatternMeta) Normalize() error {
+	m.Difficulty = strings.ToLower(strings.TrimSpace(m.Difficulty))
+	m.HookSize = strings.TrimSpace(m.HookSize)
+	m.YarnWeight = strings.TrimSpace(m.YarnWeight)
+	m.FinishedSize = strings.TrimSpace(m.FinishedSize)
+
+	if m.Difficulty != "" && !slices.Contains(ValidDifficulties, m.Difficulty) {
+		return ErrInvalidPatternMeta
+	}
+	for _, f := range []string{m.HookSize, m.YarnWeight, m.FinishedSize} {
+		if len(f) > MaxPatternMetaLength {
+			return ErrInvalidPatternMeta
+		}
+	}
+	return nil
+}
+
+// DifficultyLabel capitalizes a difficulty level for display.
+func DifficultyLabel(d string) string {
+	if d == "" {
+		return ""
+	}
+	return strings.ToUpper(d[:1]) + d[1:]
+}
+
+// Lines describes the details that are set, e.g. "Hook: 4 mm", in display order.
+func (m PatternMeta) Lines() []string {
+	var lines []string
+	if m.Difficulty != "" {
+		lines = append(lines, "Difficulty: "+DifficultyLabel(m.Difficulty))
+	}
+	if m.HookSize != "" {
+		lines = append(lines, "Hook: "+m.HookSize)
+	}
+	if m.YarnWeight != "" {
+		lines = append(lines, "Yarn: "+m.YarnWeight)
+	}
+	if m.FinishedSize != "" {
+		lines = append(lines, "Finished size: "+m.FinishedSize)
+	}
+	return lines
+}
@@ -0,0 +1,108 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Work mode stitch counter directions.
+const (
+	CountUp   = "up"   // stitches completed out of the row total
+	CountDown = "down" // stitches left in the row
+)
+
+// Color themes. ThemeSystem follows the browser's light/dark setting.
+const (
+	ThemeSystem = "system"
+	ThemeLight  = "light"
+	ThemeDark   = "dark"
+)
+
+// Prefs holds a user's preferences. They are stored as JSON, so adding one only takes
+// a field here, a default in DefaultPrefs and a check in Validate — no migration.
+type Prefs struct {
+	CountDirection string `json:"count_direction"`  // CountUp or CountDown
+	DefaultRowType string `json:"default_row_type"` // preselected in the add row form
+	Theme          string `json:"theme"`            // ThemeSystem, ThemeLight or ThemeDark
+	ShowSymbols    bool   `json:"show_symbols"`     // show stitches by chart symbol where they have one
+}
+
+// DefaultPrefs returns the preferences of a user who hasn't changed anything.
+func DefaultPrefs() Prefs {
+	return Prefs{
+		CountDirection: CountUp,
+		DefaultRowType: "continuous_round",
+		Theme:          ThemeSystem,
+	}
+}
+
+// ErrInvalidPrefs is returned by SavePreferences when a preference has an unknown value.
+var ErrInvalidPrefs = errors.New("invalid preferences")
+
+func isValidCountDirection(d string) bool { return d == CountUp || d == CountDown }
+
+func isValidTheme(t string) bool { return t == ThemeSystem || t == ThemeLight || t == ThemeDark }
+
+// Validate checks every preference has a known value.
+func (p Prefs) Validate() error {
+	switch {
+	case !isValidCountDirection(p.CountDirection):
+		return fmt.Errorf("%w: count direction %q", ErrInvalidPrefs, p.CountDirection)
+	case !IsValidRowType(p.DefaultRowType):
+		return fmt.Errorf("%w: row type %q", ErrInvalidPrefs, p.DefaultRowType)
+	case !isValidTheme(p.Theme):
+		return fmt.Errorf("%w: theme %q", ErrInvalidPrefs, p.Theme)
+	}
+	return nil
+}
+
+// GetPreferences loads the user's preferences. Anything missing or no longer valid
+// falls back to its default, so callers always get usable values.
+func GetPreferences(db *sql.DB, userID int64) (Prefs, error) {
+	prefs := DefaultPrefs()
+	var data string
+	err := db.QueryRow("SELECT data FROM user_preferences WHERE user_id = ?", userID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return prefs, nil
+	}
+	if err != nil {
+		return prefs, fmt.Errorf("get preferences: %w", err)
+	}
+
+	var stored Prefs
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return prefs, fmt.Errorf("read preferences: %w", err)
+	}
+	// Take each stored value on its own, so one bad value doesn't reset the rest.
+	if isValidCountDirection(stored.CountDirection) {
+		prefs.CountDirection = stored.CountDirection
+	}
+	if IsValidRowType(stored.DefaultRowType) {
+		prefs.DefaultRowType = stored.DefaultRowType
+	}
+	if isValidTheme(stored.Theme) {
+		prefs.Theme = stored.Theme
+	}
+	prefs.ShowSymbols = stored.ShowSymbols
+	return prefs, nil
+}
+
+// SavePreferences replaces the user's preferences.
+func SavePreferences(db *sql.DB, userID int64, prefs Prefs) error {
+	if err := prefs.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return fmt.Errorf("encode preferences: %w", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO user_preferences (user_id, data) VALUES (?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET data = excluded.data
+	`, userID, string(data)); err != nil {
+		return fmt.Errorf("save preferences: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ShareLink grants read-only, unauthenticated access to a pattern via /shared/{token}.
+// Unlike session and reset tokens the token is kept in plaintext so the owner can copy
+// the link again later; revoking it is the way to cut off access.
+type ShareLink struct {
+	Token     string
+	PatternID int64
+	CreatedAt time.Time
+}
+
+// CreateShareLink issues a new share token for a pattern the user owns.
+func CreateShareLink(db *sql.DB, patternID, userID int64) (*ShareLink, error) {
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO share_tokens (token, pattern_id)
+		SELECT ?, id FROM patterns WHERE id = ? AND user_id = ?
+	`, token, patternID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("insert share token: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("pattern not found or not owned by user")
+	}
+
+	return &ShareLink{Token: token, PatternID: patternID, CreatedAt: time.Now().UTC()}, nil
+}
+
+// ListShareLinks returns a pattern's active share links, oldest first.
+func ListShareLinks(db *sql.DB, patternID int64) ([]ShareLink, error) {
+	rows, err := db.Query(`
+		SELECT token, pattern_id, created_at FROM share_tokens
+		WHERE pattern_id = ?
+		ORDER BY created_at, rowid
+	`, patternID)
+	if err != nil {
+		return nil, fmt.Errorf("list share links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []ShareLink
+	for rows.Next() {
+		var l ShareLink
+		var createdAt string
+		if err := rows.Scan(&l.Token, &l.PatternID, &createdAt); err != nil {
+			return nil, fmt.Errorf("scan share link: %w", err)
+		}
+		l.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// FindPatternByShareToken returns the pattern a share token points to.
+// Returns sql.ErrNoRows for unknown or revoked tokens.
+func FindPatternByShareToken(db *sql.DB, token string) (*Pattern, error) {
+	var patternID int64
+	if err := db.QueryRow("SELECT pattern_id FROM share_tokens WHERE token = ?", token).Scan(&patternID); err != nil {
+		return nil, err
+	}
+	return FindPatternByID(db, patternID)
+}
+
+// DeleteShareLink revokes a share token on one of the user's patterns.
+func DeleteShareLink(db *sql.DB, token string, userID int64) error {
+	result, err := db.Exec(`
+		DELETE FROM share_tokens
+		WHERE token = ? AND pattern_id IN (SELECT id FROM patterns WHERE user_id = ?)
+	`, token, userID)
+	if err != nil {
+		return fmt.Errorf("delete share link: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
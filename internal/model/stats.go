@@ -0,0 +1,59 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Stats summarizes a user's activity for the dashboard.
+type Stats struct {
+	Patterns           int
+	Rows               int // rows/rounds across all patterns, not counting repeats
+	ActiveSessions     int
+	CompletedThisMonth int // sessions completed since the start of the current month (UTC)
+}
+
+// UserStats computes the dashboard stats in a single query.
+func UserStats(db *sql.DB, userID int64) (Stats, error) {
+	var s Stats
+	err := db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM patterns WHERE user_id = ?1),
+			(SELECT COUNT(*) FROM rows r
+			   JOIN pattern_sections ps ON ps.id = r.section_id
+			   JOIN patterns p ON p.id = ps.pattern_id
+			  WHERE p.user_id = ?1),
+			(SELECT COUNT(*) FROM work_sessions WHERE user_id = ?1 AND completed_at IS NULL),
+			(SELECT COUNT(*) FROM work_sessions
+			  WHERE user_id = ?1 AND completed_at >= strftime('%Y-%m-01T00:00:00Z', 'now'))
+	`, userID).Scan(&s.Patterns, &s.Rows, &s.ActiveSessions, &s.CompletedThisMonth)
+	if err != nil {
+		return Stats{}, fmt.Errorf("user stats: %w", err)
+	}
+	return s, nil
+}
+
+// SiteStats are instance-wide counts for the operator's metrics endpoint.
+type SiteStats struct {
+	Users          int
+	Patterns       int
+	ActiveSessions int // work sessions not yet completed
+	LoginSessions  int // unexpired login sessions
+}
+
+// SiteWideStats computes the instance-wide counts in a single query.
+func SiteWideStats(db *sql.DB) (SiteStats, error) {
+	var s SiteStats
+	err := db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM users),
+			(SELECT COUNT(*) FROM patterns),
+			(SELECT COUNT(*) FROM work_sessions WHERE completed_at IS NULL),
+			(SELECT COUNT(*) FROM sessions WHERE expires_at > ?)
+	`, time.Now().UTC().Format(time.RFC3339)).Scan(&s.Users, &s.Patterns, &s.ActiveSessions, &s.LoginSessions)
+	if err != nil {
+		return SiteStats{}, fmt.Errorf("site stats: %w", err)
+	}
+	return s, nil
+}
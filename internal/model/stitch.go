@@ -2,25 +2,164 @@ package model
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
 )
 
+// StitchInUseError is returned by DeleteStitch when instructions still reference the stitch.
+type StitchInUseError struct {
+	Count int
+}
+
+func (e *StitchInUseError) Error() string {
+	return fmt.Sprintf("stitch is used in %d instructions", e.Count)
+}
+
 type Stitch struct {
 	ID           int64
 	UserID       *int64 // nil for built-in
 	Name         string
 	Abbreviation string
 	Description  string
+	Category     string // '' for uncategorized
+	Color        string // display color as #rrggbb; '' for none
+	Symbol       string // chart symbol, e.g. "×" for sc; '' for none
 	IsBuiltin    bool
+	Shadowed     bool // built-in only: the user has a custom stitch with the same abbreviation
+}
+
+// ErrInvalidStitchColor is returned for a stitch color that isn't a #rrggbb hex string.
+var ErrInvalidStitchColor = errors.New("stitch color must be a hex color like #a1b2c3")
+
+var stitchColorRe = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// NormalizeStitchColor trims and lowercases a stitch color, returning
+// ErrInvalidStitchColor unless it is empty or a #rrggbb hex string.
+func NormalizeStitchColor(color string) (string, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if color != "" && !stitchColorRe.MatchString(color) {
+		return "", ErrInvalidStitchColor
+	}
+	return color, nil
+}
+
+// MaxStitchSymbolLength caps a stitch symbol, in characters: a symbol is a single
+// character or a short code.
+const MaxStitchSymbolLength = 6
+
+// ErrStitchSymbolTooLong is returned for a stitch symbol over MaxStitchSymbolLength.
+var ErrStitchSymbolTooLong = fmt.Errorf("stitch symbol must be %d characters or fewer", MaxStitchSymbolLength)
+
+// NormalizeStitchSymbol trims a stitch symbol, returning ErrStitchSymbolTooLong if it
+// is over MaxStitchSymbolLength characters.
+func NormalizeStitchSymbol(symbol string) (string, error) {
+	symbol = strings.TrimSpace(symbol)
+	if utf8.RuneCountInString(symbol) > MaxStitchSymbolLength {
+		return "", ErrStitchSymbolTooLong
+	}
+	return symbol, nil
 }
 
-// ListStitchesForUser returns all built-in stitches plus the user's custom stitches.
-func ListStitchesForUser(db *sql.DB, userID int64) ([]Stitch, error) {
+// UncategorizedLabel is shown for stitches with no category.
+const UncategorizedLabel = "Uncategorized"
+
+// StitchCategory is a run of stitches sharing a category, in list order.
+type StitchCategory struct {
+	Name     string
+	Stitches []Stitch
+}
+
+// GroupStitchesByCategory splits a category-ordered stitch list into groups.
+func GroupStitchesByCategory(stitches []Stitch) []StitchCategory {
+	var groups []StitchCategory
+	for _, s := range stitches {
+		name := s.Category
+		if name == "" {
+			name = UncategorizedLabel
+		}
+		if len(groups) == 0 || groups[len(groups)-1].Name != name {
+			groups = append(groups, StitchCategory{Name: name})
+		}
+		groups[len(groups)-1].Stitches = append(groups[len(groups)-1].Stitches, s)
+	}
+	return groups
+}
+
+// builtinStitches is the canonical built-in stitch library. Migration 002 seeded
+// the original set; SeedBuiltinStitches keeps existing databases in step with this list.
+// Symbols follow the standard crochet and knitting chart symbols as closely as a
+// single character can, and no two built-ins share one; post stitches have none and
+// show their abbreviation.
+var builtinStitches = []Stitch{
+	{Name: "Chain", Abbreviation: "ch", Description: "Foundation stitch; yarn over, pull through loop", Category: "Foundation", Symbol: "○"},
+	{Name: "Slip Stitch", Abbreviation: "sl st", Description: "Join or move yarn without adding height", Category: "Foundation", Symbol: "•"},
+	{Name: "Magic Ring", Abbreviation: "MR", Description: "Adjustable starting loop for working in the round", Category: "Foundation", Symbol: "◎"},
+	{Name: "Single Crochet", Abbreviation: "sc", Description: "Short stitch; insert, yarn over, pull through twice", Category: "Basic", Symbol: "×"},
+	{Name: "Half Double Crochet", Abbreviation: "hdc", Description: "Medium height; yarn over before inserting", Category: "Basic", Symbol: "T"},
+	{Name: "Double Crochet", Abbreviation: "dc", Description: "Tall stitch; yarn over, insert, three pull-throughs", Category: "Basic", Symbol: "Ŧ"},
+	{Name: "Treble Crochet", Abbreviation: "tr", Description: "Extra tall; yarn over twice before inserting", Category: "Basic", Symbol: "₮"},
+	{Name: "Increase", Abbreviation: "inc", Description: "Two single crochets worked into the same stitch", Category: "Increases", Symbol: "V"},
+	{Name: "Decrease", Abbreviation: "dec", Description: "Single crochet two together (sc2tog)", Category: "Decreases", Symbol: "Λ"},
+	{Name: "Front Post Double Crochet", Abbreviation: "FPdc", Description: "dc worked around the front of previous row's post", Category: "Post"},
+	{Name: "Back Post Double Crochet", Abbreviation: "BPdc", Description: "dc worked around the back of previous row's post", Category: "Post"},
+	{Name: "Knit", Abbreviation: "k", Description: "Insert right needle front to back, wrap, pull loop through", Category: "Knit", Symbol: "│"},
+	{Name: "Purl", Abbreviation: "p", Description: "Insert right needle back to front, wrap, push loop through", Category: "Knit", Symbol: "–"},
+	{Name: "Yarn Over", Abbreviation: "yo", Description: "Wrap yarn over the hook or needle", Category: "Knit", Symbol: "O"}, // the knitting chart O, not the chain's ○
+	{Name: "Knit Two Together", Abbreviation: "k2tog", Description: "Right-leaning knit decrease", Category: "Knit", Symbol: "/"},
+	{Name: "Slip Slip Knit", Abbreviation: "ssk", Description: "Left-leaning knit decrease", Category: "Knit", Symbol: "\\"},
+}
+
+// SeedBuiltinStitches inserts any built-in stitches missing from the database and
+// fills in the symbol of any built-in that has none.
+// It is idempotent: the unique index on built-in abbreviations makes existing rows a no-op.
+// Returns the number of stitches inserted.
+func SeedBuiltinStitches(db *sql.DB) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var inserted int64
+	for _, st := range builtinStitches {
+		result, err := tx.Exec(`
+			INSERT OR IGNORE INTO stitches (user_id, name, abbreviation, description, category, symbol, is_builtin)
+			VALUES (NULL, ?, ?, ?, ?, ?, 1)
+		`, st.Name, st.Abbreviation, st.Description, st.Category, st.Symbol)
+		if err != nil {
+			return 0, fmt.Errorf("seed stitch %s: %w", st.Abbreviation, err)
+		}
+		n, _ := result.RowsAffected()
+		inserted += n
+
+		// Built-ins seeded before symbols existed get theirs.
+		if _, err := tx.Exec(`
+			UPDATE stitches SET symbol = ? WHERE user_id IS NULL AND abbreviation = ? AND symbol = ''
+		`, st.Symbol, st.Abbreviation); err != nil {
+			return 0, fmt.Errorf("seed stitch symbol %s: %w", st.Abbreviation, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return inserted, nil
+}
+
+// ListStitchesForUser returns the user's custom stitches plus the built-ins, ordered by
+// category (uncategorized last) then name. Abbreviations are unique per user, so a custom
+// stitch may reuse a built-in's abbreviation; it then shadows the built-in, which is left
+// out so each abbreviation resolves to exactly one stitch.
+func ListStitchesForUser(db rowsQueryer, userID int64) ([]Stitch, error) {
 	rows, err := db.Query(`
-		SELECT id, user_id, name, abbreviation, description, is_builtin
+		SELECT id, user_id, name, abbreviation, description, category, color, symbol, is_builtin
 		FROM stitches
-		WHERE user_id IS NULL OR user_id = ?
-		ORDER BY is_builtin DESC, name ASC
+		WHERE user_id = ?1
+		   OR (user_id IS NULL AND abbreviation NOT IN (SELECT abbreviation FROM stitches WHERE user_id = ?1))
+		ORDER BY category = '' ASC, category ASC, name ASC
 	`, userID)
 	if err != nil {
 		return nil, fmt.Errorf("list stitches: %w", err)
@@ -30,10 +169,58 @@ func ListStitchesForUser(db *sql.DB, userID int64) ([]Stitch, error) {
 	return scanStitches(rows)
 }
 
-// ListBuiltinStitches returns only the built-in stitches.
+// FilterStitches keeps the stitches whose name or abbreviation contains q, ignoring
+// case, in their original order. A blank q keeps them all.
+func FilterStitches(stitches []Stitch, q string) []Stitch {
+	q = strings.ToLower(strings.TrimSpace(q))
+	if q == "" {
+		return stitches
+	}
+	var out []Stitch
+	for _, s := range stitches {
+		if strings.Contains(strings.ToLower(s.Name), q) || strings.Contains(strings.ToLower(s.Abbreviation), q) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ListStitchLibraryForUser returns every built-in stitch plus the user's custom stitches,
+// in the same order as ListStitchesForUser. Unlike that list it keeps shadowed built-ins,
+// marking them Shadowed, so the stitch library can show what a custom stitch overrides.
+func ListStitchLibraryForUser(db *sql.DB, userID int64) ([]Stitch, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, name, abbreviation, description, category, color, symbol, is_builtin
+		FROM stitches
+		WHERE user_id IS NULL OR user_id = ?
+		ORDER BY category = '' ASC, category ASC, name ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list stitch library: %w", err)
+	}
+	defer rows.Close()
+
+	stitches, err := scanStitches(rows)
+	if err != nil {
+		return nil, err
+	}
+	custom := make(map[string]bool)
+	for _, s := range stitches {
+		if !s.IsBuiltin {
+			custom[s.Abbreviation] = true
+		}
+	}
+	for i := range stitches {
+		stitches[i].Shadowed = stitches[i].IsBuiltin && custom[stitches[i].Abbreviation]
+	}
+	return stitches, nil
+}
+
+// ListBuiltinStitches returns the canonical built-in stitches, whether or not any user
+// has shadowed them.
 func ListBuiltinStitches(db *sql.DB) ([]Stitch, error) {
 	rows, err := db.Query(`
-		SELECT id, user_id, name, abbreviation, description, is_builtin
+		SELECT id, user_id, name, abbreviation, description, category, color, symbol, is_builtin
 		FROM stitches
 		WHERE is_builtin = 1
 		ORDER BY name ASC
@@ -49,7 +236,7 @@ func ListBuiltinStitches(db *sql.DB) ([]Stitch, error) {
 // ListCustomStitches returns only the user's custom stitches.
 func ListCustomStitches(db *sql.DB, userID int64) ([]Stitch, error) {
 	rows, err := db.Query(`
-		SELECT id, user_id, name, abbreviation, description, is_builtin
+		SELECT id, user_id, name, abbreviation, description, category, color, symbol, is_builtin
 		FROM stitches
 		WHERE user_id = ?
 		ORDER BY name ASC
@@ -66,9 +253,9 @@ func FindStitchByID(db *sql.DB, id int64) (*Stitch, error) {
 	s := &Stitch{}
 	var userID sql.NullInt64
 	err := db.QueryRow(`
-		SELECT id, user_id, name, abbreviation, description, is_builtin
+		SELECT id, user_id, name, abbreviation, description, category, color, symbol, is_builtin
 		FROM stitches WHERE id = ?
-	`, id).Scan(&s.ID, &userID, &s.Name, &s.Abbreviation, &s.Description, &s.IsBuiltin)
+	`, id).Scan(&s.ID, &userID, &s.Name, &s.Abbreviation, &s.Description, &s.Category, &s.Color, &s.Symbol, &s.IsBuiltin)
 	if err != nil {
 		return nil, err
 	}
@@ -78,11 +265,16 @@ func FindStitchByID(db *sql.DB, id int64) (*Stitch, error) {
 	return s, nil
 }
 
-func CreateStitch(db *sql.DB, userID int64, name, abbreviation, description string) (*Stitch, error) {
+func CreateStitch(db *sql.DB, userID int64, name, abbreviation, description, category, color, symbol string) (*Stitch, error) {
+	return insertStitch(db, userID, name, abbreviation, description, category, color, symbol)
+}
+
+// insertStitch inserts a custom stitch through db or a transaction.
+func insertStitch(db execer, userID int64, name, abbreviation, description, category, color, symbol string) (*Stitch, error) {
 	result, err := db.Exec(`
-		INSERT INTO stitches (user_id, name, abbreviation, description, is_builtin)
-		VALUES (?, ?, ?, ?, 0)
-	`, userID, name, abbreviation, description)
+		INSERT INTO stitches (user_id, name, abbreviation, description, category, color, symbol, is_builtin)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 0)
+	`, userID, name, abbreviation, description, category, color, symbol)
 	if err != nil {
 		return nil, fmt.Errorf("insert stitch: %w", err)
 	}
@@ -95,15 +287,18 @@ func CreateStitch(db *sql.DB, userID int64, name, abbreviation, description stri
 		Name:         name,
 		Abbreviation: abbreviation,
 		Description:  description,
+		Category:     category,
+		Color:        color,
+		Symbol:       symbol,
 		IsBuiltin:    false,
 	}, nil
 }
 
-func UpdateStitch(db *sql.DB, id, userID int64, name, abbreviation, description string) error {
+func UpdateStitch(db *sql.DB, id, userID int64, name, abbreviation, description, category, color, symbol string) error {
 	result, err := db.Exec(`
-		UPDATE stitches SET name = ?, abbreviation = ?, description = ?
+		UPDATE stitches SET name = ?, abbreviation = ?, description = ?, category = ?, color = ?, symbol = ?
 		WHERE id = ? AND user_id = ? AND is_builtin = 0
-	`, name, abbreviation, description, id, userID)
+	`, name, abbreviation, description, category, color, symbol, id, userID)
 	if err != nil {
 		return fmt.Errorf("update stitch: %w", err)
 	}
@@ -115,8 +310,124 @@ func UpdateStitch(db *sql.DB, id, userID int64, name, abbreviation, description
 	return nil
 }
 
+// DuplicateStitch copies a built-in or user-owned stitch into a new custom stitch for the user.
+// The name gets a " copy" suffix and the abbreviation a numeric suffix (dc2, dc3, ...) chosen so
+// it doesn't clash with any stitch the user can see.
+func DuplicateStitch(db *sql.DB, id, userID int64) (*Stitch, error) {
+	src, err := FindStitchByID(db, id)
+	if err != nil {
+		return nil, fmt.Errorf("find stitch: %w", err)
+	}
+	if !src.IsBuiltin && (src.UserID == nil || *src.UserID != userID) {
+		return nil, fmt.Errorf("stitch not found or not owned by user")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	abbr := ""
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s%d", src.Abbreviation, n)
+		var taken int
+		err := tx.QueryRow(`
+			SELECT COUNT(*) FROM stitches
+			WHERE abbreviation = ? AND (user_id IS NULL OR user_id = ?)
+		`, candidate, userID).Scan(&taken)
+		if err != nil {
+			return nil, fmt.Errorf("check abbreviation: %w", err)
+		}
+		if taken == 0 {
+			abbr = candidate
+			break
+		}
+	}
+
+	name := src.Name + " copy"
+	result, err := tx.Exec(`
+		INSERT INTO stitches (user_id, name, abbreviation, description, category, color, symbol, is_builtin)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 0)
+	`, userID, name, abbr, src.Description, src.Category, src.Color, src.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("insert stitch: %w", err)
+	}
+	newID, _ := result.LastInsertId()
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	uid := userID
+	return &Stitch{
+		ID:           newID,
+		UserID:       &uid,
+		Name:         name,
+		Abbreviation: abbr,
+		Description:  src.Description,
+		Category:     src.Category,
+		Color:        src.Color,
+		Symbol:       src.Symbol,
+		IsBuiltin:    false,
+	}, nil
+}
+
+// CountInstructionsUsingStitch returns how many instructions reference the stitch.
+func CountInstructionsUsingStitch(db *sql.DB, stitchID int64) (int, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM row_instructions WHERE stitch_id = ?", stitchID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("count stitch usage: %w", err)
+	}
+	return n, nil
+}
+
+// CountStitchUsageForUser returns instruction counts keyed by stitch ID for the user's custom stitches.
+// Unused stitches are absent from the map.
+func CountStitchUsageForUser(db *sql.DB, userID int64) (map[int64]int, error) {
+	rows, err := db.Query(`
+		SELECT ri.stitch_id, COUNT(*)
+		FROM row_instructions ri
+		JOIN stitches s ON s.id = ri.stitch_id
+		WHERE s.user_id = ?
+		GROUP BY ri.stitch_id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("count stitch usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make(map[int64]int)
+	for rows.Next() {
+		var id int64
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, fmt.Errorf("scan stitch usage: %w", err)
+		}
+		usage[id] = n
+	}
+	return usage, rows.Err()
+}
+
+// DeleteStitch deletes a custom stitch. It refuses with *StitchInUseError if any
+// instruction still references the stitch.
 func DeleteStitch(db *sql.DB, id, userID int64) error {
-	result, err := db.Exec(`
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var inUse int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM row_instructions WHERE stitch_id = ?", id).Scan(&inUse); err != nil {
+		return fmt.Errorf("count stitch usage: %w", err)
+	}
+	if inUse > 0 {
+		return &StitchInUseError{Count: inUse}
+	}
+
+	result, err := tx.Exec(`
 		DELETE FROM stitches WHERE id = ? AND user_id = ? AND is_builtin = 0
 	`, id, userID)
 	if err != nil {
@@ -127,7 +438,7 @@ func DeleteStitch(db *sql.DB, id, userID int64) error {
 	if n == 0 {
 		return fmt.Errorf("stitch not found, not owned by user, or is built-in")
 	}
-	return nil
+	return tx.Commit()
 }
 
 func scanStitches(rows *sql.Rows) ([]Stitch, error) {
@@ -135,7 +446,7 @@ func scanStitches(rows *sql.Rows) ([]Stitch, error) {
 	for rows.Next() {
 		var s Stitch
 		var userID sql.NullInt64
-		if err := rows.Scan(&s.ID, &userID, &s.Name, &s.Abbreviation, &s.Description, &s.IsBuiltin); err != nil {
+		if err := rows.Scan(&s.ID, &userID, &s.Name, &s.Abbreviation, &s.Description, &s.Category, &s.Color, &s.Symbol, &s.IsBuiltin); err != nil {
 			return nil, fmt.Errorf("scan stitch: %w", err)
 		}
 		if userID.Valid {
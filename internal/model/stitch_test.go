@@ -0,0 +1,92 @@
+package model
+
+import "testing"
+
+func TestCustomStitchTakesPrecedenceOverBuiltin(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "a@b.com")
+	other := newTestUser(t, db, "c@d.com")
+
+	builtins, err := ListBuiltinStitches(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtinSC := builtinStitchID(t, db, "sc")
+	custom, err := CreateStitch(db, user.ID, "Single crochet (mine)", "sc", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byAbbr := func(stitches []Stitch, abbr string) []Stitch {
+		var out []Stitch
+		for _, s := range stitches {
+			if s.Abbreviation == abbr {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+
+	picker, err := ListStitchesForUser(db, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := byAbbr(picker, "sc"); len(got) != 1 || got[0].ID != custom.ID {
+		t.Fatalf("picker has %+v for sc, want only the custom stitch %d", got, custom.ID)
+	}
+	if len(picker) != len(builtins) {
+		t.Fatalf("picker has %d stitches, want %d", len(picker), len(builtins))
+	}
+
+	library, err := ListStitchLibraryForUser(db, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := byAbbr(library, "sc")
+	if len(got) != 2 {
+		t.Fatalf("library has %d stitches for sc, want the built-in and the custom one", len(got))
+	}
+	for _, s := range got {
+		if s.IsBuiltin && (s.ID != *builtinSC || !s.Shadowed) {
+			t.Fatalf("built-in sc in the library: %+v, want it marked shadowed", s)
+		}
+	}
+
+	after, err := ListBuiltinStitches(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(builtins) || len(byAbbr(after, "sc")) != 1 || byAbbr(after, "sc")[0].ID != *builtinSC {
+		t.Fatal("ListBuiltinStitches changed after a custom stitch shadowed a built-in")
+	}
+
+	// Another user's picker still gets the built-in.
+	theirs, err := ListStitchesForUser(db, other.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := byAbbr(theirs, "sc"); len(got) != 1 || got[0].ID != *builtinSC {
+		t.Fatalf("other user's picker has %+v for sc, want the built-in", got)
+	}
+}
+
+func TestBuiltinStitchSymbolsAreDistinct(t *testing.T) {
+	db := newTestDB(t)
+	builtins, err := ListBuiltinStitches(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]string)
+	for _, s := range builtins {
+		if s.Symbol == "" {
+			continue
+		}
+		if other, ok := seen[s.Symbol]; ok {
+			t.Errorf("%s and %s both use the symbol %s", other, s.Abbreviation, s.Symbol)
+		}
+		seen[s.Symbol] = s.Abbreviation
+	}
+	if seen["○"] != "ch" {
+		t.Errorf("○ is the symbol of %q, want ch", seen["○"])
+	}
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StitchCSVHeader is the column order of a stitch library CSV.
+var StitchCSVHeader = []string{"name", "abbreviation", "description", "category"}
+
+// CSVLineError is a problem with one line of an imported CSV; the rest of the file
+// is still imported.
+type CSVLineError struct {
+	Line int
+	Err  error
+}
+
+func (e *CSVLineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *CSVLineError) Unwrap() error { return e.Err }
+
+// ImportStitchesCSV creates a custom stitch for each line of a CSV with the columns
+// name, abbreviation, description, category (the last two optional). A header line
+// is skipped if present. A line whose abbreviation the user already has, built-in
+// or custom, or that repeats an earlier line's, is skipped. Every skipped or bad
+// line is reported in errs as a *CSVLineError without stopping the import.
+func ImportStitchesCSV(db *sql.DB, userID int64, r io.Reader) (created int, errs []error) {
+	byAbbr, err := stitchIDsByAbbr(db, userID)
+	if err != nil {
+		return 0, []error{err}
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	for first := true; ; first = false {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				errs = append(errs, &CSVLineError{Line: parseErr.Line, Err: parseErr.Err})
+				continue
+			}
+			return created, append(errs, err)
+		}
+		line, _ := cr.FieldPos(0)
+		if first && isStitchCSVHeader(record) {
+			continue
+		}
+
+		for len(record) < len(StitchCSVHeader) {
+			record = append(record, "")
+		}
+		name := strings.TrimSpace(record[0])
+		abbr := strings.TrimSpace(record[1])
+		desc := strings.TrimSpace(record[2])
+		category := strings.TrimSpace(record[3])
+		if name == "" && abbr == "" && desc == "" && category == "" {
+			continue // blank line
+		}
+		if name == "" || abbr == "" {
+			errs = append(errs, &CSVLineError{Line: line, Err: errors.New("name and abbreviation are required")})
+			continue
+		}
+		key := strings.ToLower(abbr)
+		if _, ok := byAbbr[key]; ok {
+			errs = append(errs, &CSVLineError{Line: line, Err: fmt.Errorf("you already have a stitch abbreviated %q", abbr)})
+			continue
+		}
+
+		stitch, err := CreateStitch(db, userID, name, abbr, desc, category, "", "")
+		if err != nil {
+			errs = append(errs, &CSVLineError{Line: line, Err: err})
+			continue
+		}
+		byAbbr[key] = stitch.ID
+		created++
+	}
+	return created, errs
+}
+
+// isStitchCSVHeader reports whether a CSV record is the header line rather than a
+// stitch.
+func isStitchCSVHeader(record []string) bool {
+	if len(record) < 2 {
+		return false
+	}
+	name := strings.ToLower(strings.TrimSpace(record[0]))
+	abbr := strings.ToLower(strings.TrimSpace(record[1]))
+	return name == "name" && (abbr == "abbreviation" || abbr == "abbr")
+}
+
+// ExportStitchesCSV writes stitches in the format ImportStitchesCSV reads, with a
+// header line. Colors aren't included.
+func ExportStitchesCSV(stitches []Stitch) ([]byte, error) {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	cw.Write(StitchCSVHeader)
+	for _, s := range stitches {
+		cw.Write([]string{s.Name, s.Abbreviation, s.Description, s.Category})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return nil, fmt.Errorf("write stitch csv: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxTagLength is the longest tag name accepted.
+const MaxTagLength = 32
+
+// ErrInvalidTag is returned for empty or overlong tag names.
+var ErrInvalidTag = errors.New("invalid tag name")
+
+// Tag is a user-defined label for grouping patterns ("hat", "gift", "2024").
+type Tag struct {
+	ID     int64
+	UserID int64
+	Name   string
+}
+
+// normalizeTagName trims and collapses whitespace in a tag name.
+func normalizeTagName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || len([]rune(name)) > MaxTagLength {
+		return "", ErrInvalidTag
+	}
+	return name, nil
+}
+
+// AddTagToPattern attaches a tag to a pattern, creating the tag for the user if it
+// doesn't exist yet. An existing tag is reused regardless of case.
+func AddTagToPattern(db *sql.DB, patternID, userID int64, name string) (*Tag, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("INSERT OR IGNORE INTO tags (user_id, name) VALUES (?, ?)", userID, name); err != nil {
+		return nil, fmt.Errorf("insert tag: %w", err)
+	}
+
+	tag := &Tag{UserID: userID}
+	if err := tx.QueryRow("SELECT id, name FROM tags WHERE user_id = ? AND name = ?", userID, name).
+		Scan(&tag.ID, &tag.Name); err != nil {
+		return nil, fmt.Errorf("find tag: %w", err)
+	}
+
+	if _, err := tx.Exec("INSERT OR IGNORE INTO pattern_tags (pattern_id, tag_id) VALUES (?, ?)", patternID, tag.ID); err != nil {
+		return nil, fmt.Errorf("tag pattern: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// RemoveTagFromPattern detaches a tag from a pattern. The tag itself is deleted once
+// no pattern uses it.
+func RemoveTagFromPattern(db *sql.DB, patternID, tagID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM pattern_tags WHERE pattern_id = ? AND tag_id = ?", patternID, tagID); err != nil {
+		return fmt.Errorf("untag pattern: %w", err)
+	}
+	if err := deleteUnusedTags(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteUnusedTags removes tags no longer attached to any pattern.
+func deleteUnusedTags(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+		DELETE FROM tags
+		WHERE NOT EXISTS (SELECT 1 FROM pattern_tags pt WHERE pt.tag_id = tags.id)
+	`); err != nil {
+		return fmt.Errorf("delete unused tags: %w", err)
+	}
+	return nil
+}
+
+// ListTagsForPattern returns a pattern's tags in alphabetical order.
+func ListTagsForPattern(db *sql.DB, patternID int64) ([]Tag, error) {
+	return queryTags(db, `
+		SELECT t.id, t.user_id, t.name
+		FROM tags t JOIN pattern_tags pt ON pt.tag_id = t.id
+		WHERE pt.pattern_id = ?
+		ORDER BY t.name
+	`, patternID)
+}
+
+// ListTagsForUser returns every tag the user has on at least one pattern.
+func ListTagsForUser(db *sql.DB, userID int64) ([]Tag, error) {
+	return queryTags(db, `
+		SELECT id, user_id, name FROM tags
+		WHERE user_id = ?
+		ORDER BY name
+	`, userID)
+}
+
+func queryTags(db *sql.DB, query string, args ...any) ([]Tag, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []Tag
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name); err != nil {
+			return nil, fmt.Errorf("scan tag: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
@@ -0,0 +1,376 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParsedPattern is the result of ParsePatternText: rows grouped into sections, plus
+// the lines that couldn't be understood.
+type ParsedPattern struct {
+	Sections []ParsedSection
+	Skipped  []SkippedLine
+}
+
+// ParsedSection is a run of rows under a heading line ("Body:"), or under "Main"
+// if the text has no headings.
+type ParsedSection struct {
+	Name string
+	Rows []ParsedRow
+}
+
+// ParsedRow is one "Rnd 1: ..." line. A range label ("Rnds 3-5") becomes one row
+// with a repeat count.
+type ParsedRow struct {
+	Type         string // "row" or "continuous_round"
+	RepeatCount  int
+	Instructions []ParsedInstruction
+	StitchCount  int // trailing "(N)" total, or the sum of the instructions
+}
+
+// ParsedInstruction is one comma-separated step of a row. Abbr is matched against
+// the user's stitches when the pattern is created.
+type ParsedInstruction struct {
+	Abbr        string
+	Count       int
+	Into        string
+	Note        string
+	IsGroup     bool
+	GroupRepeat int
+	Children    []ParsedInstruction
+}
+
+// SkippedLine is an input line ParsePatternText didn't recognize.
+type SkippedLine struct {
+	Number int // 1-based
+	Text   string
+}
+
+// ErrNothingParsed is returned when no rows could be read from the text.
+var ErrNothingParsed = errors.New("no rows found in pattern text")
+
+var (
+	rowLineRe = regexp.MustCompile(`(?i)^(rnds?|rounds?|rows?|r)\.?\s*(\d+)(?:\s*(?:-|–|to)\s*(\d+))?\s*[:.)]\s*(.*)$`)
+	totalRe   = regexp.MustCompile(`(?i)[(\[]\s*(\d+)\s*(?:sts?|stitches)?\s*[)\]]\s*\.?$`)
+	groupRe   = regexp.MustCompile(`(?i)\*([^*]+)\*\s*(?:x\s*(\d+)|(\d+)\s*times)?`)
+	timesRe   = regexp.MustCompile(`(?i)^(?:[x×](\d+)|(\d+)[x×])$`)
+	headingRe = regexp.MustCompile(`^([^:]{1,60}):\s*$`)
+)
+
+// ParsePatternText reads plain-text crochet notation such as
+//
+//	Rnd 1: 6 sc in MR (6)
+//	Rnd 2: inc x6 (12)
+//	Rnds 3-5: *sc, inc* x6 (18)
+//
+// Row labels, counts, "in ..." placements, *...* xN groups and trailing (N) totals
+// are recognized. Lines ending in ":" start a new section. Anything else is reported
+// in Skipped rather than failing the whole parse.
+func ParsePatternText(text string) (ParsedPattern, error) {
+	var out ParsedPattern
+	current := -1
+
+	for i, raw := range strings.Split(text, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+
+		m := rowLineRe.FindStringSubmatch(line)
+		if m == nil {
+			if h := headingRe.FindStringSubmatch(line); h != nil {
+				out.Sections = append(out.Sections, ParsedSection{Name: strings.TrimSpace(h[1])})
+				current = len(out.Sections) - 1
+				continue
+			}
+			out.Skipped = append(out.Skipped, SkippedLine{Number: i + 1, Text: line})
+			continue
+		}
+
+		row, ok := parseRowBody(m[4])
+		if !ok || !row.withinLimits() {
+			out.Skipped = append(out.Skipped, SkippedLine{Number: i + 1, Text: line})
+			continue
+		}
+		row.Type = "continuous_round"
+		if strings.HasPrefix(strings.ToLower(m[1]), "row") {
+			row.Type = "row"
+		}
+		row.RepeatCount = 1
+		if m[3] != "" {
+			from, _ := strconv.Atoi(m[2])
+			to, _ := strconv.Atoi(m[3])
+			if to > from {
+				row.RepeatCount = to - from + 1
+			}
+		}
+		if row.RepeatCount > MaxRowRepeat {
+			out.Skipped = append(out.Skipped, SkippedLine{Number: i + 1, Text: line})
+			continue
+		}
+
+		if current < 0 {
+			out.Sections = append(out.Sections, ParsedSection{Name: "Main"})
+			current = 0
+		}
+		out.Sections[current].Rows = append(out.Sections[current].Rows, row)
+	}
+
+	// Drop headings that never got any rows.
+	sections := out.Sections[:0]
+	for _, s := range out.Sections {
+		if len(s.Rows) > 0 {
+			sections = append(sections, s)
+		}
+	}
+	out.Sections = sections
+
+	if len(out.Sections) == 0 {
+		return out, ErrNothingParsed
+	}
+	return out, nil
+}
+
+// parseRowBody parses the part of a row line after its label.
+func parseRowBody(body string) (ParsedRow, bool) {
+	var row ParsedRow
+	body = strings.TrimSpace(body)
+
+	explicitCount := false
+	if m := totalRe.FindStringSubmatchIndex(body); m != nil {
+		row.StitchCount, _ = strconv.Atoi(body[m[2]:m[3]])
+		explicitCount = true
+		body = strings.TrimSpace(body[:m[0]])
+	}
+	body = strings.TrimRight(body, ". ")
+
+	// Pull out *...* xN groups first so their commas don't split the row.
+	var groups []ParsedInstruction
+	body = groupRe.ReplaceAllStringFunc(body, func(g string) string {
+		m := groupRe.FindStringSubmatch(g)
+		repeat := 1
+		if m[2] != "" {
+			repeat, _ = strconv.Atoi(m[2])
+		} else if m[3] != "" {
+			repeat, _ = strconv.Atoi(m[3])
+		}
+		group := ParsedInstruction{IsGroup: true, GroupRepeat: max(repeat, 1), Count: 1}
+		for _, seg := range splitSegments(m[1]) {
+			if instr, ok := parseSegment(seg); ok {
+				group.Children = append(group.Children, instr)
+			}
+		}
+		groups = append(groups, group)
+		return fmt.Sprintf(",\x00%d,", len(groups)-1)
+	})
+
+	for _, seg := range splitSegments(body) {
+		if strings.HasPrefix(seg, "\x00") {
+			idx, _ := strconv.Atoi(seg[1:])
+			if len(groups[idx].Children) > 0 {
+				row.Instructions = append(row.Instructions, groups[idx])
+			}
+			continue
+		}
+		instr, ok := parseSegment(seg)
+		if !ok {
+			return row, false
+		}
+		row.Instructions = append(row.Instructions, instr)
+	}
+	if len(row.Instructions) == 0 {
+		return row, false
+	}
+
+	if !explicitCount {
+		for _, ri := range row.Instructions {
+			if ri.IsGroup {
+				for _, c := range ri.Children {
+					row.StitchCount += c.Count * ri.GroupRepeat
+				}
+			} else {
+				row.StitchCount += ri.Count
+			}
+		}
+	}
+	return row, true
+}
+
+// withinLimits reports whether the row's instruction counts and group repeats are
+// within the limits the editor enforces.
+func (row ParsedRow) withinLimits() bool {
+	for _, ri := range row.Instructions {
+		if CheckInstructionCounts(ri.Count, max(ri.GroupRepeat, 1), nil) != nil {
+			return false
+		}
+		for _, c := range ri.Children {
+			if CheckInstructionCounts(c.Count, 1, nil) != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// splitSegments splits on commas and semicolons, dropping empty parts.
+func splitSegments(s string) []string {
+	var out []string
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// parseSegment reads one step like "6 sc in MR", "inc x6", "sc2tog" or "2 dc in next st".
+func parseSegment(seg string) (ParsedInstruction, bool) {
+	words := strings.Fields(seg)
+	instr := ParsedInstruction{Count: 1}
+
+	// Leading count: "6 sc".
+	if len(words) > 1 {
+		if n, err := strconv.Atoi(words[0]); err == nil && n > 0 {
+			instr.Count = n
+			words = words[1:]
+		}
+	}
+	if len(words) == 0 {
+		return instr, false
+	}
+	if _, err := strconv.Atoi(words[0]); err == nil || timesRe.MatchString(words[0]) {
+		return instr, false // a count with no stitch
+	}
+	instr.Abbr = words[0]
+	words = words[1:]
+
+	// Trailing count: "inc x6", "inc 6x", "sc 6 times", "ch 3".
+	if n := len(words); n == 1 {
+		if times, err := strconv.Atoi(words[0]); err == nil && times > 0 {
+			instr.Count *= times
+			words = nil
+		}
+	}
+	if n := len(words); n > 1 && strings.EqualFold(words[n-1], "times") {
+		if times, err := strconv.Atoi(words[n-2]); err == nil && times > 0 {
+			instr.Count *= times
+			words = words[:n-2]
+		}
+	} else if n > 0 {
+		if m := timesRe.FindStringSubmatch(words[n-1]); m != nil {
+			times, _ := strconv.Atoi(m[1] + m[2])
+			if times > 0 {
+				instr.Count *= times
+				words = words[:n-1]
+			}
+		}
+	}
+
+	rest := strings.Join(words, " ")
+	switch {
+	case rest == "":
+	case strings.HasPrefix(strings.ToLower(rest), "in "):
+		instr.Into = strings.TrimSpace(rest[3:])
+	case strings.HasPrefix(strings.ToLower(rest), "into "):
+		instr.Into = strings.TrimSpace(rest[5:])
+	default:
+		instr.Note = rest
+	}
+	return instr, true
+}
+
+// UnknownStitchNote marks imported instructions whose abbreviation didn't match any
+// of the user's stitches.
+const UnknownStitchNote = "unknown stitch: "
+
+// CreatePatternFromParsed creates a pattern from ParsePatternText output in one
+// transaction. Abbreviations are matched case-insensitively against the user's
+// stitches; unmatched ones become instructions with no stitch and a note naming the
+// abbreviation, so they can be fixed by hand.
+func CreatePatternFromParsed(db *sql.DB, userID int64, name string, parsed ParsedPattern) (*Pattern, error) {
+	byAbbr, err := stitchIDsByAbbr(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO patterns (user_id, name, description) VALUES (?, ?, '')", userID, name)
+	if err != nil {
+		return nil, fmt.Errorf("insert pattern: %w", err)
+	}
+	patternID, _ := result.LastInsertId()
+
+	for si, section := range parsed.Sections {
+		result, err := tx.Exec(
+			"INSERT INTO pattern_sections (pattern_id, position, name) VALUES (?, ?, ?)",
+			patternID, si+1, section.Name,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("insert section: %w", err)
+		}
+		sectionID, _ := result.LastInsertId()
+
+		for ri, row := range section.Rows {
+			result, err := tx.Exec(`
+				INSERT INTO rows (section_id, position, label, type, expected_stitch_count,
+				                  turning_chain_count, turning_chain_counts_as_stitch, repeat_count, notes, count_only)
+				VALUES (?, ?, '', ?, ?, 0, 0, ?, '', 0)
+			`, sectionID, ri+1, row.Type, max(row.StitchCount, 1), row.RepeatCount)
+			if err != nil {
+				return nil, fmt.Errorf("insert row: %w", err)
+			}
+			rowID, _ := result.LastInsertId()
+			if err := insertInstructionTree(tx, rowID, nil, 0, rowInstructions(row.Instructions), byAbbr); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return FindPatternByID(db, patternID)
+}
+
+// stitchIDsByAbbr maps the lowercased abbreviations of the user's stitches to their IDs.
+func stitchIDsByAbbr(db rowsQueryer, userID int64) (map[string]int64, error) {
+	stitches, err := ListStitchesForUser(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	byAbbr := make(map[string]int64, len(stitches))
+	for _, s := range stitches {
+		key := strings.ToLower(s.Abbreviation)
+		if _, ok := byAbbr[key]; !ok || !s.IsBuiltin {
+			byAbbr[key] = s.ID // the user's own stitch wins over a built-in
+		}
+	}
+	return byAbbr, nil
+}
+
+// rowInstructions converts parsed instructions to unsaved RowInstructions, stitches
+// named by StitchAbbr, for insertInstructionTree.
+func rowInstructions(parsed []ParsedInstruction) []RowInstruction {
+	out := make([]RowInstruction, 0, len(parsed))
+	for _, pi := range parsed {
+		out = append(out, RowInstruction{
+			StitchAbbr:  pi.Abbr,
+			Count:       pi.Count,
+			Into:        pi.Into,
+			Note:        pi.Note,
+			IsGroup:     pi.IsGroup,
+			GroupRepeat: pi.GroupRepeat,
+			Children:    rowInstructions(pi.Children),
+		})
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ToggleSectionCollapsed flips whether a section is collapsed for the user in the
+// pattern editor and returns the new state.
+func ToggleSectionCollapsed(db *sql.DB, userID, sectionID int64) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM ui_section_state WHERE user_id = ? AND section_id = ?", userID, sectionID)
+	if err != nil {
+		return false, fmt.Errorf("expand section: %w", err)
+	}
+	collapsed := false
+	if n, _ := result.RowsAffected(); n == 0 {
+		if _, err := tx.Exec("INSERT INTO ui_section_state (user_id, section_id) VALUES (?, ?)", userID, sectionID); err != nil {
+			return false, fmt.Errorf("collapse section: %w", err)
+		}
+		collapsed = true
+	}
+	return collapsed, tx.Commit()
+}
+
+// CollapsedSections returns the IDs of the pattern's sections the user has collapsed.
+func CollapsedSections(db *sql.DB, userID, patternID int64) (map[int64]bool, error) {
+	rows, err := db.Query(`
+		SELECT s.section_id
+		FROM ui_section_state s
+		JOIN pattern_sections ps ON ps.id = s.section_id
+		WHERE s.user_id = ? AND ps.pattern_id = ?
+	`, userID, patternID)
+	if err != nil {
+		return nil, fmt.Errorf("list collapsed sections: %w", err)
+	}
+	defer rows.Close()
+
+	collapsed := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan collapsed section: %w", err)
+		}
+		collapsed[id] = true
+	}
+	return collapsed, rows.Err()
+}
@@ -2,27 +2,88 @@ package model
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// MinPasswordLength is the shortest password accepted at registration or reset.
+const MinPasswordLength = 8
+
+// DefaultBcryptCost is the bcrypt cost new password hashes use unless the server
+// is configured otherwise.
+const DefaultBcryptCost = 12
+
+// bcryptCost is the cost for new hashes; hashes at any other cost are upgraded the
+// next time their owner logs in.
+var bcryptCost = DefaultBcryptCost
+
+// SetBcryptCost sets the cost for new password hashes. Call it at startup, before
+// serving requests.
+func SetBcryptCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	bcryptCost = cost
+	return nil
+}
+
+var ErrPasswordTooShort = fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+
+// ErrWrongPassword is returned when a supplied current password does not match.
+var ErrWrongPassword = errors.New("current password is incorrect")
+
 type User struct {
 	ID           int64
 	Email        string
 	PasswordHash string
 	CreatedAt    time.Time
+	IsAdmin      bool
+	DisabledAt   *time.Time // nil unless an admin disabled the account
 }
 
+// Disabled reports whether the account has been disabled by an admin.
+func (u *User) Disabled() bool {
+	return u.DisabledAt != nil
+}
+
+const userColumns = "id, email, password_hash, created_at, is_admin, disabled_at"
+
+func scanUser(row interface{ Scan(...any) error }) (*User, error) {
+	u := &User{}
+	var createdAt string
+	var disabledAt sql.NullString
+	if err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &createdAt, &u.IsAdmin, &disabledAt); err != nil {
+		return nil, err
+	}
+	u.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	if disabledAt.Valid {
+		t, _ := time.Parse(time.RFC3339, disabledAt.String)
+		u.DisabledAt = &t
+	}
+	return u, nil
+}
+
+// NormalizeEmail is the form emails are stored and looked up in: trimmed and
+// lowercased, so logging in doesn't depend on how the address was capitalized.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// CreateUser registers a user, storing the email normalized. The first user of a
+// new instance is made its admin.
 func CreateUser(db *sql.DB, email, password string) (*User, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	email = NormalizeEmail(email)
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
 	if err != nil {
 		return nil, fmt.Errorf("hash password: %w", err)
 	}
 
 	result, err := db.Exec(
-		"INSERT INTO users (email, password_hash) VALUES (?, ?)",
+		"INSERT INTO users (email, password_hash, is_admin) VALUES (?, ?, NOT EXISTS (SELECT 1 FROM users))",
 		email, string(hash),
 	)
 	if err != nil {
@@ -30,43 +91,167 @@ func CreateUser(db *sql.DB, email, password string) (*User, error) {
 	}
 
 	id, _ := result.LastInsertId()
-	return &User{
-		ID:           id,
-		Email:        email,
-		PasswordHash: string(hash),
-		CreatedAt:    time.Now().UTC(),
-	}, nil
+	return FindUserByID(db, id)
 }
 
+// FindUserByEmail looks a user up by email regardless of case. An account whose
+// email was left mixed-case because it collided with another (see
+// EmailCaseCollisions) is still found when its exact email is given.
 func FindUserByEmail(db *sql.DB, email string) (*User, error) {
-	u := &User{}
-	var createdAt string
-	err := db.QueryRow(
-		"SELECT id, email, password_hash, created_at FROM users WHERE email = ?",
-		email,
-	).Scan(&u.ID, &u.Email, &u.PasswordHash, &createdAt)
-	if err != nil {
-		return nil, err
-	}
-	u.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-	return u, nil
+	email = strings.TrimSpace(email)
+	return scanUser(db.QueryRow(
+		"SELECT "+userColumns+" FROM users WHERE email IN (?1, ?2) ORDER BY email = ?1 DESC LIMIT 1",
+		email, NormalizeEmail(email),
+	))
 }
 
 func FindUserByID(db *sql.DB, id int64) (*User, error) {
-	u := &User{}
-	var createdAt string
-	err := db.QueryRow(
-		"SELECT id, email, password_hash, created_at FROM users WHERE id = ?",
-		id,
-	).Scan(&u.ID, &u.Email, &u.PasswordHash, &createdAt)
-	if err != nil {
-		return nil, err
-	}
-	u.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-	return u, nil
+	return scanUser(db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", id))
 }
 
 func CheckPassword(user *User, password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	return err == nil
 }
+
+// NeedsRehash reports whether a stored hash should be replaced by one at targetCost.
+// An unreadable hash always does.
+func NeedsRehash(hash string, targetCost int) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != targetCost
+}
+
+// RehashPasswordIfNeeded re-hashes a password that has just been checked against
+// the user's stored hash, if that hash isn't at the current cost. It lets the cost
+// change over time without anyone having to reset their password.
+func RehashPasswordIfNeeded(db *sql.DB, user *User, password string) error {
+	if !NeedsRehash(user.PasswordHash, bcryptCost) {
+		return nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+	// Only replace the hash that was checked, in case the password changed meanwhile.
+	if _, err := db.Exec(
+		"UPDATE users SET password_hash = ? WHERE id = ? AND password_hash = ?",
+		string(hash), user.ID, user.PasswordHash,
+	); err != nil {
+		return fmt.Errorf("update password hash: %w", err)
+	}
+	user.PasswordHash = string(hash)
+	return nil
+}
+
+// UpdateUserPassword re-hashes and stores a new password for the user.
+func UpdateUserPassword(db *sql.DB, userID int64, newPassword string) error {
+	if len(newPassword) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcryptCost)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+
+	result, err := db.Exec("UPDATE users SET password_hash = ? WHERE id = ?", string(hash), userID)
+	if err != nil {
+		return fmt.Errorf("update password: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// ChangePassword verifies the user's current password and replaces it.
+// Returns ErrWrongPassword if current doesn't match, ErrPasswordTooShort if new is too short.
+func ChangePassword(db *sql.DB, userID int64, current, new string) error {
+	user, err := FindUserByID(db, userID)
+	if err != nil {
+		return fmt.Errorf("find user: %w", err)
+	}
+	if !CheckPassword(user, current) {
+		return ErrWrongPassword
+	}
+	return UpdateUserPassword(db, userID, new)
+}
+
+type userEmail struct {
+	id    int64
+	email string
+}
+
+// emailGroups loads every account's ID and email, grouped by NormalizeEmail of the
+// email, with each group's IDs in ascending order.
+func emailGroups(db rowsQueryer) (map[string][]userEmail, []string, error) {
+	rows, err := db.Query("SELECT id, email FROM users ORDER BY id")
+	if err != nil {
+		return nil, nil, fmt.Errorf("list emails: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make(map[string][]userEmail)
+	var order []string
+	for rows.Next() {
+		var u userEmail
+		if err := rows.Scan(&u.id, &u.email); err != nil {
+			return nil, nil, fmt.Errorf("scan email: %w", err)
+		}
+		key := NormalizeEmail(u.email)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], u)
+	}
+	return groups, order, rows.Err()
+}
+
+// NormalizeStoredEmails rewrites every account's email to NormalizeEmail form,
+// except where two accounts would end up with the same email (see
+// EmailCaseCollisions). Migration 031 did this in SQL, but SQLite's lower() only
+// folds ASCII, so it is done here at startup; it returns how many were changed.
+func NormalizeStoredEmails(db *sql.DB) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	groups, order, err := emailGroups(tx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, key := range order {
+		if g := groups[key]; len(g) == 1 && g[0].email != key {
+			if _, err := tx.Exec("UPDATE users SET email = ? WHERE id = ?", key, g[0].id); err != nil {
+				return 0, fmt.Errorf("normalize email: %w", err)
+			}
+			n++
+		}
+	}
+	return n, tx.Commit()
+}
+
+// EmailCaseCollisions lists, by user ID, the groups of accounts whose emails differ
+// only in case or surrounding spaces. NormalizeStoredEmails leaves these as they
+// are, since normalizing would make them clash; each group needs merging or
+// renaming by hand.
+func EmailCaseCollisions(db *sql.DB) ([][]int64, error) {
+	groups, order, err := emailGroups(db)
+	if err != nil {
+		return nil, err
+	}
+	var collisions [][]int64
+	for _, key := range order {
+		if g := groups[key]; len(g) > 1 {
+			ids := make([]int64, len(g))
+			for i, u := range g {
+				ids[i] = u.id
+			}
+			collisions = append(collisions, ids)
+		}
+	}
+	return collisions, nil
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeStoredEmails(t *testing.T) {
+	db := newTestDB(t)
+	insert := func(email string) int64 {
+		t.Helper()
+		res, err := db.Exec("INSERT INTO users (email, password_hash) VALUES (?, 'x')", email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _ := res.LastInsertId()
+		return id
+	}
+	mixed := insert(" Bob@Example.com")
+	accented := insert("ÉMILE@Example.com")
+	clashA := insert("ann@example.com")
+	clashB := insert("ANN@example.com ")
+	clean := insert("zoe@example.com")
+
+	n, err := NormalizeStoredEmails(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("normalized %d emails, want 2", n)
+	}
+	want := map[int64]string{
+		mixed:    "bob@example.com",
+		accented: "émile@example.com",
+		clashA:   "ann@example.com",
+		clashB:   "ANN@example.com ",
+		clean:    "zoe@example.com",
+	}
+	for id, email := range want {
+		var got string
+		if err := db.QueryRow("SELECT email FROM users WHERE id = ?", id).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != email {
+			t.Errorf("user %d email = %q, want %q", id, got, email)
+		}
+	}
+
+	groups, err := EmailCaseCollisions(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || !slices.Equal(groups[0], []int64{clashA, clashB}) {
+		t.Fatalf("collisions = %v, want [[%d %d]]", groups, clashA, clashB)
+	}
+
+	if u, err := FindUserByEmail(db, "Émile@example.com"); err != nil || u.ID != accented {
+		t.Fatalf("FindUserByEmail for the accented account: %v, %v", u, err)
+	}
+}
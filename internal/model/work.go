@@ -2,7 +2,9 @@ package model
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 )
 
@@ -11,9 +13,19 @@ type WorkSession struct {
 	ID           int64
 	UserID       int64
 	PatternID    int64
+	Label        string // optional, tells concurrent sessions of one pattern apart
 	StartedAt    time.Time
 	LastActiveAt time.Time
 	CompletedAt  *time.Time // nil if still active
+	PausedAt     *time.Time // set while the session is explicitly paused
+}
+
+// DisplayName returns the session's label, or a name based on its start date.
+func (s WorkSession) DisplayName() string {
+	if s.Label != "" {
+		return s.Label
+	}
+	return "Started " + s.StartedAt.Format("Jan 2, 2006")
 }
 
 // WorkProgress tracks the current position within a work session.
@@ -23,7 +35,7 @@ type WorkProgress struct {
 	SectionID              int64
 	RowID                  int64
 	RowRepeatIndex         int
-	InstructionID          int64
+	InstructionID          int64 // 0 on a count-only row
 	StitchIndex            int
 	GroupRepeatIndex       int
 	StitchesCompletedInRow int
@@ -35,49 +47,176 @@ type StitchPos struct {
 	InstructionID    int64
 	StitchIndex      int
 	GroupRepeatIndex int
+	Color            string // the instruction's color, or its group's if it has none
 }
 
 // FlattenInstructions produces an ordered flat list of every stitch position for a row.
-// Groups are expanded across all repeats.
+// Groups are expanded across all repeats, and children without a color take the group's.
+// Work mode itself never builds the list; it uses CountStitchPos, StitchPosAt and
+// FindFlatIndex, which work from the counts.
+// A "to end" instruction flattens to its Count as resolved when the row was loaded
+// (see ResolveToEnd), so instructions from ListInstructionsForRow or LoadPatternFull
+// need no further row context.
 func FlattenInstructions(instructions []RowInstruction) []StitchPos {
-	var out []StitchPos
+	out := make([]StitchPos, 0, CountStitchPos(instructions))
+	ForEachStitchPos(instructions, func(_ int, p StitchPos) bool {
+		out = append(out, p)
+		return true
+	})
+	return out
+}
+
+// ForEachStitchPos calls fn with each stitch position of the instructions and its
+// index, in the order FlattenInstructions lists them, stopping early if fn returns
+// false.
+func ForEachStitchPos(instructions []RowInstruction, fn func(i int, p StitchPos) bool) {
+	i := 0
 	for _, ri := range instructions {
 		if ri.IsGroup {
 			for grp := 0; grp < ri.GroupRepeat; grp++ {
 				for _, child := range ri.Children {
+					color := child.Color
+					if color == "" {
+						color = ri.Color
+					}
 					for si := 0; si < child.Count; si++ {
-						out = append(out, StitchPos{child.ID, si, grp})
+						if !fn(i, StitchPos{child.ID, si, grp, color}) {
+							return
+						}
+						i++
 					}
 				}
 			}
 		} else {
 			for si := 0; si < ri.Count; si++ {
-				out = append(out, StitchPos{ri.ID, si, 0})
+				if !fn(i, StitchPos{ri.ID, si, 0, ri.Color}) {
+					return
+				}
+				i++
 			}
 		}
 	}
-	return out
 }
 
-// FindFlatIndex returns the index of the progress position in the flat list, or -1 if not found.
-func FindFlatIndex(flat []StitchPos, p *WorkProgress) int {
-	for i, pos := range flat {
-		if pos.InstructionID == p.InstructionID &&
-			pos.StitchIndex == p.StitchIndex &&
-			pos.GroupRepeatIndex == p.GroupRepeatIndex {
-			return i
+// CountStitchPos returns how many stitch positions the instructions flatten to.
+func CountStitchPos(instructions []RowInstruction) int {
+	n := 0
+	for _, ri := range instructions {
+		n += instructionStitchPos(ri)
+	}
+	return n
+}
+
+// instructionStitchPos is the number of positions one top-level instruction
+// flattens to, across all of a group's repeats.
+func instructionStitchPos(ri RowInstruction) int {
+	if !ri.IsGroup {
+		return max(ri.Count, 0)
+	}
+	return groupRepeatStitchPos(ri) * max(ri.GroupRepeat, 0)
+}
+
+// groupRepeatStitchPos is the number of positions in one repeat of a group.
+func groupRepeatStitchPos(ri RowInstruction) int {
+	n := 0
+	for _, c := range ri.Children {
+		n += max(c.Count, 0)
+	}
+	return n
+}
+
+// StitchPosAt returns the i-th stitch position of the instructions, worked out from
+// the counts rather than by walking every position before it.
+func StitchPosAt(instructions []RowInstruction, i int) (StitchPos, bool) {
+	if i < 0 {
+		return StitchPos{}, false
+	}
+	for _, ri := range instructions {
+		n := instructionStitchPos(ri)
+		if i >= n {
+			i -= n
+			continue
+		}
+		if !ri.IsGroup {
+			return StitchPos{ri.ID, i, 0, ri.Color}, true
+		}
+		per := groupRepeatStitchPos(ri)
+		grp, within := i/per, i%per
+		for _, child := range ri.Children {
+			if within < child.Count {
+				color := child.Color
+				if color == "" {
+					color = ri.Color
+				}
+				return StitchPos{child.ID, within, grp, color}, true
+			}
+			within -= max(child.Count, 0)
+		}
+	}
+	return StitchPos{}, false
+}
+
+// FindFlatIndex returns the index of the progress position among the instructions'
+// stitch positions, or -1 if not found.
+func FindFlatIndex(instructions []RowInstruction, p *WorkProgress) int {
+	offset := 0
+	for _, ri := range instructions {
+		if !ri.IsGroup {
+			if ri.ID == p.InstructionID && p.GroupRepeatIndex == 0 &&
+				p.StitchIndex >= 0 && p.StitchIndex < ri.Count {
+				return offset + p.StitchIndex
+			}
+		} else if p.GroupRepeatIndex >= 0 && p.GroupRepeatIndex < ri.GroupRepeat {
+			per := groupRepeatStitchPos(ri)
+			within := 0
+			for _, child := range ri.Children {
+				if child.ID == p.InstructionID && p.StitchIndex >= 0 && p.StitchIndex < child.Count {
+					return offset + p.GroupRepeatIndex*per + within + p.StitchIndex
+				}
+				within += max(child.Count, 0)
+			}
 		}
+		offset += instructionStitchPos(ri)
 	}
 	return -1
 }
 
+// rowStitchCount is the number of positions work mode steps through for one repeat
+// of a row. A count-only row has no instructions and counts as a single synthetic
+// position, so it is marked done with one advance.
+func rowStitchCount(row *Row) int {
+	if row.CountOnly {
+		return 1
+	}
+	return CountStitchPos(row.Instructions)
+}
+
+// rowStitchAt returns the i-th position of one repeat of a row.
+func rowStitchAt(row *Row, i int) (StitchPos, bool) {
+	if row.CountOnly {
+		return StitchPos{}, i == 0
+	}
+	return StitchPosAt(row.Instructions, i)
+}
+
+// rowFlatIndex returns the index of p among a row's positions, or -1 if not found.
+func rowFlatIndex(row *Row, p *WorkProgress) int {
+	if row.CountOnly {
+		if p.InstructionID == 0 && p.StitchIndex == 0 && p.GroupRepeatIndex == 0 {
+			return 0
+		}
+		return -1
+	}
+	return FindFlatIndex(row.Instructions, p)
+}
+
 // --- Session CRUD ---
 
 func scanSession(row interface{ Scan(...any) error }) (*WorkSession, error) {
 	s := &WorkSession{}
 	var startedAt, lastActiveAt string
-	var completedAt sql.NullString
-	err := row.Scan(&s.ID, &s.UserID, &s.PatternID, &startedAt, &lastActiveAt, &completedAt)
+	var completedAt, pausedAt sql.NullString
+	err := row.Scan(&s.ID, &s.UserID, &s.PatternID, &s.Label, &startedAt, &lastActiveAt, &completedAt, &pausedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -87,15 +226,22 @@ func scanSession(row interface{ Scan(...any) error }) (*WorkSession, error) {
 		t, _ := time.Parse(time.RFC3339, completedAt.String)
 		s.CompletedAt = &t
 	}
+	if pausedAt.Valid {
+		t, _ := time.Parse(time.RFC3339, pausedAt.String)
+		s.PausedAt = &t
+	}
 	return s, nil
 }
 
-// FindActiveSession returns the active (non-completed) session for a user+pattern, or nil.
+// FindActiveSession returns the most recently active (non-completed) session for a
+// user+pattern, or nil.
 func FindActiveSession(db *sql.DB, userID, patternID int64) (*WorkSession, error) {
 	row := db.QueryRow(`
-		SELECT id, user_id, pattern_id, started_at, last_active_at, completed_at
+		SELECT id, user_id, pattern_id, label, started_at, last_active_at, completed_at, paused_at
 		FROM work_sessions
 		WHERE user_id = ? AND pattern_id = ? AND completed_at IS NULL
+		ORDER BY last_active_at DESC, id DESC
+		LIMIT 1
 	`, userID, patternID)
 	s, err := scanSession(row)
 	if err == sql.ErrNoRows {
@@ -104,20 +250,46 @@ func FindActiveSession(db *sql.DB, userID, patternID int64) (*WorkSession, error
 	return s, err
 }
 
+// ListActiveSessionsForPattern returns the user's active sessions on a pattern,
+// most recently active first.
+func ListActiveSessionsForPattern(db *sql.DB, userID, patternID int64) ([]WorkSession, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, pattern_id, label, started_at, last_active_at, completed_at, paused_at
+		FROM work_sessions
+		WHERE user_id = ? AND pattern_id = ? AND completed_at IS NULL
+		ORDER BY last_active_at DESC, id DESC
+	`, userID, patternID)
+	if err != nil {
+		return nil, fmt.Errorf("list active sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []WorkSession
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan session: %w", err)
+		}
+		sessions = append(sessions, *s)
+	}
+	return sessions, rows.Err()
+}
+
 // FindSessionByID loads a session by ID.
-func FindSessionByID(db *sql.DB, id int64) (*WorkSession, error) {
+func FindSessionByID(db queryer, id int64) (*WorkSession, error) {
 	row := db.QueryRow(`
-		SELECT id, user_id, pattern_id, started_at, last_active_at, completed_at
+		SELECT id, user_id, pattern_id, label, started_at, last_active_at, completed_at, paused_at
 		FROM work_sessions WHERE id = ?
 	`, id)
 	return scanSession(row)
 }
 
-// CreateWorkSession creates a new work session.
-func CreateWorkSession(db *sql.DB, userID, patternID int64) (*WorkSession, error) {
+// CreateWorkSession creates a new work session. label may be empty. Most callers
+// want StartWorkSession, which also sets up the session's progress.
+func CreateWorkSession(db execer, userID, patternID int64, label string) (*WorkSession, error) {
 	result, err := db.Exec(`
-		INSERT INTO work_sessions (user_id, pattern_id) VALUES (?, ?)
-	`, userID, patternID)
+		INSERT INTO work_sessions (user_id, pattern_id, label) VALUES (?, ?, ?)
+	`, userID, patternID, label)
 	if err != nil {
 		return nil, fmt.Errorf("create session: %w", err)
 	}
@@ -127,332 +299,1098 @@ func CreateWorkSession(db *sql.DB, userID, patternID int64) (*WorkSession, error
 		ID:           id,
 		UserID:       userID,
 		PatternID:    patternID,
+		Label:        label,
 		StartedAt:    now,
 		LastActiveAt: now,
 	}, nil
 }
 
-// MarkSessionCompleted marks a session as complete.
-func MarkSessionCompleted(db *sql.DB, id int64) error {
-	_, err := db.Exec(`
-		UPDATE work_sessions
-		SET completed_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now'),
-		    last_active_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
-		WHERE id = ?
-	`, id)
-	return err
+// StartWorkSession creates a session with its progress on the pattern's first stitch,
+// in one transaction. If the pattern has nothing to track it returns
+// ErrNothingToTrack and no session is left behind.
+func StartWorkSession(db *sql.DB, userID, patternID int64, label string, sections []PatternSection) (*WorkSession, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	session, err := CreateWorkSession(tx, userID, patternID, label)
+	if err != nil {
+		return nil, err
+	}
+	if err := InitProgress(tx, session.ID, sections); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// PauseSession marks an active session as paused. Pausing a paused or completed
+// session is a no-op.
+func PauseSession(db *sql.DB, sessionID int64) error {
+	if _, err := db.Exec(`
+		UPDATE work_sessions SET paused_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		WHERE id = ? AND completed_at IS NULL AND paused_at IS NULL
+	`, sessionID); err != nil {
+		return fmt.Errorf("pause session: %w", err)
+	}
+	return nil
+}
+
+// ResumeSession clears a session's pause and touches its activity. The pause is
+// also taken out of the current row's timing: the progress timestamp moves forward
+// by the length of the pause, so only the time worked before and after it counts.
+func ResumeSession(db *sql.DB, sessionID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE work_progress
+		SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', updated_at, (
+			SELECT (strftime('%s', 'now') - strftime('%s', paused_at)) || ' seconds'
+			FROM work_sessions WHERE id = ?1
+		))
+		WHERE session_id = ?1
+		  AND (SELECT paused_at FROM work_sessions WHERE id = ?1) IS NOT NULL
+	`, sessionID); err != nil {
+		return fmt.Errorf("shift progress time: %w", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE work_sessions
+		SET paused_at = NULL,
+		    last_active_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		WHERE id = ?
+	`, sessionID); err != nil {
+		return fmt.Errorf("resume session: %w", err)
+	}
+	return tx.Commit()
+}
+
+// MarkSessionCompleted marks a session as complete.
+func MarkSessionCompleted(db execer, id int64) error {
+	_, err := db.Exec(`
+		UPDATE work_sessions
+		SET completed_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now'),
+		    last_active_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		WHERE id = ?
+	`, id)
+	return err
+}
+
+// DeleteWorkSession removes an abandoned session along with its progress, row timings,
+// redo history and the notes written in it. Unlike completing, it leaves no trace.
+func DeleteWorkSession(db *sql.DB, sessionID, userID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var owner int64
+	if err := tx.QueryRow("SELECT user_id FROM work_sessions WHERE id = ?", sessionID).Scan(&owner); err != nil || owner != userID {
+		return fmt.Errorf("session not found or not owned by user")
+	}
+
+	for _, table := range []string{"work_progress", "row_timings", "work_history", "work_notes"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE session_id = ?", sessionID); err != nil {
+			return fmt.Errorf("delete %s: %w", table, err)
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM work_sessions WHERE id = ?", sessionID); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	return tx.Commit()
+}
+
+// TouchSessionActivity updates last_active_at.
+func TouchSessionActivity(db execer, id int64) {
+	db.Exec(`UPDATE work_sessions SET last_active_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now') WHERE id = ?`, id)
+}
+
+// --- Progress CRUD ---
+
+// GetProgress loads the work progress for a session.
+func GetProgress(db queryer, sessionID int64) (*WorkProgress, error) {
+	p := &WorkProgress{}
+	var instructionID sql.NullInt64
+	var updatedAt string
+	err := db.QueryRow(`
+		SELECT id, session_id, section_id, row_id, row_repeat_index,
+		       instruction_id, stitch_index, group_repeat_index,
+		       stitches_completed_in_row, updated_at
+		FROM work_progress WHERE session_id = ?
+	`, sessionID).Scan(
+		&p.ID, &p.SessionID, &p.SectionID, &p.RowID, &p.RowRepeatIndex,
+		&instructionID, &p.StitchIndex, &p.GroupRepeatIndex,
+		&p.StitchesCompletedInRow, &updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	p.InstructionID = instructionID.Int64
+	p.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return p, nil
+}
+
+// ErrNothingToTrack is returned when a pattern has no instructions to work through.
+var ErrNothingToTrack = errors.New("pattern has no instructions to track")
+
+// firstStitchPosition finds the first trackable stitch of the pattern.
+func firstStitchPosition(sections []PatternSection) (sectionID, rowID int64, first StitchPos, ok bool) {
+	for _, section := range sections {
+		for _, row := range section.Rows {
+			if first, ok := rowStitchAt(&row, 0); ok {
+				return section.ID, row.ID, first, true
+			}
+		}
+	}
+	return 0, 0, StitchPos{}, false
+}
+
+// InitProgress creates the initial work_progress row pointing to the first stitch of the pattern.
+// Returns ErrNothingToTrack if the pattern has no sections, no rows, or no instructions.
+func InitProgress(db execer, sessionID int64, sections []PatternSection) error {
+	sectionID, rowID, first, ok := firstStitchPosition(sections)
+	if !ok {
+		return ErrNothingToTrack
+	}
+	_, err := db.Exec(`
+		INSERT INTO work_progress
+		  (session_id, section_id, row_id, row_repeat_index,
+		   instruction_id, stitch_index, group_repeat_index, stitches_completed_in_row)
+		VALUES (?, ?, ?, 0, ?, ?, ?, 0)
+	`, sessionID, sectionID, rowID, nullableInstructionID(first.InstructionID), first.StitchIndex, first.GroupRepeatIndex)
+	return err
+}
+
+// RestartSession reopens a session and resets its progress to the first stitch of the pattern,
+// so the same session can be used to make the item again.
+func RestartSession(db *sql.DB, sessionID int64, sections []PatternSection) error {
+	sectionID, rowID, first, ok := firstStitchPosition(sections)
+	if !ok {
+		return ErrNothingToTrack
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE work_sessions
+		SET completed_at = NULL,
+		    last_active_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		WHERE id = ?
+	`, sessionID)
+	if err != nil {
+		return fmt.Errorf("reopen session: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("session %d not found", sessionID)
+	}
+
+	if _, err := tx.Exec("DELETE FROM work_progress WHERE session_id = ?", sessionID); err != nil {
+		return fmt.Errorf("clear progress: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM row_timings WHERE session_id = ?", sessionID); err != nil {
+		return fmt.Errorf("clear row timings: %w", err)
+	}
+	if err := clearWorkHistory(tx, sessionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO work_progress
+		  (session_id, section_id, row_id, row_repeat_index,
+		   instruction_id, stitch_index, group_repeat_index, stitches_completed_in_row)
+		VALUES (?, ?, ?, 0, ?, ?, ?, 0)
+	`, sessionID, sectionID, rowID, nullableInstructionID(first.InstructionID), first.StitchIndex, first.GroupRepeatIndex); err != nil {
+		return fmt.Errorf("reset progress: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// rowsQueryer is satisfied by both *sql.DB and *sql.Tx.
+type rowsQueryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// readQueryer is satisfied by both *sql.DB and *sql.Tx.
+type readQueryer interface {
+	queryer
+	rowsQueryer
+}
+
+// saveProgress updates the work_progress row for a session.
+func saveProgress(db execer, p *WorkProgress) error {
+	_, err := db.Exec(`
+		UPDATE work_progress
+		SET section_id = ?, row_id = ?, row_repeat_index = ?,
+		    instruction_id = ?, stitch_index = ?, group_repeat_index = ?,
+		    stitches_completed_in_row = ?,
+		    updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		WHERE session_id = ?
+	`, p.SectionID, p.RowID, p.RowRepeatIndex,
+		nullableInstructionID(p.InstructionID), p.StitchIndex, p.GroupRepeatIndex,
+		p.StitchesCompletedInRow, p.SessionID)
+	return err
+}
+
+// nullableInstructionID stores the synthetic position of a count-only row as NULL.
+func nullableInstructionID(id int64) *int64 {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// --- Advance / Undo ---
+
+// ErrInvalidAdvance is returned when asked to advance by fewer than one stitch.
+var ErrInvalidAdvance = errors.New("advance count must be at least 1")
+
+// stepForward computes the position one stitch after p. rowFinished is true when p
+// was the last stitch of its row repeat, and done when it was the last of the pattern
+// (next is then unset).
+func stepForward(sections []PatternSection, p *WorkProgress) (next WorkProgress, rowFinished, done bool, err error) {
+	section, sIdx := findSectionByID(sections, p.SectionID)
+	if section == nil {
+		return next, false, false, fmt.Errorf("section %d not found", p.SectionID)
+	}
+	row, rIdx := findRowByID(section.Rows, p.RowID)
+	if row == nil {
+		return next, false, false, fmt.Errorf("row %d not found", p.RowID)
+	}
+
+	curIdx := rowFlatIndex(row, p)
+
+	next = *p
+
+	if curIdx+1 < rowStitchCount(row) {
+		// Advance within same row repeat.
+		setToStitch(&next, row, curIdx+1)
+		return next, false, false, nil
+	}
+
+	// Current row repeat exhausted — try next repeat or next row.
+	next.StitchesCompletedInRow = 0
+
+	if p.RowRepeatIndex+1 < row.RepeatCount {
+		// Next repeat of the same row.
+		next.RowRepeatIndex = p.RowRepeatIndex + 1
+		setToFirstStitch(&next, row)
+		return next, true, false, nil
+	}
+
+	// All row repeats done — move on to the next row with something to work, later
+	// in this section or in a later one. Empty rows and sections are passed over
+	// wherever they fall, so one can't hide the rows after it.
+	if seekFirstStitch(&next, sections, sIdx, rIdx+1) {
+		return next, true, false, nil
+	}
+
+	// Pattern complete!
+	return WorkProgress{}, true, true, nil
+}
+
+// AdvanceProgress moves progress forward by one stitch.
+// Returns true if the pattern is now complete.
+//
+// The session and progress are read inside the write transaction, which takes the
+// write lock up front (_txlock=immediate), so two rapid taps are serialized: the
+// second sees the first's new position and advances one further, never the same
+// stitch twice.
+func AdvanceProgress(db *sql.DB, sessionID int64) (completed bool, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	session, err := FindSessionByID(tx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	if session.CompletedAt != nil {
+		return true, nil // already done
+	}
+
+	progress, err := GetProgress(tx, sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	// The pattern itself isn't written here; reading it on another connection is
+	// fine under WAL.
+	_, sections, err := LoadPatternFull(db, session.PatternID)
+	if err != nil {
+		return false, err
+	}
+	// If the pattern was edited under the session, carry on from the nearest
+	// stitch that still exists.
+	progress, err = repairProgress(tx, sections, progress)
+	if err != nil {
+		return false, err
+	}
+
+	next, rowFinished, done, err := stepForward(sections, progress)
+	if err != nil {
+		return false, err
+	}
+
+	recordRowTiming(tx, progress, rowFinished)
+	if err := clearWorkHistory(tx, sessionID); err != nil {
+		return false, err
+	}
+
+	if done {
+		if err := MarkSessionCompleted(tx, sessionID); err != nil {
+			return false, err
+		}
+	} else {
+		if err := saveProgress(tx, &next); err != nil {
+			return false, err
+		}
+		TouchSessionActivity(tx, sessionID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return done, nil
+}
+
+// AdvanceProgressBy moves progress forward by n stitches in one go, e.g. to finish a
+// long round without tapping through it. The pattern is loaded once and the new
+// position saved in a single transaction. Advancing past the end completes the
+// pattern. Returns true if the pattern is now complete.
+func AdvanceProgressBy(db *sql.DB, sessionID int64, n int) (completed bool, err error) {
+	if n < 1 {
+		return false, ErrInvalidAdvance
+	}
+
+	// As in AdvanceProgress, read and write under one transaction so concurrent
+	// advances are serialized.
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	session, err := FindSessionByID(tx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	if session.CompletedAt != nil {
+		return true, nil // already done
+	}
+
+	progress, err := GetProgress(tx, sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	_, sections, err := LoadPatternFull(db, session.PatternID)
+	if err != nil {
+		return false, err
+	}
+	progress, err = repairProgress(tx, sections, progress)
+	if err != nil {
+		return false, err
+	}
+
+	// Only the starting row gets a timing: rows skipped over entirely weren't
+	// worked stitch by stitch, so their (near zero) time would skew the average.
+	cur := *progress
+	startRowFinished := false
+	for i := 0; i < n; i++ {
+		next, rowFinished, last, err := stepForward(sections, &cur)
+		if err != nil {
+			return false, err
+		}
+		if rowFinished && cur.RowID == progress.RowID && cur.RowRepeatIndex == progress.RowRepeatIndex {
+			startRowFinished = true
+		}
+		if last {
+			completed = true
+			break
+		}
+		cur = next
+	}
+
+	recordRowTiming(tx, progress, startRowFinished)
+	if err := saveProgress(tx, &cur); err != nil {
+		return false, fmt.Errorf("save progress: %w", err)
+	}
+	if err := clearWorkHistory(tx, sessionID); err != nil {
+		return false, err
+	}
+	completedAt := "completed_at"
+	if completed {
+		completedAt = "strftime('%Y-%m-%dT%H:%M:%SZ', 'now')"
+	}
+	if _, err := tx.Exec(`
+		UPDATE work_sessions
+		SET completed_at = `+completedAt+`,
+		    last_active_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		WHERE id = ?
+	`, sessionID); err != nil {
+		return false, fmt.Errorf("update session: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return completed, nil
+}
+
+// --- Row timings ---
+
+// RowTimingIdleThreshold is the longest gap between two stitches that still counts as
+// working time. Longer gaps are treated as a break and left out of row timings.
+const RowTimingIdleThreshold = 30 * time.Minute
+
+// recordRowTiming adds the time since the previous advance to the current row's
+// working time, and marks the row finished when this advance completes it.
+// Timing is best-effort and never blocks progress.
+func recordRowTiming(db execer, progress *WorkProgress, rowFinished bool) {
+	gap := time.Since(progress.UpdatedAt)
+	if gap < 0 || gap > RowTimingIdleThreshold || progress.UpdatedAt.IsZero() {
+		gap = 0
+	}
+
+	var completedAt any
+	if rowFinished {
+		completedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	db.Exec(`
+		INSERT INTO row_timings (session_id, row_id, row_repeat_index, active_seconds, completed_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(session_id, row_id, row_repeat_index) DO UPDATE SET
+			active_seconds = active_seconds + excluded.active_seconds,
+			completed_at = COALESCE(excluded.completed_at, completed_at)
+	`, progress.SessionID, progress.RowID, progress.RowRepeatIndex, int64(gap.Seconds()), completedAt)
+}
+
+// AverageRowDuration returns the mean working time of the session's finished rows,
+// or 0 if no row has been finished yet.
+func AverageRowDuration(db *sql.DB, sessionID int64) (time.Duration, error) {
+	var avg sql.NullFloat64
+	err := db.QueryRow(`
+		SELECT AVG(active_seconds) FROM row_timings
+		WHERE session_id = ? AND completed_at IS NOT NULL
+	`, sessionID).Scan(&avg)
+	if err != nil {
+		return 0, fmt.Errorf("average row duration: %w", err)
+	}
+	if !avg.Valid {
+		return 0, nil
+	}
+	return time.Duration(avg.Float64 * float64(time.Second)), nil
+}
+
+// rowsRemaining counts the rows (repeats included) still to be worked, counting the
+// current one. Rows with nothing to track are skipped, as work mode skips them.
+func rowsRemaining(sections []PatternSection, progress *WorkProgress) int {
+	remaining := 0
+	reached := false
+	for _, section := range sections {
+		for _, row := range section.Rows {
+			if row.ID == progress.RowID {
+				reached = true
+				remaining += row.RepeatCount - progress.RowRepeatIndex
+				continue
+			}
+			if reached && rowStitchCount(&row) > 0 {
+				remaining += row.RepeatCount
+			}
+		}
+	}
+	return remaining
+}
+
+// rowsCompleted counts the row repeats worked before the current one, skipping rows
+// with nothing to work just as rowsRemaining does.
+func rowsCompleted(sections []PatternSection, progress *WorkProgress) int {
+	completed := 0
+	for _, section := range sections {
+		for _, row := range section.Rows {
+			if row.ID == progress.RowID {
+				return completed + progress.RowRepeatIndex
+			}
+			if rowStitchCount(&row) > 0 {
+				completed += row.RepeatCount
+			}
+		}
+	}
+	return completed
+}
+
+// stepBackward computes the position one stitch before p. ok is false at the very
+// first stitch of the pattern.
+func stepBackward(sections []PatternSection, p *WorkProgress) (prev WorkProgress, ok bool, err error) {
+	section, sIdx := findSectionByID(sections, p.SectionID)
+	if section == nil {
+		return prev, false, fmt.Errorf("section %d not found", p.SectionID)
+	}
+	row, rIdx := findRowByID(section.Rows, p.RowID)
+	if row == nil {
+		return prev, false, fmt.Errorf("row %d not found", p.RowID)
+	}
+
+	curIdx := rowFlatIndex(row, p)
+
+	prev = *p
+
+	if curIdx > 0 {
+		// Step back within same row repeat.
+		setToStitch(&prev, row, curIdx-1)
+		return prev, true, nil
+	}
+
+	// At first position in this row repeat.
+	if p.RowRepeatIndex > 0 {
+		// Go to last stitch of previous repeat.
+		prev.RowRepeatIndex = p.RowRepeatIndex - 1
+		setToLastStitch(&prev, row)
+		return prev, true, nil
+	}
+
+	// At first repeat of this row — go back to the last stitch of the nearest earlier
+	// row with something to work, skipping empty rows and sections as advance does.
+	if seekLastStitch(&prev, sections, sIdx, rIdx-1) {
+		return prev, true, nil
+	}
+
+	// At the very beginning.
+	return prev, false, nil
+}
+
+// UndoProgress moves progress backward by one stitch through sections, the session's
+// pattern as loaded by LoadPatternFull, so a caller that renders the result afterwards
+// loads the pattern once per click. No-op at the very beginning. The position undone
+// from is kept so RedoProgress can return to it.
+//
+// As in AdvanceProgress, the session and progress are read inside the write
+// transaction, so two rapid undos step back two stitches rather than both
+// stepping back from the same one.
+func UndoProgress(db *sql.DB, sessionID int64, sections []PatternSection) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	session, err := FindSessionByID(tx, sessionID)
+	if err != nil {
+		return err
+	}
+	// Allow undo on a completed session (un-complete it).
+	progress, err := GetProgress(tx, sessionID)
+	if err != nil {
+		return err
+	}
+	if progress, err = repairProgress(tx, sections, progress); err != nil {
+		return err
+	}
+
+	prev, ok, err := stepBackward(sections, progress)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// Keep any repair made to the position.
+		return tx.Commit()
+	}
+
+	if err := pushWorkHistory(tx, progress, session.CompletedAt != nil); err != nil {
+		return err
+	}
+	if err := saveProgress(tx, &prev); err != nil {
+		return fmt.Errorf("save progress: %w", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE work_sessions
+		SET completed_at = NULL,
+		    last_active_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		WHERE id = ?
+	`, sessionID); err != nil {
+		return fmt.Errorf("update session: %w", err)
+	}
+	return tx.Commit()
+}
+
+// --- Redo ---
+
+// WorkHistoryLimit is how many undone positions are kept per session for redo.
+const WorkHistoryLimit = 50
+
+// ErrNothingToRedo is returned by RedoProgress when there is no undone step to replay.
+var ErrNothingToRedo = errors.New("nothing to redo")
+
+// pushWorkHistory records the position an undo is leaving, dropping the oldest
+// entries beyond WorkHistoryLimit.
+func pushWorkHistory(tx *sql.Tx, p *WorkProgress, sessionCompleted bool) error {
+	if _, err := tx.Exec(`
+		INSERT INTO work_history
+		  (session_id, section_id, row_id, row_repeat_index,
+		   instruction_id, stitch_index, group_repeat_index, stitches_completed_in_row, session_completed)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.SessionID, p.SectionID, p.RowID, p.RowRepeatIndex,
+		nullableInstructionID(p.InstructionID), p.StitchIndex, p.GroupRepeatIndex,
+		p.StitchesCompletedInRow, sessionCompleted); err != nil {
+		return fmt.Errorf("save undo history: %w", err)
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM work_history
+		WHERE session_id = ? AND id NOT IN (
+			SELECT id FROM work_history WHERE session_id = ? ORDER BY id DESC LIMIT ?
+		)
+	`, p.SessionID, p.SessionID, WorkHistoryLimit); err != nil {
+		return fmt.Errorf("trim undo history: %w", err)
+	}
+	return nil
+}
+
+// clearWorkHistory empties a session's redo stack; any move other than undo or redo
+// makes the undone steps meaningless.
+func clearWorkHistory(db execer, sessionID int64) error {
+	if _, err := db.Exec("DELETE FROM work_history WHERE session_id = ?", sessionID); err != nil {
+		return fmt.Errorf("clear undo history: %w", err)
+	}
+	return nil
+}
+
+// CanRedo reports whether the session has an undone step to replay.
+func CanRedo(db *sql.DB, sessionID int64) bool {
+	var n int
+	db.QueryRow("SELECT COUNT(*) FROM work_history WHERE session_id = ?", sessionID).Scan(&n)
+	return n > 0
 }
 
-// TouchSessionActivity updates last_active_at.
-func TouchSessionActivity(db *sql.DB, id int64) {
-	db.Exec(`UPDATE work_sessions SET last_active_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now') WHERE id = ?`, id)
-}
+// RedoProgress returns to the position of the most recent undo. If the pattern was
+// edited since and that position no longer exists, the redo stack is dropped and
+// ErrNothingToRedo returned. Either way the current position is repaired first, as
+// for undo, so a redo with nothing to replay still leaves a valid position.
+func RedoProgress(db *sql.DB, sessionID int64) error {
+	// Read and write under one transaction, as UndoProgress does, so concurrent
+	// redos each pop their own step.
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-// --- Progress CRUD ---
+	session, err := FindSessionByID(tx, sessionID)
+	if err != nil {
+		return err
+	}
+	_, sections, err := LoadPatternFull(db, session.PatternID)
+	if err != nil {
+		return err
+	}
+	progress, err := GetProgress(tx, sessionID)
+	if err != nil {
+		return err
+	}
+	if _, err := repairProgress(tx, sections, progress); err != nil {
+		return err
+	}
 
-// GetProgress loads the work progress for a session.
-func GetProgress(db *sql.DB, sessionID int64) (*WorkProgress, error) {
-	p := &WorkProgress{}
-	var updatedAt string
-	err := db.QueryRow(`
-		SELECT id, session_id, section_id, row_id, row_repeat_index,
+	var historyID int64
+	var instructionID sql.NullInt64
+	var sessionCompleted bool
+	p := WorkProgress{SessionID: sessionID}
+	err = tx.QueryRow(`
+		SELECT id, section_id, row_id, row_repeat_index,
 		       instruction_id, stitch_index, group_repeat_index,
-		       stitches_completed_in_row, updated_at
-		FROM work_progress WHERE session_id = ?
+		       stitches_completed_in_row, session_completed
+		FROM work_history WHERE session_id = ?
+		ORDER BY id DESC LIMIT 1
 	`, sessionID).Scan(
-		&p.ID, &p.SessionID, &p.SectionID, &p.RowID, &p.RowRepeatIndex,
-		&p.InstructionID, &p.StitchIndex, &p.GroupRepeatIndex,
-		&p.StitchesCompletedInRow, &updatedAt,
+		&historyID, &p.SectionID, &p.RowID, &p.RowRepeatIndex,
+		&instructionID, &p.StitchIndex, &p.GroupRepeatIndex,
+		&p.StitchesCompletedInRow, &sessionCompleted,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Keep any repair made to the position.
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		return ErrNothingToRedo
+	}
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("load undo history: %w", err)
 	}
-	p.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
-	return p, nil
-}
+	p.InstructionID = instructionID.Int64
 
-// InitProgress creates the initial work_progress row pointing to the first stitch of the pattern.
-// Returns an error if the pattern has no sections, no rows, or no instructions.
-func InitProgress(db *sql.DB, sessionID int64, sections []PatternSection) error {
-	for _, section := range sections {
-		for _, row := range section.Rows {
-			flat := FlattenInstructions(row.Instructions)
-			if len(flat) == 0 {
-				continue
-			}
-			first := flat[0]
-			_, err := db.Exec(`
-				INSERT INTO work_progress
-				  (session_id, section_id, row_id, row_repeat_index,
-				   instruction_id, stitch_index, group_repeat_index, stitches_completed_in_row)
-				VALUES (?, ?, ?, 0, ?, ?, ?, 0)
-			`, sessionID, section.ID, row.ID, first.InstructionID, first.StitchIndex, first.GroupRepeatIndex)
+	if !positionExists(sections, &p) {
+		if err := clearWorkHistory(tx, sessionID); err != nil {
 			return err
 		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		return ErrNothingToRedo
+	}
+
+	if _, err := tx.Exec("DELETE FROM work_history WHERE id = ?", historyID); err != nil {
+		return fmt.Errorf("pop undo history: %w", err)
+	}
+	if err := saveProgress(tx, &p); err != nil {
+		return fmt.Errorf("save progress: %w", err)
+	}
+	completedAt := "NULL"
+	if sessionCompleted {
+		completedAt = "strftime('%Y-%m-%dT%H:%M:%SZ', 'now')"
+	}
+	if _, err := tx.Exec(`
+		UPDATE work_sessions
+		SET completed_at = `+completedAt+`,
+		    last_active_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		WHERE id = ?
+	`, sessionID); err != nil {
+		return fmt.Errorf("update session: %w", err)
 	}
-	return fmt.Errorf("pattern has no instructions to track")
+	return tx.Commit()
 }
 
-// saveProgress updates the work_progress row for a session.
-func saveProgress(db *sql.DB, p *WorkProgress) error {
-	_, err := db.Exec(`
-		UPDATE work_progress
-		SET section_id = ?, row_id = ?, row_repeat_index = ?,
-		    instruction_id = ?, stitch_index = ?, group_repeat_index = ?,
-		    stitches_completed_in_row = ?,
-		    updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
-		WHERE session_id = ?
-	`, p.SectionID, p.RowID, p.RowRepeatIndex,
-		p.InstructionID, p.StitchIndex, p.GroupRepeatIndex,
-		p.StitchesCompletedInRow, p.SessionID)
-	return err
+// --- Bookmarks ---
+
+// ErrNoBookmark is returned when jumping to a bookmark on a session that has none.
+var ErrNoBookmark = errors.New("no bookmark set")
+
+// SetBookmark saves the session's current position as its bookmark, replacing any
+// earlier one.
+func SetBookmark(db *sql.DB, sessionID int64) error {
+	result, err := db.Exec(`
+		INSERT OR REPLACE INTO work_bookmarks (session_id, section_id, row_id, row_repeat_index,
+		                                       instruction_id, stitch_index, group_repeat_index,
+		                                       stitches_completed_in_row)
+		SELECT session_id, section_id, row_id, row_repeat_index,
+		       instruction_id, stitch_index, group_repeat_index,
+		       stitches_completed_in_row
+		FROM work_progress WHERE session_id = ?
+	`, sessionID)
+	if err != nil {
+		return fmt.Errorf("set bookmark: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
 }
 
-// --- Advance / Undo ---
+// GetBookmark loads the session's bookmarked position, or ErrNoBookmark.
+func GetBookmark(db queryer, sessionID int64) (*WorkProgress, error) {
+	p := &WorkProgress{SessionID: sessionID}
+	var instructionID sql.NullInt64
+	err := db.QueryRow(`
+		SELECT section_id, row_id, row_repeat_index,
+		       instruction_id, stitch_index, group_repeat_index,
+		       stitches_completed_in_row
+		FROM work_bookmarks WHERE session_id = ?
+	`, sessionID).Scan(
+		&p.SectionID, &p.RowID, &p.RowRepeatIndex,
+		&instructionID, &p.StitchIndex, &p.GroupRepeatIndex,
+		&p.StitchesCompletedInRow,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoBookmark
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load bookmark: %w", err)
+	}
+	p.InstructionID = instructionID.Int64
+	return p, nil
+}
 
-// AdvanceProgress moves progress forward by one stitch.
-// Returns true if the pattern is now complete.
-func AdvanceProgress(db *sql.DB, sessionID int64) (completed bool, err error) {
+// JumpToBookmark moves the session back (or forward) to its bookmark, un-completing
+// it if it was finished. If the pattern was edited since, the bookmark snaps to the
+// nearest position still there, as progress does. The bookmark is kept so the
+// crafter can jump again; the redo stack is dropped like any other move.
+func JumpToBookmark(db *sql.DB, sessionID int64) error {
 	session, err := FindSessionByID(db, sessionID)
 	if err != nil {
-		return false, err
+		return err
 	}
-	if session.CompletedAt != nil {
-		return true, nil // already done
+	bookmark, err := GetBookmark(db, sessionID)
+	if err != nil {
+		return err
 	}
 
-	progress, err := GetProgress(db, sessionID)
+	_, sections, err := LoadPatternFull(db, session.PatternID)
 	if err != nil {
-		return false, err
+		return err
+	}
+	target, ok := nearestPosition(sections, bookmark)
+	if !ok {
+		return ErrNothingToTrack
 	}
 
-	_, sections, err := LoadPatternFull(db, session.PatternID)
+	tx, err := db.Begin()
 	if err != nil {
-		return false, err
+		return err
 	}
+	defer tx.Rollback()
 
-	section, sIdx := findSectionByID(sections, progress.SectionID)
+	if err := clearWorkHistory(tx, sessionID); err != nil {
+		return err
+	}
+	if err := saveProgress(tx, &target); err != nil {
+		return fmt.Errorf("save progress: %w", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE work_sessions
+		SET completed_at = NULL,
+		    last_active_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		WHERE id = ?
+	`, sessionID); err != nil {
+		return fmt.Errorf("update session: %w", err)
+	}
+	return tx.Commit()
+}
+
+// PositionLabel names the row a position is on, e.g. "Row 4", for showing where a
+// bookmark points. It is "?" if the position is no longer in the pattern.
+func PositionLabel(sections []PatternSection, p *WorkProgress) string {
+	section, _ := findSectionByID(sections, p.SectionID)
 	if section == nil {
-		return false, fmt.Errorf("section %d not found", progress.SectionID)
+		return "?"
 	}
-	row, rIdx := findRowByID(section.Rows, progress.RowID)
-	if row == nil {
-		return false, fmt.Errorf("row %d not found", progress.RowID)
+	label := computeCurrentRowLabel(section.Rows, p.RowID, p.RowRepeatIndex)
+	if len(sections) > 1 {
+		label = section.Name + " · " + label
 	}
+	return label
+}
 
-	flat := FlattenInstructions(row.Instructions)
-	curIdx := FindFlatIndex(flat, progress)
+// positionExists reports whether p still points at a stitch of the pattern.
+func positionExists(sections []PatternSection, p *WorkProgress) bool {
+	section, _ := findSectionByID(sections, p.SectionID)
+	if section == nil {
+		return false
+	}
+	row, _ := findRowByID(section.Rows, p.RowID)
+	if row == nil || p.RowRepeatIndex >= row.RepeatCount {
+		return false
+	}
+	return rowFlatIndex(row, p) >= 0
+}
 
-	newProg := *progress
+// --- Repair ---
 
-	if curIdx+1 < len(flat) {
-		// Advance within same row repeat.
-		next := flat[curIdx+1]
-		newProg.InstructionID = next.InstructionID
-		newProg.StitchIndex = next.StitchIndex
-		newProg.GroupRepeatIndex = next.GroupRepeatIndex
-		newProg.StitchesCompletedInRow = curIdx + 1
-		if err := saveProgress(db, &newProg); err != nil {
-			return false, err
+// nearestPosition finds where p should point after the pattern was edited under it.
+// A stitch that moved or disappeared snaps to the same count of stitches into the row
+// (clamped to its end), a row with no stitches left moves on to the next row that has
+// some, and a deleted row or section falls back to the start of its section or of the
+// pattern. ok is false if the pattern has nothing left to track.
+func nearestPosition(sections []PatternSection, p *WorkProgress) (next WorkProgress, ok bool) {
+	next = *p
+	section, sIdx := findSectionByID(sections, p.SectionID)
+	if section != nil {
+		row, rIdx := findRowByID(section.Rows, p.RowID)
+		if row != nil {
+			if n := rowStitchCount(row); n > 0 {
+				next.RowRepeatIndex = min(max(p.RowRepeatIndex, 0), max(row.RepeatCount-1, 0))
+				idx := rowFlatIndex(row, p)
+				if idx < 0 {
+					idx = min(max(p.StitchesCompletedInRow, 0), n-1)
+				}
+				setToStitch(&next, row, idx)
+				return next, true
+			}
+			if seekFirstStitch(&next, sections, sIdx, rIdx+1) {
+				return next, true
+			}
+		} else if seekFirstStitch(&next, sections, sIdx, 0) {
+			return next, true
 		}
-		TouchSessionActivity(db, sessionID)
-		return false, nil
 	}
+	return next, seekFirstStitch(&next, sections, 0, 0)
+}
 
-	// Current row repeat exhausted — try next repeat or next row.
-	newProg.StitchesCompletedInRow = 0
-
-	if progress.RowRepeatIndex+1 < row.RepeatCount {
-		// Next repeat of the same row.
-		newProg.RowRepeatIndex = progress.RowRepeatIndex + 1
-		setToFirstStitch(&newProg, flat)
-		if err := saveProgress(db, &newProg); err != nil {
-			return false, err
+// seekFirstStitch points p at the first stitch found from row rIdx of section sIdx
+// onward, reporting whether there was one.
+func seekFirstStitch(p *WorkProgress, sections []PatternSection, sIdx, rIdx int) bool {
+	for si := sIdx; si < len(sections); si++ {
+		rows := sections[si].Rows
+		for ri := rIdx; ri < len(rows); ri++ {
+			if rowStitchCount(&rows[ri]) > 0 {
+				p.SectionID = sections[si].ID
+				p.RowID = rows[ri].ID
+				p.RowRepeatIndex = 0
+				setToFirstStitch(p, &rows[ri])
+				return true
+			}
 		}
-		TouchSessionActivity(db, sessionID)
-		return false, nil
+		rIdx = 0
 	}
+	return false
+}
 
-	// All row repeats done — find next row in section.
-	newProg.RowRepeatIndex = 0
-	if rIdx+1 < len(section.Rows) {
-		nextRow := &section.Rows[rIdx+1]
-		nextFlat := FlattenInstructions(nextRow.Instructions)
-		if len(nextFlat) > 0 {
-			newProg.RowID = nextRow.ID
-			setToFirstStitch(&newProg, nextFlat)
-			if err := saveProgress(db, &newProg); err != nil {
-				return false, err
-			}
-			TouchSessionActivity(db, sessionID)
-			return false, nil
-		}
-	}
-
-	// Section exhausted — find next section.
-	if sIdx+1 < len(sections) {
-		for si := sIdx + 1; si < len(sections); si++ {
-			nextSection := &sections[si]
-			for ri := range nextSection.Rows {
-				nextRow := &nextSection.Rows[ri]
-				nextFlat := FlattenInstructions(nextRow.Instructions)
-				if len(nextFlat) > 0 {
-					newProg.SectionID = nextSection.ID
-					newProg.RowID = nextRow.ID
-					setToFirstStitch(&newProg, nextFlat)
-					if err := saveProgress(db, &newProg); err != nil {
-						return false, err
-					}
-					TouchSessionActivity(db, sessionID)
-					return false, nil
-				}
+// seekLastStitch points p at the last stitch of the last repeat of the nearest row
+// with something to work, searching back from row rIdx of section sIdx (-1 starts at
+// the section before), and reports whether there was one.
+func seekLastStitch(p *WorkProgress, sections []PatternSection, sIdx, rIdx int) bool {
+	for si := sIdx; si >= 0; si-- {
+		rows := sections[si].Rows
+		for ri := min(rIdx, len(rows)-1); ri >= 0; ri-- {
+			if rowStitchCount(&rows[ri]) > 0 {
+				p.SectionID = sections[si].ID
+				p.RowID = rows[ri].ID
+				p.RowRepeatIndex = max(rows[ri].RepeatCount-1, 0)
+				setToLastStitch(p, &rows[ri])
+				return true
 			}
 		}
+		if si > 0 {
+			rIdx = len(sections[si-1].Rows) - 1
+		}
 	}
+	return false
+}
 
-	// Pattern complete!
-	if err := MarkSessionCompleted(db, sessionID); err != nil {
-		return false, err
+// repairProgress moves p to the nearest valid position if the pattern was edited
+// so that it no longer exists, saving the new position. It returns the position to
+// carry on from, which is p itself when nothing needed fixing.
+func repairProgress(db execer, sections []PatternSection, p *WorkProgress) (*WorkProgress, error) {
+	if positionExists(sections, p) {
+		return p, nil
+	}
+	next, ok := nearestPosition(sections, p)
+	if !ok {
+		return nil, ErrNothingToTrack
 	}
-	return true, nil
+	if err := saveProgress(db, &next); err != nil {
+		return nil, fmt.Errorf("repair progress: %w", err)
+	}
+	log.Printf("work session %d: progress moved from section %d row %d to section %d row %d stitch %d after a pattern edit",
+		p.SessionID, p.SectionID, p.RowID, next.SectionID, next.RowID, next.StitchesCompletedInRow)
+	return &next, nil
 }
 
-// UndoProgress moves progress backward by one stitch. No-op at the very beginning.
-func UndoProgress(db *sql.DB, sessionID int64) error {
-	session, err := FindSessionByID(db, sessionID)
+// RepairProgress makes sure a session's progress still points at a stitch of its
+// pattern, snapping it to the nearest valid position if the section, row or
+// instruction it pointed at was edited away. It is a no-op for a valid position and
+// returns ErrNothingToTrack if the pattern has no stitches left at all.
+func RepairProgress(db *sql.DB, sessionID int64) error {
+	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
-	// Allow undo on a completed session (un-complete it).
-	progress, err := GetProgress(db, sessionID)
+	defer tx.Rollback()
+
+	session, err := FindSessionByID(tx, sessionID)
 	if err != nil {
 		return err
 	}
-
-	_, sections, err := LoadPatternFull(db, session.PatternID)
+	progress, err := GetProgress(tx, sessionID)
 	if err != nil {
 		return err
 	}
-
-	section, sIdx := findSectionByID(sections, progress.SectionID)
-	if section == nil {
-		return fmt.Errorf("section %d not found", progress.SectionID)
+	_, sections, err := LoadPatternFull(db, session.PatternID)
+	if err != nil {
+		return err
 	}
-	row, rIdx := findRowByID(section.Rows, progress.RowID)
-	if row == nil {
-		return fmt.Errorf("row %d not found", progress.RowID)
+	if _, err := repairProgress(tx, sections, progress); err != nil {
+		return err
 	}
+	return tx.Commit()
+}
 
-	flat := FlattenInstructions(row.Instructions)
-	curIdx := FindFlatIndex(flat, progress)
-
-	newProg := *progress
-
-	if curIdx > 0 {
-		// Step back within same row repeat.
-		prev := flat[curIdx-1]
-		newProg.InstructionID = prev.InstructionID
-		newProg.StitchIndex = prev.StitchIndex
-		newProg.GroupRepeatIndex = prev.GroupRepeatIndex
-		newProg.StitchesCompletedInRow = curIdx - 1
-		if err := saveProgress(db, &newProg); err != nil {
-			return err
-		}
-		// Un-complete session if it was marked done.
-		if session.CompletedAt != nil {
-			db.Exec(`UPDATE work_sessions SET completed_at = NULL WHERE id = ?`, sessionID)
-		}
-		TouchSessionActivity(db, sessionID)
-		return nil
+// RepairPatternSessions runs RepairProgress for every active session on a pattern,
+// after an edit that may have removed the section, row or stitch one was on. Sessions
+// that still point at a valid stitch are left alone, so it is safe to call after any
+// edit. Failures are logged rather than returned: the edit itself has succeeded, and
+// work mode repairs progress again on its next move.
+func RepairPatternSessions(db *sql.DB, patternID int64) {
+	rows, err := db.Query(
+		"SELECT id FROM work_sessions WHERE pattern_id = ? AND completed_at IS NULL", patternID,
+	)
+	if err != nil {
+		log.Printf("pattern %d: list sessions to repair: %v", patternID, err)
+		return
 	}
-
-	// At first position in this row repeat.
-	if progress.RowRepeatIndex > 0 {
-		// Go to last stitch of previous repeat.
-		newProg.RowRepeatIndex = progress.RowRepeatIndex - 1
-		setToLastStitch(&newProg, flat)
-		if err := saveProgress(db, &newProg); err != nil {
-			return err
-		}
-		if session.CompletedAt != nil {
-			db.Exec(`UPDATE work_sessions SET completed_at = NULL WHERE id = ?`, sessionID)
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			log.Printf("pattern %d: scan session to repair: %v", patternID, err)
+			return
 		}
-		TouchSessionActivity(db, sessionID)
-		return nil
+		ids = append(ids, id)
 	}
+	rows.Close()
 
-	// At first repeat of this row — go to previous row in section.
-	if rIdx > 0 {
-		prevRow := &section.Rows[rIdx-1]
-		prevFlat := FlattenInstructions(prevRow.Instructions)
-		if len(prevFlat) > 0 {
-			newProg.RowID = prevRow.ID
-			newProg.RowRepeatIndex = prevRow.RepeatCount - 1
-			setToLastStitch(&newProg, prevFlat)
-			if err := saveProgress(db, &newProg); err != nil {
-				return err
-			}
-			if session.CompletedAt != nil {
-				db.Exec(`UPDATE work_sessions SET completed_at = NULL WHERE id = ?`, sessionID)
-			}
-			TouchSessionActivity(db, sessionID)
-			return nil
-		}
-	}
-
-	// At first row of section — go to previous section.
-	if sIdx > 0 {
-		for si := sIdx - 1; si >= 0; si-- {
-			prevSection := &sections[si]
-			for ri := len(prevSection.Rows) - 1; ri >= 0; ri-- {
-				prevRow := &prevSection.Rows[ri]
-				prevFlat := FlattenInstructions(prevRow.Instructions)
-				if len(prevFlat) > 0 {
-					newProg.SectionID = prevSection.ID
-					newProg.RowID = prevRow.ID
-					newProg.RowRepeatIndex = prevRow.RepeatCount - 1
-					setToLastStitch(&newProg, prevFlat)
-					if err := saveProgress(db, &newProg); err != nil {
-						return err
-					}
-					if session.CompletedAt != nil {
-						db.Exec(`UPDATE work_sessions SET completed_at = NULL WHERE id = ?`, sessionID)
-					}
-					TouchSessionActivity(db, sessionID)
-					return nil
-				}
-			}
+	for _, id := range ids {
+		if err := RepairProgress(db, id); err != nil {
+			log.Printf("work session %d: repair progress: %v", id, err)
 		}
 	}
-
-	// At the very beginning — no-op.
-	return nil
 }
 
 // --- Session summary for dashboard ---
 
 // SessionSummary holds display data for an active session on the dashboard.
 type SessionSummary struct {
-	SessionID   int64
-	PatternID   int64
-	PatternName string
+	SessionID    int64
+	PatternID    int64
+	PatternName  string
+	SessionLabel string
+	PausedAt     *time.Time
 	// Progress text
-	SectionName         string
-	RowLabel            string
-	RowNumberInSection  int
-	TotalRowsInSection  int
-	StitchesCompleted   int
-	ExpectedStitches    int
+	SectionName        string
+	RowLabel           string
+	RowNumberInSection int
+	TotalRowsInSection int
+	SectionNumber      int // 1-based; 0 if the section is not found
+	TotalSections      int
+	RowRepeatIndex     int // 0-based
+	RowRepeatCount     int
+	StitchesCompleted  int
+	ExpectedStitches   int
+	// Journal
+	NoteCount  int
+	LatestNote string
+	// Pace, for EstimatedCompletion
+	StartedAt           time.Time
+	LastActiveAt        time.Time
+	PatternStitchesDone int
+	PatternStitches     int // TotalPatternStitches
+	// Set for finished sessions by ListCompletedSessions
+	CompletedAt *time.Time
+	Duration    time.Duration // completed_at minus started_at, pauses included
 }
 
 // ListSessionSummaries returns summaries of all active sessions for a user.
 func ListSessionSummaries(db *sql.DB, userID int64) ([]SessionSummary, error) {
 	rows, err := db.Query(`
-		SELECT ws.id, ws.pattern_id, p.name
+		SELECT ws.id, ws.pattern_id, p.name, ws.label, ws.paused_at, ws.started_at, ws.last_active_at
 		FROM work_sessions ws
 		JOIN patterns p ON ws.pattern_id = p.id
 		WHERE ws.user_id = ? AND ws.completed_at IS NULL
-		ORDER BY ws.last_active_at DESC
+		ORDER BY ws.paused_at IS NOT NULL, ws.last_active_at DESC
 	`, userID)
 	if err != nil {
 		return nil, err
@@ -463,11 +1401,15 @@ func ListSessionSummaries(db *sql.DB, userID int64) ([]SessionSummary, error) {
 		sessionID   int64
 		patternID   int64
 		patternName string
+		label       string
+		pausedAt    sql.NullString
+		startedAt   string
+		lastActive  string
 	}
 	var stubs []stub
 	for rows.Next() {
 		var s stub
-		if err := rows.Scan(&s.sessionID, &s.patternID, &s.patternName); err != nil {
+		if err := rows.Scan(&s.sessionID, &s.patternID, &s.patternName, &s.label, &s.pausedAt, &s.startedAt, &s.lastActive); err != nil {
 			return nil, err
 		}
 		stubs = append(stubs, s)
@@ -487,45 +1429,149 @@ func ListSessionSummaries(db *sql.DB, userID int64) ([]SessionSummary, error) {
 			continue
 		}
 
-		section, _ := findSectionByID(sections, progress.SectionID)
+		section, sIdx := findSectionByID(sections, progress.SectionID)
 		var sectionName, rowLabel string
 		var rowNum, totalRows, stitchesDone, expectedStitches int
+		repeatCount := 1
 
 		if section != nil {
 			sectionName = section.Name
 			row, _ := findRowByID(section.Rows, progress.RowID)
-			rowLabel = computeRowLabel(section.Rows, progress.RowID)
+			rowLabel = computeCurrentRowLabel(section.Rows, progress.RowID, progress.RowRepeatIndex)
 			// Count rows in section (counting repeats)
 			totalRows, rowNum = countRowsInSection(section.Rows, progress.RowID, progress.RowRepeatIndex)
 			if row != nil {
 				expectedStitches = row.ExpectedStitchCount
+				repeatCount = row.RepeatCount
 			}
 		}
 		stitchesDone = progress.StitchesCompletedInRow
 
+		var noteCount int
+		var latestNote string
+		db.QueryRow(`
+			SELECT COUNT(*), COALESCE((
+				SELECT text FROM work_notes
+				WHERE user_id = ? AND pattern_id = ?
+				ORDER BY created_at DESC, id DESC LIMIT 1
+			), '')
+			FROM work_notes WHERE user_id = ? AND pattern_id = ?
+		`, userID, s.patternID, userID, s.patternID).Scan(&noteCount, &latestNote)
+
+		var pausedAt *time.Time
+		if s.pausedAt.Valid {
+			t, _ := time.Parse(time.RFC3339, s.pausedAt.String)
+			pausedAt = &t
+		}
+		startedAt, _ := time.Parse(time.RFC3339, s.startedAt)
+		lastActiveAt, _ := time.Parse(time.RFC3339, s.lastActive)
+
 		summaries = append(summaries, SessionSummary{
-			SessionID:          s.sessionID,
-			PatternID:          s.patternID,
-			PatternName:        s.patternName,
-			SectionName:        sectionName,
-			RowLabel:           rowLabel,
-			RowNumberInSection: rowNum,
-			TotalRowsInSection: totalRows,
-			StitchesCompleted:  stitchesDone,
-			ExpectedStitches:   expectedStitches,
+			SessionID:           s.sessionID,
+			PatternID:           s.patternID,
+			PatternName:         s.patternName,
+			SessionLabel:        s.label,
+			PausedAt:            pausedAt,
+			SectionName:         sectionName,
+			RowLabel:            rowLabel,
+			RowNumberInSection:  rowNum,
+			TotalRowsInSection:  totalRows,
+			SectionNumber:       sIdx + 1,
+			TotalSections:       len(sections),
+			RowRepeatIndex:      progress.RowRepeatIndex,
+			RowRepeatCount:      repeatCount,
+			StitchesCompleted:   stitchesDone,
+			ExpectedStitches:    expectedStitches,
+			NoteCount:           noteCount,
+			LatestNote:          latestNote,
+			StartedAt:           startedAt,
+			LastActiveAt:        lastActiveAt,
+			PatternStitchesDone: stitchesWorked(sections, progress),
+			PatternStitches:     TotalPatternStitches(sections),
 		})
 	}
 	return summaries, nil
 }
 
+// MinEstimateFraction is how far through a pattern a session must be before
+// EstimatedCompletion will extrapolate from it.
+const MinEstimateFraction = 0.05
+
+// EstimatedCompletion extrapolates when the session will finish if work continues at
+// the pace so far: the time from start to last activity, scaled from the fraction of
+// the pattern's stitches done up to all of them. It returns nil until the session is
+// past MinEstimateFraction.
+func EstimatedCompletion(s SessionSummary) *time.Time {
+	if s.PatternStitches <= 0 {
+		return nil
+	}
+	fraction := float64(s.PatternStitchesDone) / float64(s.PatternStitches)
+	elapsed := s.LastActiveAt.Sub(s.StartedAt)
+	if fraction <= MinEstimateFraction || fraction >= 1 || elapsed <= 0 {
+		return nil
+	}
+	remaining := time.Duration(float64(elapsed) * (1 - fraction) / fraction)
+	eta := time.Now().Add(remaining)
+	return &eta
+}
+
+// stitchesWorked counts the stitches before progress, expanding repeats the same
+// way TotalPatternStitches does.
+func stitchesWorked(sections []PatternSection, progress *WorkProgress) int {
+	done := 0
+	for _, section := range sections {
+		for _, row := range section.Rows {
+			if row.ID == progress.RowID {
+				return done + rowStitchTotal(row)*progress.RowRepeatIndex + progress.StitchesCompletedInRow
+			}
+			done += rowStitchTotal(row) * row.RepeatCount
+		}
+	}
+	return done
+}
+
+// ListCompletedSessions returns summaries of the user's finished sessions, most
+// recently completed first. Only the pattern, label, completion time and duration
+// are filled in; there is no position to report.
+func ListCompletedSessions(db *sql.DB, userID int64) ([]SessionSummary, error) {
+	rows, err := db.Query(`
+		SELECT ws.id, ws.pattern_id, p.name, ws.label, ws.started_at, ws.completed_at
+		FROM work_sessions ws
+		JOIN patterns p ON ws.pattern_id = p.id
+		WHERE ws.user_id = ? AND ws.completed_at IS NOT NULL
+		ORDER BY ws.completed_at DESC, ws.id DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list completed sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []SessionSummary
+	for rows.Next() {
+		var s SessionSummary
+		var startedAt, completedAt string
+		if err := rows.Scan(&s.SessionID, &s.PatternID, &s.PatternName, &s.SessionLabel, &startedAt, &completedAt); err != nil {
+			return nil, fmt.Errorf("scan completed session: %w", err)
+		}
+		started, _ := time.Parse(time.RFC3339, startedAt)
+		completed, _ := time.Parse(time.RFC3339, completedAt)
+		s.CompletedAt = &completed
+		s.Duration = max(completed.Sub(started), 0)
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
 // --- Work display state (used by work mode view) ---
 
 // WorkDisplayState holds all computed data needed to render the work mode UI.
 type WorkDisplayState struct {
-	SessionID   int64
-	PatternID   int64
-	PatternName string
-	Completed   bool
+	SessionID    int64
+	PatternID    int64
+	PatternName  string
+	SessionLabel string
+	Completed    bool
+	Paused       bool
 
 	SectionName        string
 	RowLabel           string
@@ -533,58 +1579,153 @@ type WorkDisplayState struct {
 	RowRepeatCount     int
 	RowNumberInSection int
 	TotalRowsInSection int
-
-	Instructions          []RowInstruction
-	CurrentInstrID        int64
-	CurrentStitchIndex    int
-	CurrentGroupRepeatIdx int
-	CurrentStitchAbbr     string
-	CurrentStitchCount    int
+	SectionNumber      int // 1-based; 0 if the section is not found
+	TotalSections      int // every section counts, including ones with nothing to work
+	CountOnlyRow       bool
+
+	Instructions             []RowInstruction
+	CurrentInstrID           int64
+	CurrentStitchIndex       int
+	CurrentGroupRepeatIdx    int
+	CurrentStitchAbbr        string
+	CurrentStitchSymbol      string // the current stitch's chart symbol; "" if it has none
+	CurrentStitchDescription string // how to work the current stitch; "" hides the tooltip
+	CurrentStitchCount       int
+	CurrentInto              string // where the current instruction's stitches go, e.g. "ch-2 sp"; "" if unset
+	CurrentNote              string // the current instruction's note
+	RowNotes                 string
+	SectionNotes             string
+	CurrentColor             string  // yarn to hold for the current stitch; "" if none set
+	ReachedMarker            *string // note of the row marker at the current stitch, if any
+
+	HasNext          bool   // false on the last stitch of the pattern
+	NextStitchAbbr   string // the stitch after the current one, as advance would reach it
+	NextStitchSymbol string
+	NextStitchCount  int
+	NextStitchIndex  int // 0-based within its instruction; 0 means it starts a new instruction
+	NextInto         string
+	NextNewRow       bool // the next stitch starts a new row or row repeat
 
 	StitchesCompleted   int
 	ExpectedStitchCount int
+	RemainingInRow      int  // ExpectedStitchCount - StitchesCompleted, never negative
+	CountDown           bool // show RemainingInRow instead of counting up; set by the caller
+	ShowSymbols         bool // show stitches by chart symbol where they have one; set by the caller
+	StitchesLeftInRow   int  // advances until the next row repeat, counting the current stitch
+
+	RowsRemaining  int           // including the current row
+	RowsCompleted  int           // fully worked rows before the current one, across all sections
+	AvgRowDuration time.Duration // 0 until a row has been finished; set by the caller
+	CanRedo        bool          // an undone step can be replayed; set by the caller
+	BookmarkLabel  string        // row the session's bookmark is on; "" if none; set by the caller
+
+	ApproxLengthInches float64 // RowsCompleted at the pattern's row gauge; 0 without one; set by the caller
 }
 
 // BuildWorkDisplayState computes the display state from a session + progress.
 func BuildWorkDisplayState(session *WorkSession, progress *WorkProgress, sections []PatternSection, patternName string) WorkDisplayState {
 	state := WorkDisplayState{
-		SessionID:   session.ID,
-		PatternID:   session.PatternID,
-		PatternName: patternName,
-		Completed:   session.CompletedAt != nil,
+		SessionID:    session.ID,
+		PatternID:    session.PatternID,
+		PatternName:  patternName,
+		SessionLabel: session.Label,
+		Completed:    session.CompletedAt != nil,
+		Paused:       session.PausedAt != nil,
 	}
 	if state.Completed {
 		return state
 	}
 
-	section, _ := findSectionByID(sections, progress.SectionID)
+	section, sIdx := findSectionByID(sections, progress.SectionID)
+	state.SectionNumber = sIdx + 1
+	state.TotalSections = len(sections)
 	if section != nil {
 		state.SectionName = section.Name
+		state.SectionNotes = section.Notes
 		row, _ := findRowByID(section.Rows, progress.RowID)
 		if row != nil {
 			state.RowLabel = computeRowLabel(section.Rows, progress.RowID)
 			state.RowRepeatCount = row.RepeatCount
 			state.RowRepeatIndex = progress.RowRepeatIndex
 			state.TotalRowsInSection, state.RowNumberInSection = countRowsInSection(section.Rows, progress.RowID, progress.RowRepeatIndex)
+			state.CountOnlyRow = row.CountOnly
+			state.RowNotes = row.Notes
 			state.Instructions = row.Instructions
 			state.ExpectedStitchCount = row.ExpectedStitchCount
+			if idx := rowFlatIndex(row, progress); idx >= 0 {
+				pos, _ := rowStitchAt(row, idx)
+				state.StitchesLeftInRow = rowStitchCount(row) - idx
+				state.CurrentColor = pos.Color
+				state.ReachedMarker = markerNotesAt(row.Markers, idx+1)
+			}
 		}
 	}
 
+	state.RowsRemaining = rowsRemaining(sections, progress)
+	state.RowsCompleted = rowsCompleted(sections, progress)
 	state.CurrentInstrID = progress.InstructionID
 	state.CurrentStitchIndex = progress.StitchIndex
 	state.CurrentGroupRepeatIdx = progress.GroupRepeatIndex
 	state.StitchesCompleted = progress.StitchesCompletedInRow
+	state.RemainingInRow = max(state.ExpectedStitchCount-state.StitchesCompleted, 0)
 
 	// Resolve stitch abbreviation and count for current instruction.
 	if instr := findInstructionInTree(state.Instructions, progress.InstructionID); instr != nil {
 		state.CurrentStitchAbbr = instr.StitchAbbr
+		state.CurrentStitchSymbol = instr.StitchSymbol
+		state.CurrentStitchDescription = instr.StitchDesc
 		state.CurrentStitchCount = instr.Count
+		state.CurrentInto = instr.Into
+		state.CurrentNote = instr.Note
 	}
+	setNextStitch(&state, sections, progress)
 
 	return state
 }
 
+// CurrentStitchLabel is the current stitch as work mode shows it: its chart symbol
+// if ShowSymbols is set and it has one, else CurrentStitchAbbr.
+func (s WorkDisplayState) CurrentStitchLabel() string {
+	if s.ShowSymbols && s.CurrentStitchSymbol != "" {
+		return s.CurrentStitchSymbol
+	}
+	return s.CurrentStitchAbbr
+}
+
+// NextStitchLabel is CurrentStitchLabel for the stitch after the current one.
+func (s WorkDisplayState) NextStitchLabel() string {
+	if s.ShowSymbols && s.NextStitchSymbol != "" {
+		return s.NextStitchSymbol
+	}
+	return s.NextStitchAbbr
+}
+
+// setNextStitch fills in the stitch after the current one, peeking one step ahead
+// with the same traversal as advance.
+func setNextStitch(state *WorkDisplayState, sections []PatternSection, progress *WorkProgress) {
+	next, rowFinished, done, err := stepForward(sections, progress)
+	if err != nil || done {
+		return
+	}
+	section, _ := findSectionByID(sections, next.SectionID)
+	if section == nil {
+		return
+	}
+	row, _ := findRowByID(section.Rows, next.RowID)
+	if row == nil {
+		return
+	}
+	state.HasNext = true
+	state.NextNewRow = rowFinished
+	state.NextStitchIndex = next.StitchIndex
+	if instr := findInstructionInTree(row.Instructions, next.InstructionID); instr != nil {
+		state.NextStitchAbbr = instr.StitchAbbr
+		state.NextStitchSymbol = instr.StitchSymbol
+		state.NextStitchCount = instr.Count
+		state.NextInto = instr.Into
+	}
+}
+
 // --- Internal helpers ---
 
 func findSectionByID(sections []PatternSection, id int64) (*PatternSection, int) {
@@ -619,23 +1760,23 @@ func findInstructionInTree(instructions []RowInstruction, id int64) *RowInstruct
 	return nil
 }
 
-func setToFirstStitch(p *WorkProgress, flat []StitchPos) {
-	if len(flat) > 0 {
-		p.InstructionID = flat[0].InstructionID
-		p.StitchIndex = flat[0].StitchIndex
-		p.GroupRepeatIndex = flat[0].GroupRepeatIndex
+// setToStitch points p at the i-th position of one repeat of row.
+func setToStitch(p *WorkProgress, row *Row, i int) {
+	if pos, ok := rowStitchAt(row, i); ok {
+		p.InstructionID = pos.InstructionID
+		p.StitchIndex = pos.StitchIndex
+		p.GroupRepeatIndex = pos.GroupRepeatIndex
+		p.StitchesCompletedInRow = i
 	}
+}
+
+func setToFirstStitch(p *WorkProgress, row *Row) {
+	setToStitch(p, row, 0)
 	p.StitchesCompletedInRow = 0
 }
 
-func setToLastStitch(p *WorkProgress, flat []StitchPos) {
-	if len(flat) > 0 {
-		last := flat[len(flat)-1]
-		p.InstructionID = last.InstructionID
-		p.StitchIndex = last.StitchIndex
-		p.GroupRepeatIndex = last.GroupRepeatIndex
-		p.StitchesCompletedInRow = len(flat) - 1
-	}
+func setToLastStitch(p *WorkProgress, row *Row) {
+	setToStitch(p, row, rowStitchCount(row)-1)
 }
 
 // computeRowLabel returns the display label for a row (auto-generated if Label is empty).
@@ -661,6 +1802,26 @@ func computeRowLabel(rows []Row, rowID int64) string {
 	return "?"
 }
 
+// computeCurrentRowLabel is like computeRowLabel but names the single row being worked
+// ("Row 4") rather than the whole repeated range ("Rows 2-6").
+func computeCurrentRowLabel(rows []Row, rowID int64, rowRepeatIndex int) string {
+	n := 1
+	for _, r := range rows {
+		if r.ID == rowID {
+			if r.Label != "" {
+				return r.Label
+			}
+			prefix := "Row"
+			if r.Type == "joined_round" || r.Type == "continuous_round" {
+				prefix = "Rnd"
+			}
+			return fmt.Sprintf("%s %d", prefix, n+rowRepeatIndex)
+		}
+		n += r.RepeatCount
+	}
+	return "?"
+}
+
 // countRowsInSection returns (totalRowsInSection, rowNumberOfCurrent) where
 // row numbers count each repeat as one row, 1-based.
 func countRowsInSection(rows []Row, rowID int64, rowRepeatIndex int) (total int, current int) {
@@ -676,3 +1837,23 @@ func countRowsInSection(rows []Row, rowID int64, rowRepeatIndex int) (total int,
 	}
 	return n, cur
 }
+
+// SectionRowProgress returns how many row repeats of sections[sIdx] have been worked
+// at the given progress, and how many it has in total. Sections before the one being
+// worked are done, later ones untouched; a nil progress means nothing is worked yet.
+func SectionRowProgress(sections []PatternSection, sIdx int, progress *WorkProgress) (done, total int) {
+	section := &sections[sIdx]
+	if progress == nil {
+		total, _ = countRowsInSection(section.Rows, 0, 0)
+		return 0, total
+	}
+	if section.ID == progress.SectionID {
+		total, current := countRowsInSection(section.Rows, progress.RowID, progress.RowRepeatIndex)
+		return max(current-1, 0), total
+	}
+	total, _ = countRowsInSection(section.Rows, 0, 0)
+	if _, cur := findSectionByID(sections, progress.SectionID); cur > sIdx {
+		return total, total
+	}
+	return 0, total
+}
@@ -0,0 +1,405 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// testRow builds a one-repeat row with a top-level instruction of each count.
+// Instruction IDs are derived from the row ID so they are unique in a pattern.
+func testRow(id int64, counts ...int) Row {
+	row := Row{ID: id, RepeatCount: 1}
+	for i, c := range counts {
+		row.Instructions = append(row.Instructions, RowInstruction{ID: id*100 + int64(i), RowID: id, Count: c})
+	}
+	return row
+}
+
+// walkPattern steps from the first stitch to the end of the pattern, checking
+// that stepping back from each position lands on the one before it and that
+// there is nothing before the first. It returns every position visited, or nil
+// if the pattern has nothing to track.
+func walkPattern(t *testing.T, sections []PatternSection) []WorkProgress {
+	t.Helper()
+	sectionID, rowID, first, ok := firstStitchPosition(sections)
+	if !ok {
+		return nil
+	}
+	p := WorkProgress{SectionID: sectionID, RowID: rowID, InstructionID: first.InstructionID}
+	seq := []WorkProgress{p}
+	for {
+		next, _, done, err := stepForward(sections, &p)
+		if err != nil {
+			t.Fatalf("step forward from %+v: %v", p, err)
+		}
+		if done {
+			break
+		}
+		if len(seq) > 1000 {
+			t.Fatal("stepForward never finished")
+		}
+		p = next
+		seq = append(seq, p)
+	}
+
+	for i := len(seq) - 1; i > 0; i-- {
+		prev, ok, err := stepBackward(sections, &seq[i])
+		if err != nil || !ok {
+			t.Fatalf("step back from %+v: ok=%v err=%v", seq[i], ok, err)
+		}
+		if !samePosition(prev, seq[i-1]) {
+			t.Fatalf("step back from %+v: got %+v, want %+v", seq[i], prev, seq[i-1])
+		}
+	}
+	if _, ok, _ := stepBackward(sections, &seq[0]); ok {
+		t.Fatal("stepped back from the first stitch")
+	}
+	return seq
+}
+
+func samePosition(a, b WorkProgress) bool {
+	return a.SectionID == b.SectionID && a.RowID == b.RowID && a.RowRepeatIndex == b.RowRepeatIndex &&
+		a.InstructionID == b.InstructionID && a.StitchIndex == b.StitchIndex &&
+		a.GroupRepeatIndex == b.GroupRepeatIndex && a.StitchesCompletedInRow == b.StitchesCompletedInRow
+}
+
+func TestTraversalSkipsEmptyRowsAndSections(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections []PatternSection
+		stitches int     // positions visited; 0 for nothing to track
+		rows     []int64 // row of each visited position, in order
+	}{
+		{
+			name:     "empty row mid-section",
+			sections: []PatternSection{{ID: 1, Rows: []Row{testRow(1, 2), testRow(2), testRow(3, 3)}}},
+			stitches: 5,
+			rows:     []int64{1, 1, 3, 3, 3},
+		},
+		{
+			name: "several empty rows, one with a zero count",
+			sections: []PatternSection{{ID: 1, Rows: []Row{
+				testRow(1), testRow(2, 0), testRow(3, 3), testRow(4), testRow(5), testRow(6, 1),
+			}}},
+			stitches: 4,
+			rows:     []int64{3, 3, 3, 6},
+		},
+		{
+			name: "empty rows and sections interleaved",
+			sections: []PatternSection{
+				{ID: 1},
+				{ID: 2, Rows: []Row{testRow(1)}},
+				{ID: 3, Rows: []Row{testRow(2, 2), testRow(3), testRow(4, 1)}},
+				{ID: 4},
+				{ID: 5, Rows: []Row{testRow(5), testRow(6, 1, 0, 2)}},
+				{ID: 6, Rows: []Row{testRow(7)}},
+			},
+			stitches: 6,
+			rows:     []int64{2, 2, 4, 6, 6, 6},
+		},
+		{
+			name:     "empty last row of the last section",
+			sections: []PatternSection{{ID: 1, Rows: []Row{testRow(1, 1), testRow(2)}}},
+			stitches: 1,
+			rows:     []int64{1},
+		},
+		{
+			name: "count-only row between empty rows",
+			sections: []PatternSection{{ID: 1, Rows: []Row{
+				testRow(1), {ID: 2, RepeatCount: 2, CountOnly: true}, testRow(3), testRow(4, 1),
+			}}},
+			stitches: 3,
+			rows:     []int64{2, 2, 4},
+		},
+		{
+			name:     "nothing to track",
+			sections: []PatternSection{{ID: 1, Rows: []Row{testRow(1)}}, {ID: 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq := walkPattern(t, tt.sections)
+			if len(seq) != tt.stitches {
+				t.Fatalf("visited %d stitches, want %d", len(seq), tt.stitches)
+			}
+			for i, p := range seq {
+				if p.RowID != tt.rows[i] {
+					t.Errorf("stitch %d on row %d, want row %d", i, p.RowID, tt.rows[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWorkSessionAdvancesAndUndoesPastEmptyRows(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "a@b.com")
+	sc := builtinStitchID(t, db, "sc")
+
+	pattern, err := CreatePattern(db, user.ID, "Hat", "", PatternMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty, err := CreateSection(db, pattern.ID, "Empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustRow(t, db, empty.ID, 0)
+	body, err := CreateSection(db, pattern.ID, "Body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r1 := mustRow(t, db, body.ID, 2)
+	mustInstruction(t, db, r1.ID, sc, 2)
+	mustRow(t, db, body.ID, 0)
+	r3 := mustRow(t, db, body.ID, 1)
+	mustInstruction(t, db, r3.ID, sc, 1)
+	if _, err := CreateSection(db, pattern.ID, "Trailing"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, sections, err := LoadPatternFull(db, pattern.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := StartWorkSession(db, user.ID, pattern.ID, "", sections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, err := GetProgress(db, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start.RowID != r1.ID {
+		t.Fatalf("started on row %d, want %d", start.RowID, r1.ID)
+	}
+
+	// Three stitches: the third advance finishes the pattern, not the second.
+	for i := 1; i <= 3; i++ {
+		completed, err := AdvanceProgress(db, session.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if completed != (i == 3) {
+			t.Fatalf("advance %d: completed = %v", i, completed)
+		}
+		if i == 2 {
+			p, _ := GetProgress(db, session.ID)
+			if p.RowID != r3.ID {
+				t.Fatalf("after advance 2 on row %d, want %d", p.RowID, r3.ID)
+			}
+		}
+	}
+
+	// Undo back through the empty row to the very first stitch.
+	for i := 0; i < 3; i++ {
+		if err := UndoProgress(db, session.ID, sections); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := GetProgress(db, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !samePosition(*p, *start) {
+		t.Fatalf("after undoing everything at %+v, want %+v", p, start)
+	}
+	if err := UndoProgress(db, session.ID, sections); err != nil && !errors.Is(err, ErrNothingToTrack) {
+		t.Fatal(err)
+	}
+	if p, _ := GetProgress(db, session.ID); !samePosition(*p, *start) {
+		t.Fatalf("undo at the first stitch moved to %+v", p)
+	}
+}
+
+func TestStartWorkOnPatternWithoutInstructionsLeavesNoSession(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "a@b.com")
+	pattern, err := CreatePattern(db, user.ID, "Blank", "", PatternMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	section, err := CreateSection(db, pattern.ID, "Body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustRow(t, db, section.ID, 6)
+	if _, err := CreateSection(db, pattern.ID, "Empty"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, sections, err := LoadPatternFull(db, pattern.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StartWorkSession(db, user.ID, pattern.ID, "", sections); !errors.Is(err, ErrNothingToTrack) {
+		t.Fatalf("got %v, want %v", err, ErrNothingToTrack)
+	}
+	var sessions, progress int
+	if err := db.QueryRow("SELECT COUNT(*) FROM work_sessions WHERE pattern_id = ?", pattern.ID).Scan(&sessions); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM work_progress").Scan(&progress); err != nil {
+		t.Fatal(err)
+	}
+	if sessions != 0 || progress != 0 {
+		t.Fatalf("%d sessions and %d progress rows left behind", sessions, progress)
+	}
+}
+
+// startTestSession starts a session on a one-row pattern of stitches sc.
+func startTestSession(t *testing.T, stitches int) (*sql.DB, *WorkSession) {
+	t.Helper()
+	db, row := testRowFor(t)
+	mustInstruction(t, db, row.ID, builtinStitchID(t, db, "sc"), stitches)
+	patternID, err := GetPatternIDForRow(db, row.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sections, err := LoadPatternFull(db, patternID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var userID int64
+	if err := db.QueryRow("SELECT user_id FROM patterns WHERE id = ?", patternID).Scan(&userID); err != nil {
+		t.Fatal(err)
+	}
+	session, err := StartWorkSession(db, userID, patternID, "", sections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, session
+}
+
+// runConcurrently calls f n times at once and fails on any error.
+func runConcurrently(t *testing.T, n int, f func() error) {
+	t.Helper()
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- f()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConcurrentAdvancesEachMoveOneStitch(t *testing.T) {
+	db, session := startTestSession(t, 20)
+	if _, err := AdvanceProgressBy(db, session.ID, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	runConcurrently(t, 8, func() error {
+		_, err := AdvanceProgress(db, session.ID)
+		return err
+	})
+	p, err := GetProgress(db, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.StitchesCompletedInRow != 11 {
+		t.Fatalf("after 8 concurrent advances from stitch 3, at stitch %d; want 11", p.StitchesCompletedInRow)
+	}
+}
+
+func TestConcurrentUndoAndRedoEachMoveOneStitch(t *testing.T) {
+	db, session := startTestSession(t, 20)
+	if _, err := AdvanceProgressBy(db, session.ID, 10); err != nil {
+		t.Fatal(err)
+	}
+	_, sections, err := LoadPatternFull(db, session.PatternID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runConcurrently(t, 6, func() error { return UndoProgress(db, session.ID, sections) })
+	p, err := GetProgress(db, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.StitchesCompletedInRow != 4 {
+		t.Fatalf("after 6 concurrent undos from stitch 10, at stitch %d; want 4", p.StitchesCompletedInRow)
+	}
+
+	runConcurrently(t, 6, func() error { return RedoProgress(db, session.ID) })
+	if p, err = GetProgress(db, session.ID); err != nil {
+		t.Fatal(err)
+	}
+	if p.StitchesCompletedInRow != 10 {
+		t.Fatalf("after 6 concurrent redos, at stitch %d; want 10", p.StitchesCompletedInRow)
+	}
+	if CanRedo(db, session.ID) {
+		t.Fatal("redo history left over after redoing every undo")
+	}
+}
+
+func TestRedoRepairsProgressAfterAnEdit(t *testing.T) {
+	db, session := startTestSession(t, 20)
+	if _, err := AdvanceProgressBy(db, session.ID, 10); err != nil {
+		t.Fatal(err)
+	}
+	_, sections, err := LoadPatternFull(db, session.PatternID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := UndoProgress(db, session.ID, sections); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Shrink the row under both the current position and the undone ones.
+	if _, err := db.Exec("UPDATE row_instructions SET count = 5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RedoProgress(db, session.ID); !errors.Is(err, ErrNothingToRedo) {
+		t.Fatalf("redo to a position edited away: got %v, want %v", err, ErrNothingToRedo)
+	}
+	p, err := GetProgress(db, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.StitchIndex != 4 || p.StitchesCompletedInRow != 4 {
+		t.Fatalf("after redo at stitch %d (%d completed), want the last stitch, 4", p.StitchIndex, p.StitchesCompletedInRow)
+	}
+
+	// With the redo stack gone, a redo still repairs.
+	if _, err := db.Exec("UPDATE row_instructions SET count = 2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RedoProgress(db, session.ID); !errors.Is(err, ErrNothingToRedo) {
+		t.Fatalf("redo with nothing undone: got %v, want %v", err, ErrNothingToRedo)
+	}
+	if p, err = GetProgress(db, session.ID); err != nil {
+		t.Fatal(err)
+	}
+	if p.StitchIndex != 1 {
+		t.Fatalf("after redo at stitch %d, want 1", p.StitchIndex)
+	}
+}
+
+func TestRepairNeverSetsANegativeRowRepeat(t *testing.T) {
+	// A row whose repeat count was zeroed under the position.
+	sections := []PatternSection{{ID: 1, Rows: []Row{{ID: 1, RepeatCount: 0,
+		Instructions: []RowInstruction{{ID: 10, RowID: 1, Count: 3}}}}}}
+
+	next, ok := nearestPosition(sections, &WorkProgress{SectionID: 1, RowID: 1, RowRepeatIndex: 2, InstructionID: 10})
+	if !ok || next.RowRepeatIndex != 0 {
+		t.Errorf("nearestPosition: ok=%v repeat %d, want repeat 0", ok, next.RowRepeatIndex)
+	}
+	var last WorkProgress
+	if !seekLastStitch(&last, sections, 0, 0) || last.RowRepeatIndex != 0 {
+		t.Errorf("seekLastStitch: repeat %d, want 0", last.RowRepeatIndex)
+	}
+}
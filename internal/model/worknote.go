@@ -0,0 +1,116 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// WorkNote is a journal entry written during a work session ("ran out of yarn here").
+// Notes are listed per pattern, so earlier sessions' notes show up when the pattern is worked again.
+type WorkNote struct {
+	ID        int64
+	PatternID int64
+	SessionID *int64 // nil if the originating session was deleted
+	Context   string // where in the pattern the note was taken, e.g. "Body — Rnd 4"
+	Text      string
+	CreatedAt time.Time
+}
+
+// AddWorkNote records a note against a session (and its pattern), tagged with the
+// session's current position.
+func AddWorkNote(db *sql.DB, sessionID int64, text string) (*WorkNote, error) {
+	session, err := FindSessionByID(db, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("find session: %w", err)
+	}
+
+	context := workNoteContext(db, session)
+
+	result, err := db.Exec(`
+		INSERT INTO work_notes (user_id, pattern_id, session_id, context, text)
+		VALUES (?, ?, ?, ?, ?)
+	`, session.UserID, session.PatternID, sessionID, context, text)
+	if err != nil {
+		return nil, fmt.Errorf("insert work note: %w", err)
+	}
+
+	id, _ := result.LastInsertId()
+	sid := sessionID
+	return &WorkNote{
+		ID:        id,
+		PatternID: session.PatternID,
+		SessionID: &sid,
+		Context:   context,
+		Text:      text,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// ListWorkNotes returns every note for the session's pattern, newest first.
+func ListWorkNotes(db *sql.DB, sessionID int64) ([]WorkNote, error) {
+	session, err := FindSessionByID(db, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("find session: %w", err)
+	}
+
+	rows, err := db.Query(`
+		SELECT id, pattern_id, session_id, context, text, created_at
+		FROM work_notes
+		WHERE user_id = ? AND pattern_id = ?
+		ORDER BY created_at DESC, id DESC
+	`, session.UserID, session.PatternID)
+	if err != nil {
+		return nil, fmt.Errorf("list work notes: %w", err)
+	}
+	defer rows.Close()
+
+	var notes []WorkNote
+	for rows.Next() {
+		var n WorkNote
+		var sid sql.NullInt64
+		var createdAt string
+		if err := rows.Scan(&n.ID, &n.PatternID, &sid, &n.Context, &n.Text, &createdAt); err != nil {
+			return nil, fmt.Errorf("scan work note: %w", err)
+		}
+		if sid.Valid {
+			n.SessionID = &sid.Int64
+		}
+		n.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// DeleteWorkNote deletes a note owned by the user.
+func DeleteWorkNote(db *sql.DB, id, userID int64) error {
+	result, err := db.Exec("DELETE FROM work_notes WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return fmt.Errorf("delete work note: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return fmt.Errorf("work note not found or not owned by user")
+	}
+	return nil
+}
+
+// workNoteContext describes the session's current position for tagging a note.
+func workNoteContext(db *sql.DB, session *WorkSession) string {
+	if session.CompletedAt != nil {
+		return "Completed"
+	}
+	progress, err := GetProgress(db, session.ID)
+	if err != nil {
+		return ""
+	}
+	_, sections, err := LoadPatternFull(db, session.PatternID)
+	if err != nil {
+		return ""
+	}
+	section, _ := findSectionByID(sections, progress.SectionID)
+	if section == nil {
+		return ""
+	}
+	return section.Name + " — " + computeRowLabel(section.Rows, progress.RowID)
+}
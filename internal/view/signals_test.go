@@ -0,0 +1,106 @@
+package view
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ"
+	"github.com/stitchmap/stitchmap/internal/model"
+)
+
+var hostileStrings = []string{
+	`say "hi"`,
+	`back\slash`,
+	`</script><script>alert(1)</script>`,
+	"line\u2028separator",
+	`{"x":1}`,
+}
+
+func TestJSString(t *testing.T) {
+	for _, s := range hostileStrings {
+		got := jsString(s)
+		if strings.Contains(got, "</script") {
+			t.Errorf("jsString(%q) = %s, contains a closing script tag", s, got)
+		}
+		if strings.ContainsRune(got, '\u2028') {
+			t.Errorf("jsString(%q) = %s, contains a raw line separator", s, got)
+		}
+		var back string
+		if err := json.Unmarshal([]byte(got), &back); err != nil {
+			t.Errorf("jsString(%q) = %s, not a JSON string: %v", s, got, err)
+			continue
+		}
+		if back != s {
+			t.Errorf("jsString(%q) round-tripped to %q", s, back)
+		}
+	}
+}
+
+var signalsAttr = regexp.MustCompile(`data-signals="([^"]*)"`)
+
+// renderedSignals renders c and decodes its first data-signals attribute.
+func renderedSignals(t *testing.T, c templ.Component) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := c.Render(context.Background(), &buf); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	m := signalsAttr.FindStringSubmatch(buf.String())
+	if m == nil {
+		t.Fatal("no data-signals attribute rendered")
+	}
+	var signals map[string]any
+	if err := json.Unmarshal([]byte(html.UnescapeString(m[1])), &signals); err != nil {
+		t.Fatalf("data-signals is not valid JSON: %v\n%s", err, m[1])
+	}
+	return signals
+}
+
+func TestFormSignalsRoundTripUserText(t *testing.T) {
+	for _, s := range hostileStrings {
+		forms := []struct {
+			name string
+			c    templ.Component
+			keys []string
+		}{
+			{"PatternEditForm", PatternEditForm(&model.Pattern{Name: s, Description: s, Meta: model.PatternMeta{
+				Difficulty: s, HookSize: s, YarnWeight: s, FinishedSize: s,
+			}}), []string{"patternName", "patternDesc", "patternDifficulty", "patternHookSize", "patternYarnWeight", "patternFinishedSize"}},
+			{"SectionEditForm", SectionEditForm(&model.PatternSection{Name: s, Notes: s}),
+				[]string{"sectionName", "sectionNotes"}},
+			{"EditRowForm", EditRowForm(&model.Row{Label: s, Type: s, Notes: s, RepeatCount: 1}),
+				[]string{"rowLabel", "rowType", "rowNotes"}},
+			{"EditInstructionForm", EditInstructionForm(&model.RowInstruction{Count: 1, Into: s, Note: s, Color: s}, nil, nil),
+				[]string{"editInstrInto", "editInstrNote", "editInstrColor"}},
+			{"EditGroupForm", EditGroupForm(&model.RowInstruction{GroupRepeat: 2, Note: s, Color: s}),
+				[]string{"editGrpNote", "editGrpColor"}},
+			{"StitchForm", StitchForm(&model.Stitch{Name: s, Abbreviation: s, Description: s, Category: s, Color: s, Symbol: s}),
+				[]string{"stitchName", "stitchAbbr", "stitchDesc", "stitchCategory", "stitchColor", "stitchSymbol"}},
+		}
+		for _, f := range forms {
+			signals := renderedSignals(t, f.c)
+			for _, k := range f.keys {
+				if got, _ := signals[k].(string); got != s {
+					t.Errorf("%s: signal %s = %q, want %q", f.name, k, signals[k], s)
+				}
+			}
+		}
+	}
+}
+
+func TestAddRowFormStartsFromThePreviousRow(t *testing.T) {
+	for _, tt := range []struct {
+		prev int
+		want string
+	}{{48, "48"}, {0, "6"}} {
+		signals := renderedSignals(t, AddRowForm(1, model.Row{Type: "row", ExpectedStitchCount: tt.prev}))
+		if got := signals["rowStitchCount"]; got != tt.want {
+			t.Errorf("previous row of %d: rowStitchCount = %v, want %s", tt.prev, got, tt.want)
+		}
+	}
+}